@@ -0,0 +1,95 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/ISDuBA/ISDuBA/pkg/archive"
+)
+
+// entry is an advisory to import together with the
+// signature and hash files found next to it.
+type entry struct {
+	key        string            // key of the advisory in the checkpoint
+	name       string            // name of the advisory in the logs
+	path       string            // file on disk, empty for members of archives
+	filename   string            // file name without compression suffix
+	data       []byte            // content of the advisory
+	companions map[string][]byte // content of the companion files by extension
+	err        error             // reading the advisory from the archive failed
+}
+
+// fileKey returns the key of an advisory file or a member
+// of an archive in the checkpoint.
+func fileKey(file, member string) string {
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	if member == "" {
+		return file
+	}
+	return file + "!" + member
+}
+
+// newFile returns an entry for an advisory file on disk.
+// The content is loaded later by [entry.load].
+func newFile(file string) *entry {
+	return &entry{
+		key:        fileKey(file, ""),
+		name:       file,
+		path:       file,
+		filename:   filepath.Base(archive.AdvisoryName(file)),
+		companions: map[string][]byte{},
+	}
+}
+
+// newMember returns an entry for an advisory in an archive.
+func newMember(file string, m *archive.Member) *entry {
+	return &entry{
+		key:        fileKey(file, m.Name),
+		name:       file + "!" + m.Name,
+		filename:   path.Base(archive.AdvisoryName(m.Name)),
+		data:       m.Data,
+		companions: m.Companions,
+		err:        m.Err,
+	}
+}
+
+// load reads an advisory file and its companion files from disk.
+func (e *entry) load() error {
+	data, err := os.ReadFile(e.path)
+	if err != nil {
+		return err
+	}
+	e.data = data
+	base := archive.AdvisoryName(e.path)
+	for _, ext := range archive.CompanionExts {
+		switch data, err := os.ReadFile(base + ext); {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return err
+		default:
+			e.companions[ext] = data
+		}
+	}
+	return nil
+}
+
+// uncompress decompresses the content of gzipped advisories
+// up to the given limit.
+func (e *entry) uncompress(limit int64) error {
+	var err error
+	e.data, err = archive.Uncompress(e.name, e.data, limit)
+	return err
+}
@@ -0,0 +1,87 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// checkpoint records the successfully processed files of an import
+// so that an interrupted run can be resumed. Failed files are not
+// recorded and are retried by the next run.
+// The file contains the key of a processed file per line.
+// See [fileKey].
+// A nil checkpoint records nothing.
+type checkpoint struct {
+	path string
+	file *os.File
+	done map[string]bool
+}
+
+// openCheckpoint loads the already processed files from the
+// checkpoint file and opens it to append the next ones.
+func openCheckpoint(path string) (*checkpoint, error) {
+	if path == "" {
+		return nil, nil
+	}
+	cp := &checkpoint{path: path, done: map[string]bool{}}
+	switch f, err := os.Open(path); {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			if line := sc.Text(); line != "" {
+				cp.done[line] = true
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return nil, fmt.Errorf("reading checkpoint %q failed: %w", path, err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	cp.file = f
+	return cp, nil
+}
+
+// contains checks if the file was processed by an earlier run.
+func (cp *checkpoint) contains(key string) bool {
+	return cp != nil && cp.done[key]
+}
+
+// add records a file as processed.
+func (cp *checkpoint) add(key string) error {
+	if cp == nil {
+		return nil
+	}
+	_, err := fmt.Fprintln(cp.file, key)
+	return err
+}
+
+// close closes the checkpoint file.
+// If the import is complete the file is removed.
+func (cp *checkpoint) close(complete bool) error {
+	if cp == nil {
+		return nil
+	}
+	err := cp.file.Close()
+	if complete && err == nil {
+		err = os.Remove(cp.path)
+	}
+	return err
+}
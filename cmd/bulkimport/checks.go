@@ -0,0 +1,183 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/gocsaf/csaf/v3/util"
+
+	"github.com/ISDuBA/ISDuBA/pkg/models"
+)
+
+// hashes are the supported hash files, the preferred first.
+var hashes = []struct {
+	ext     string
+	newHash func() hash.Hash
+}{
+	{".sha512", sha512.New},
+	{".sha256", sha256.New},
+}
+
+// outcomes are the results of the checks of an advisory by the
+// columns of the downloads table. Checks not done are missing.
+type outcomes map[string]bool
+
+// fail marks a check as failed and logs the reason.
+func (o outcomes) fail(column, name, msg string, args ...any) {
+	o[column] = true
+	slog.Warn(fmt.Sprintf(msg, args...), "file", name)
+}
+
+// pass marks a check as passed if it has not failed before.
+func (o outcomes) pass(column string) {
+	if _, ok := o[column]; !ok {
+		o[column] = false
+	}
+}
+
+// failed checks if any of the checks failed.
+func (o outcomes) failed() bool {
+	return slices.Contains(slices.Collect(maps.Values(o)), true)
+}
+
+// insertSQL returns the statement to record the outcomes and the
+// validation report for the given document in the downloads table
+// of the bulk feed. A document id of 0 records the outcomes without
+// a document.
+func (o outcomes) insertSQL(docID int64, report *models.ValidationReport) (string, []any) {
+	var (
+		columns      = []string{"feeds_id"}
+		placeholders = []string{`(SELECT id FROM feeds WHERE sources_id = 0 AND label = 'bulk')`}
+		values       []any
+	)
+	add := func(column string, value any) {
+		values = append(values, value)
+		columns = append(columns, column)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(values)))
+	}
+	if docID != 0 {
+		add("documents_id", docID)
+	}
+	for _, column := range slices.Sorted(maps.Keys(o)) {
+		add(column, o[column])
+	}
+	if report != nil {
+		add("validation_report", report)
+	}
+	return fmt.Sprintf("INSERT INTO downloads (%s) VALUES (%s)",
+		strings.Join(columns, ","), strings.Join(placeholders, ",")), values
+}
+
+// loadKeys loads the ASCII armored OpenPGP public keys from a file.
+func loadKeys(file string) (*crypto.KeyRing, error) {
+	if file == "" {
+		return nil, nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entities, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading keys from %q failed: %w", file, err)
+	}
+	keys, err := crypto.NewKeyRing(nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, entity := range entities {
+		key, err := crypto.NewKeyFromEntity(entity)
+		if err != nil {
+			return nil, err
+		}
+		if err := keys.AddKey(key); err != nil {
+			return nil, err
+		}
+	}
+	if keys.CountEntities() == 0 {
+		return nil, fmt.Errorf("no keys found in %q", file)
+	}
+	return keys, nil
+}
+
+// verify runs the checks of the online downloader on the
+// file name, the checksum and the signature of an advisory.
+func (im *importer) verify(j *job) {
+	e := j.entry
+
+	switch j.report.CheckFilename(j.document, e.filename); {
+	case !j.report.Filename.Conforming:
+		j.outcomes.fail("filename_failed", e.name, "File name %q is not conforming", e.filename)
+	case !j.report.Filename.Matches:
+		j.outcomes.fail("filename_failed", e.name, "Tracking ID is not conforming: %s", j.report.Filename.Error)
+	}
+	j.outcomes.pass("filename_failed")
+
+	// Only check the checksum if there is a hash file.
+	for _, h := range hashes {
+		data, ok := e.companions[h.ext]
+		if !ok {
+			continue
+		}
+		switch expected, err := util.HashFromReader(bytes.NewReader(data)); {
+		case err != nil:
+			j.outcomes.fail("checksum_failed", e.name, "Reading hash file failed: %v", err)
+		case expected == nil:
+			j.outcomes.fail("checksum_failed", e.name, "No hash found in %q", e.filename+h.ext)
+		default:
+			checksum := h.newHash()
+			checksum.Write(e.data)
+			if !bytes.Equal(checksum.Sum(nil), expected) {
+				j.outcomes.fail("checksum_failed", e.name, "Checksum mismatch")
+			}
+		}
+		j.outcomes.pass("checksum_failed")
+		break
+	}
+
+	// The signature is stored even if there are no keys to check it.
+	var (
+		signature *crypto.PGPSignature
+		err       error
+	)
+	data, ok := e.companions[".asc"]
+	if ok {
+		if signature, err = crypto.NewPGPSignatureFromArmored(string(data)); err == nil {
+			j.signature = data
+		}
+	}
+	if im.keys != nil {
+		switch {
+		case !ok:
+			j.outcomes.fail("signature_failed", e.name, "Missing OpenPGP signature")
+		case err != nil:
+			j.outcomes.fail("signature_failed", e.name, "Loading OpenPGP signature failed: %v", err)
+		default:
+			if err := im.keys.VerifyDetached(
+				crypto.NewPlainMessage(e.data), signature, crypto.GetUnixTime(),
+			); err != nil {
+				j.outcomes.fail("signature_failed", e.name, "Verifying OpenPGP signature failed: %v", err)
+			}
+		}
+		j.outcomes.pass("signature_failed")
+	}
+}
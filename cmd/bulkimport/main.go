@@ -10,154 +10,397 @@
 package main
 
 import (
-	"compress/gzip"
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"github.com/ISDuBA/ISDuBA/pkg/config"
 	"github.com/ISDuBA/ISDuBA/pkg/database"
 	"github.com/ISDuBA/ISDuBA/pkg/version"
-	"io"
 	"io/fs"
 	"log/slog"
 	"os"
+	"os/signal"
 	"os/user"
 	"path/filepath"
-	"strings"
+	"runtime"
+	"slices"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/ISDuBA/ISDuBA/pkg/archive"
 	"github.com/ISDuBA/ISDuBA/pkg/models"
 )
 
-func processFile(
-	ctx context.Context,
-	db *database.DB,
-	dry bool,
-	importer, file string,
-	del bool,
-	mov string,
-	ctn bool,
-) error {
-	var actor *string
-	if importer != "" {
-		actor = &importer
+// importer stores the read documents in the database.
+type importer struct {
+	db     *database.DB
+	dry    bool
+	actor  *string
+	del    bool
+	mov    string
+	ctn    bool
+	strict bool
+	keys   *crypto.KeyRing
+	limit  int64
+}
+
+// job is an advisory read and checked by a worker.
+type job struct {
+	entry     *entry
+	document  any
+	signature []byte
+	outcomes  outcomes
+	report    *models.ValidationReport
+	skip      bool
+	err       error
+}
+
+// collect returns the advisory files and archives
+// found in the given files and directories.
+func collect(files []string) ([]string, error) {
+	var paths []string
+	for _, file := range files {
+		if err := filepath.WalkDir(file, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() && (archive.IsAdvisory(path) || archive.IsArchive(path)) {
+				paths = append(paths, path)
+			}
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("processing %q failed: %w", file, err)
+		}
 	}
-	walk := func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
+	return paths, nil
+}
+
+// readJob reads, decodes, validates and checks an advisory.
+func (im *importer) readJob(e *entry) *job {
+	j := &job{entry: e, outcomes: outcomes{}}
+	j.err = func() error {
+		if e.err != nil {
+			return e.err
+		}
+		if e.path != "" {
+			if err := e.load(); err != nil {
+				return err
+			}
+		}
+		if err := e.uncompress(im.limit); err != nil {
 			return err
 		}
-		if !d.Type().IsRegular() {
-			return nil
+		if err := json.Unmarshal(e.data, &j.document); err != nil {
+			return err
 		}
-		lower := strings.ToLower(path)
-		if !(strings.HasSuffix(lower, ".json") || strings.HasSuffix(lower, "json.gz")) {
-			return nil
+		// Archives of provider directories contain other JSON files, too.
+		if e.path == "" {
+			if obj, ok := j.document.(map[string]any); !ok || obj["document"] == nil {
+				j.skip = true
+				return nil
+			}
+		}
+		j.report = models.NewValidationReport()
+		im.verify(j)
+		if err := j.report.CheckSchema(j.document); err != nil {
+			j.outcomes.fail("schema_failed", e.name, "Schema validation failed: %v", err)
+			return fmt.Errorf("schema validation failed: %w", err)
+		}
+		if j.report.SchemaFailed() {
+			j.outcomes.fail("schema_failed", e.name,
+				"Schema validation has %d errors", len(j.report.Schema))
+			return j.report.SchemaErr()
+		}
+		j.outcomes.pass("schema_failed")
+		if im.strict && j.outcomes.failed() {
+			return errChecks
 		}
+		return nil
+	}()
+	return j
+}
 
-		slog.Info("processing document", "file", filepath.Base(path))
+// storeOutcomes records the outcomes of the checks of an advisory
+// which is not imported.
+func (im *importer) storeOutcomes(ctx context.Context, j *job) error {
+	if im.dry || len(j.outcomes) == 0 {
+		return nil
+	}
+	return im.db.Run(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+		sql, values := j.outcomes.insertSQL(0, j.report)
+		_, err := conn.Exec(ctx, sql, values...)
+		return err
+	}, 0)
+}
 
-		file, err := os.Open(path)
-		if err != nil {
-			return err
+// store stores a read advisory in the database.
+// It returns an error if the import should be stopped.
+func (im *importer) store(ctx context.Context, j *job) error {
+	e := j.entry
+	slog.Info("processing document", "file", e.name)
+
+	// Store stats in database.
+	storeStats := func(ctx context.Context, tx pgx.Tx, docID int64, duplicate bool) error {
+		if duplicate {
+			j.outcomes["duplicate_failed"] = true
 		}
-		defer file.Close()
+		sql, values := j.outcomes.insertSQL(docID, j.report)
+		_, err := tx.Exec(ctx, sql, values...)
+		return err
+	}
 
-		filename := filepath.Base(path)
+	// Store signature data in database.
+	storeSignature := func(ctx context.Context, tx pgx.Tx, docID int64, duplicate bool) error {
+		if duplicate || j.signature == nil {
+			return nil
+		}
+		const updateSQL = `UPDATE documents SET signature = $1 WHERE id = $2`
+		_, err := tx.Exec(ctx, updateSQL, j.signature, docID)
+		return err
+	}
 
-		var r io.Reader
-		if strings.HasSuffix(lower, ".gz") {
-			if r, err = gzip.NewReader(file); err != nil {
-				return err
+	var id int64
+	err := j.err
+	if err != nil {
+		if errStats := im.storeOutcomes(ctx, j); errStats != nil {
+			return fmt.Errorf("storing stats of %q failed: %w", e.name, errStats)
+		}
+	} else {
+		err = im.db.Run(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+			var err error
+			id, err = models.ImportDocumentData(
+				ctx, conn, j.document, e.data, im.actor,
+				nil,
+				models.ChainInTx(storeStats, storeSignature, models.StoreFilename(e.filename)),
+				im.dry)
+			return err
+		}, 0)
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrAlreadyInDatabase) {
+			slog.Warn("advisory already in database", "file", e.name)
+			if im.del && e.path != "" {
+				errDel := deleteAdvisory(e.path)
+				if errDel != nil {
+					return fmt.Errorf("failed to delete duplicate advisory %s: %w", filepath.Base(e.path), errDel)
+				}
 			}
-			// Strip away extension
-			filename = filename[:len(filename)-len(".gz")]
-		} else {
-			r = file
+			return nil
+		}
+		if im.mov != "" && e.path != "" {
+			errMov := moveAdvisory(e.path, im.mov)
+			if errMov != nil {
+				return fmt.Errorf("failed to import: %w, failed to move not imported advisory: %w", err, errMov)
+			}
+		}
+		if !im.ctn {
+			return fmt.Errorf("importing %q failed: %w", e.name, err)
+		}
+		slog.Warn("import failed", "file", e.name, "error", err)
+		return errFailed
+	}
+	if im.del && e.path != "" {
+		errDel := deleteAdvisory(e.path)
+		if errDel != nil {
+			return fmt.Errorf("failed to delete imported advisory %s: %w", filepath.Base(e.path), errDel)
 		}
+	}
+	slog.Info("inserted", "id", id)
+	return nil
+}
+
+// defaultLimit is the default maximal size of a decompressed advisory.
+const defaultLimit = 512 * 1024 * 1024
+
+var (
+	// errFailed signals a failed import which does not stop the run.
+	errFailed = errors.New("import failed")
+	// errChecks signals an advisory rejected in strict mode.
+	errChecks = errors.New("checks failed in strict mode")
+)
+
+// run reads the files with the given number of workers in parallel
+// and stores them one after the other. The members of archives
+// are read one after the other before they are passed to the workers.
+func (im *importer) run(
+	ctx context.Context,
+	paths []string,
+	workers int,
+	cp *checkpoint,
+	prog *progress,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-		// Store stats in database.
-		storeStats := func(ctx context.Context, tx pgx.Tx, docID int64, duplicate bool) error {
-			if duplicate {
+	todo := make(chan *entry)
+	go func() {
+		defer close(todo)
+		send := func(e *entry) error {
+			select {
+			case todo <- e:
 				return nil
+			case <-ctx.Done():
+				return ctx.Err()
 			}
-			const insertSQL = `INSERT INTO downloads ` +
-				`(documents_id, feeds_id) VALUES ($1, ` +
-				`(SELECT id FROM feeds WHERE sources_id = 0 AND label = 'bulk'))`
-			_, err := tx.Exec(ctx, insertSQL, docID)
-			return err
 		}
-
-		var id int64
-		if err = db.Run(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
-			id, err = models.ImportDocument(
-				ctx, conn, r, actor,
-				nil,
-				models.ChainInTx(storeStats, models.StoreFilename(filename)),
-				dry)
-			return err
-		}, 0); err != nil {
-			if errors.Is(err, models.ErrAlreadyInDatabase) {
-				slog.Warn("advisory already in database", "file", filepath.Base(path))
-				if del {
-					errDel := deleteAdvisory(path)
-					if errDel != nil {
-						return fmt.Errorf("failed to delete duplicate advisory %s: %w", filepath.Base(path), errDel)
-					}
+		for _, path := range paths {
+			if !archive.IsArchive(path) {
+				if send(newFile(path)) != nil {
+					return
 				}
-				return nil
+				continue
 			}
-			if mov != "" {
-				errMov := moveAdvisory(path, mov)
-				if errMov != nil {
-					return fmt.Errorf("failed to import: %w, failed to move not imported advisory: %w", err, errMov)
+			slog.Info("reading archive", "archive", path)
+			// The members of the archives are counted when found.
+			member := func(m *archive.Member) error {
+				e := newMember(path, m)
+				if cp.contains(e.key) {
+					prog.skipped.Add(1)
+					return nil
 				}
+				prog.total.Add(1)
+				return send(e)
 			}
-			if !ctn {
-				return err
+			switch err := archive.ReadFile(path, im.limit, member); {
+			case errors.Is(err, context.Canceled):
+				return
+			case err != nil:
+				// Report the broken archive like a failed advisory.
+				prog.total.Add(1)
+				broken := &entry{
+					key:  fileKey(path, ""),
+					name: path,
+					err:  fmt.Errorf("reading archive failed: %w", err),
+				}
+				if send(broken) != nil {
+					return
+				}
 			}
-			slog.Warn("import failed", "error", err)
-			return nil
 		}
-		if del {
-			errDel := deleteAdvisory(path)
-			if errDel != nil {
-				return fmt.Errorf("failed to delete imported advisory %s: %w", filepath.Base(path), errDel)
+	}()
+
+	jobs := make(chan *job, workers)
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Go(func() {
+			for e := range todo {
+				select {
+				case jobs <- im.readJob(e):
+				case <-ctx.Done():
+					return
+				}
+			}
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(jobs)
+	}()
+	// Let the workers finish if we stop early.
+	defer func() {
+		cancel()
+		for range jobs {
+		}
+	}()
+
+	for j := range jobs {
+		// Stop if interrupted.
+		if ctx.Err() != nil {
+			break
+		}
+		if j.skip {
+			slog.Debug("skipping non advisory", "file", j.entry.name)
+			prog.total.Add(-1)
+		} else {
+			// The current document is stored even if interrupted.
+			err := im.store(context.WithoutCancel(ctx), j)
+			if err != nil && !errors.Is(err, errFailed) {
+				return err
+			}
+			prog.done.Add(1)
+			// Failed advisories are not recorded
+			// so that they are retried on resume.
+			if err != nil {
+				prog.failed.Add(1)
+				continue
 			}
 		}
-		slog.Info("inserted", "id", id)
-		return nil
+		if err := cp.add(j.entry.key); err != nil {
+			return fmt.Errorf("writing checkpoint failed: %w", err)
+		}
 	}
-	return filepath.WalkDir(file, walk)
+	return ctx.Err()
 }
 
-func process(creds *config.Database, dry bool, importer string, files []string, del bool, mov string, ctn bool) error {
+func process(
+	creds *config.Database,
+	im *importer,
+	files []string,
+	workers int,
+	checkpointFile string,
+	interval time.Duration,
+) error {
 	start := time.Now()
 	defer func() {
 		slog.Info("processing took", "duration", time.Since(start))
 	}()
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	db, err := database.NewDB(ctx, creds)
 	if err != nil {
 		return err
 	}
 	defer db.Close(ctx)
+	im.db = db
 
-	conn, err := pgx.Connect(ctx, creds.ConnString())
+	paths, err := collect(files)
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
-	for _, file := range files {
-		if err := processFile(ctx, db, dry, importer, file, del, mov, ctn); err != nil {
-			return fmt.Errorf("processing %q failed: %w", file, err)
-		}
+
+	cp, err := openCheckpoint(checkpointFile)
+	if err != nil {
+		return err
 	}
-	return nil
+	// Skip the files processed by an interrupted run.
+	// The members of archives are skipped when they are read.
+	found := len(paths)
+	todo := slices.DeleteFunc(paths, func(path string) bool {
+		return !archive.IsArchive(path) && cp.contains(fileKey(path, ""))
+	})
+	plain := slices.DeleteFunc(slices.Clone(todo), archive.IsArchive)
+	prog := newProgress(len(plain), found-len(todo))
+	if skipped := prog.skipped.Load(); skipped > 0 {
+		slog.Info("resuming", "checkpoint", checkpointFile, "skipped", skipped)
+	}
+
+	reportCtx, stopReport := context.WithCancel(ctx)
+	go prog.report(reportCtx, interval)
+
+	err = im.run(ctx, todo, workers, cp, prog)
+	stopReport()
+	prog.log()
+	// Keep the checkpoint to retry the failed advisories.
+	failed := prog.failed.Load() > 0
+	if errCp := cp.close(err == nil && !failed); errCp != nil {
+		return errors.Join(err, fmt.Errorf("closing checkpoint failed: %w", errCp))
+	}
+	switch {
+	case cp == nil:
+	case err != nil:
+		slog.Info("resume with same checkpoint", "checkpoint", checkpointFile)
+	case failed:
+		slog.Info("retry failed advisories with same checkpoint", "checkpoint", checkpointFile)
+	}
+	return err
 }
 
 func check(err error) {
@@ -208,12 +451,18 @@ func moveAdvisory(path string, destination string) error {
 func main() {
 	var (
 		creds           config.Database
-		importer        string
+		importerName    string
 		moveOnErr       string
 		dry             bool
 		showVersion     bool
 		deleteOnSuccess bool
 		continueOnError bool
+		workers         int
+		checkpointFile  string
+		interval        time.Duration
+		keysFile        string
+		strict          bool
+		limit           = config.HumanSize(defaultLimit)
 	)
 	flag.StringVar(&creds.Database, "database", "isduba", "database name")
 	flag.StringVar(&creds.User, "user", "isduba", "database user")
@@ -224,12 +473,32 @@ func main() {
 	flag.BoolVar(&showVersion, "version", false, "show version information")
 	flag.BoolVar(&deleteOnSuccess, "delete", false, "delete successfully imported advisories")
 	flag.StringVar(&moveOnErr, "move", "", "move unsuccessfully imported advisories to this folder (create folder if it does not exist)")
-	flag.StringVar(&importer, "importer", userName(), "importing person")
+	flag.StringVar(&importerName, "importer", userName(), "importing person")
 	flag.BoolVar(&continueOnError, "continue", false, "continue bulkimport even if an advisory was not imported successfully")
+	flag.IntVar(&workers, "workers", runtime.NumCPU(), "number of workers reading and validating advisories in parallel")
+	flag.StringVar(&checkpointFile, "checkpoint", "", "record processed advisories in this file to resume an interrupted import")
+	flag.DurationVar(&interval, "progress", 10*time.Second, "interval of progress reports (0 disables them)")
+	flag.StringVar(&keysFile, "keys", "", "verify the signatures of the advisories against the OpenPGP keys in this file")
+	flag.BoolVar(&strict, "strict", false, "do not import advisories failing the file name, checksum or signature checks")
+	flag.TextVar(&limit, "limit", limit, "maximal size of a decompressed advisory in archives (k, K, m, M, g, G as units)")
 	flag.Parse()
 	if showVersion {
 		fmt.Printf("%s version: %s\n", os.Args[0], version.SemVersion)
 		os.Exit(0)
 	}
-	check(process(&creds, dry, importer, flag.Args(), deleteOnSuccess, moveOnErr, continueOnError))
+	im := importer{
+		dry:    dry,
+		del:    deleteOnSuccess,
+		mov:    moveOnErr,
+		ctn:    continueOnError,
+		strict: strict,
+		limit:  int64(limit),
+	}
+	if importerName != "" {
+		im.actor = &importerName
+	}
+	keys, err := loadKeys(keysFile)
+	check(err)
+	im.keys = keys
+	check(process(&creds, &im, flag.Args(), workers, checkpointFile, interval))
 }
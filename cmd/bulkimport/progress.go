@@ -0,0 +1,70 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// progress tracks the number of processed files.
+// The members of archives are added to the total when found.
+type progress struct {
+	start   time.Time
+	total   atomic.Int64
+	skipped atomic.Int64
+	done    atomic.Int64
+	failed  atomic.Int64
+}
+
+func newProgress(total, skipped int) *progress {
+	p := &progress{start: time.Now()}
+	p.total.Store(int64(total))
+	p.skipped.Store(int64(skipped))
+	return p
+}
+
+// log logs the number of processed files, the rate and the
+// estimated time until all files are processed.
+func (p *progress) log() {
+	done, total := p.done.Load(), p.total.Load()
+	elapsed := time.Since(p.start)
+	rate := float64(done) / elapsed.Seconds()
+	eta := "unknown"
+	if rate > 0 {
+		eta = (time.Duration(float64(total-done)/rate) * time.Second).String()
+	}
+	slog.Info("progress",
+		"done", done,
+		"total", total,
+		"failed", p.failed.Load(),
+		"skipped", p.skipped.Load(),
+		"rate", fmt.Sprintf("%.1f/s", rate),
+		"eta", eta)
+}
+
+// report logs the progress periodically until the context is done.
+func (p *progress) report(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.log()
+		}
+	}
+}
@@ -26,7 +26,9 @@ import (
 	"github.com/ISDuBA/ISDuBA/pkg/aggregators"
 	"github.com/ISDuBA/ISDuBA/pkg/config"
 	"github.com/ISDuBA/ISDuBA/pkg/database"
+	"github.com/ISDuBA/ISDuBA/pkg/database/query"
 	"github.com/ISDuBA/ISDuBA/pkg/forwarder"
+	"github.com/ISDuBA/ISDuBA/pkg/imports"
 	"github.com/ISDuBA/ISDuBA/pkg/sources"
 	"github.com/ISDuBA/ISDuBA/pkg/tempstore"
 	"github.com/ISDuBA/ISDuBA/pkg/version"
@@ -47,6 +49,15 @@ func run(cfg *config.Config) error {
 	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGKILL, syscall.SIGTERM)
 	defer stop()
 
+	// Make the virtual columns usable in the queries.
+	vcs := make([]query.VirtualColumn, len(cfg.VirtualColumns))
+	for i, vc := range cfg.VirtualColumns {
+		vcs[i] = query.VirtualColumn{Name: vc.Name, Path: vc.Path, Type: vc.Type}
+	}
+	if err := query.RegisterVirtualColumns(vcs); err != nil {
+		return fmt.Errorf("configuring virtual columns failed: %w", err)
+	}
+
 	terminate, err := database.CheckMigrations(ctx, &cfg.Database)
 	if err != nil {
 		return fmt.Errorf("migrating failed: %w", err)
@@ -68,9 +79,6 @@ func run(cfg *config.Config) error {
 	}
 	go forwardManager.Run(ctx)
 
-	agg := aggregators.NewManager(cfg, db)
-	go agg.Run(ctx)
-
 	// Is the remote validator configured?
 	var val csaf.RemoteValidator
 	if cfg.RemoteValidator.URL != "" {
@@ -92,6 +100,13 @@ func run(cfg *config.Config) error {
 	}
 	go sm.Run(ctx)
 
+	agg := aggregators.NewManager(cfg, db, sm)
+	go agg.Run(ctx)
+
+	importManager := imports.NewManager(
+		&cfg.Imports, int64(cfg.General.AdvisoryUploadLimit), db, val)
+	go importManager.Run(ctx)
+
 	cfg.Web.Configure()
 
 	ctrl := web.NewController(
@@ -100,6 +115,7 @@ func run(cfg *config.Config) error {
 		tmpStore,
 		sm,
 		agg,
+		importManager,
 		val,
 	)
 
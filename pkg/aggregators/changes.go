@@ -0,0 +1,173 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package aggregators
+
+import (
+	"cmp"
+	"crypto/sha1"
+	"slices"
+	"time"
+
+	"github.com/gocsaf/csaf/v3/csaf"
+)
+
+// ChangeKind is the kind of change of a provider listed in an aggregator.
+type ChangeKind string
+
+// The kinds of changes.
+const (
+	ProviderAdded   ChangeKind = "added"   // ProviderAdded is a newly listed provider.
+	ProviderRemoved ChangeKind = "removed" // ProviderRemoved is a provider not listed any more.
+	ProviderChanged ChangeKind = "changed" // ProviderChanged is a provider with changed metadata.
+	ProviderUpdated ChangeKind = "updated" // ProviderUpdated is a provider with a new last update time.
+)
+
+// Provider is the snapshot of a provider or publisher listed in an aggregator.
+type Provider struct {
+	Name        string     `json:"name"`
+	Role        string     `json:"role,omitempty"`
+	URL         string     `json:"url"`
+	Mirrors     []string   `json:"mirrors,omitempty"`
+	LastUpdated *time.Time `json:"last_updated,omitempty"`
+}
+
+// Change is a change of a provider between two snapshots.
+type Change struct {
+	Kind     ChangeKind `json:"kind"`
+	URL      string     `json:"url"`
+	Previous *Provider  `json:"previous,omitempty"`
+	Current  *Provider  `json:"current,omitempty"`
+}
+
+// Providers returns a snapshot of the providers and publishers
+// listed in the aggregator ordered by their PMD URLs.
+func (ca *CachedAggregator) Providers() []Provider {
+	var providers []Provider
+	add := func(metadata *csaf.AggregatorCSAFProviderMetadata, mirrors []csaf.ProviderURL) {
+		if metadata == nil || metadata.URL == nil {
+			return
+		}
+		p := Provider{URL: string(*metadata.URL)}
+		if slices.ContainsFunc(providers, func(o Provider) bool { return o.URL == p.URL }) {
+			return
+		}
+		if metadata.Publisher != nil && metadata.Publisher.Name != nil {
+			p.Name = *metadata.Publisher.Name
+		}
+		if metadata.Role != nil {
+			p.Role = string(*metadata.Role)
+		}
+		for _, m := range mirrors {
+			p.Mirrors = append(p.Mirrors, string(m))
+		}
+		slices.Sort(p.Mirrors)
+		if metadata.LastUpdated != nil {
+			lu := time.Time(*metadata.LastUpdated).UTC()
+			p.LastUpdated = &lu
+		}
+		providers = append(providers, p)
+	}
+	for _, provider := range ca.Aggregator.CSAFProviders {
+		if provider != nil {
+			add(provider.Metadata, provider.Mirrors)
+		}
+	}
+	for _, publisher := range ca.Aggregator.CSAFPublishers {
+		if publisher != nil {
+			add(publisher.Metadata, publisher.Mirrors)
+		}
+	}
+	slices.SortFunc(providers, func(a, b Provider) int { return cmp.Compare(a.URL, b.URL) })
+	return providers
+}
+
+// differs checks if two snapshots of a provider differ.
+// The last update time is not considered as it changes
+// with every new advisory of the provider.
+func (p *Provider) differs(o *Provider) bool {
+	return p.Name != o.Name || p.Role != o.Role || !slices.Equal(p.Mirrors, o.Mirrors)
+}
+
+// updated checks if the last update time of a provider changed.
+func (p *Provider) updated(o *Provider) bool {
+	if p.LastUpdated == nil || o.LastUpdated == nil {
+		return p.LastUpdated != o.LastUpdated
+	}
+	return !p.LastUpdated.Equal(*o.LastUpdated)
+}
+
+// needsAttention checks if a change has to be acknowledged.
+// New last update times are recorded but need no attention.
+func (ch *Change) needsAttention() bool {
+	return ch.Kind != ProviderUpdated
+}
+
+// providersChecksum calculates a checksum over the relevant
+// fields of the given sorted providers.
+func providersChecksum(providers []Provider) []byte {
+	hash := sha1.New()
+	for i := range providers {
+		p := &providers[i]
+		hash.Write([]byte(p.URL))
+		hash.Write([]byte{0})
+		hash.Write([]byte(p.Name))
+		hash.Write([]byte{0})
+		hash.Write([]byte(p.Role))
+		for _, m := range p.Mirrors {
+			hash.Write([]byte{0})
+			hash.Write([]byte(m))
+		}
+		hash.Write([]byte{1})
+	}
+	return hash.Sum(nil)
+}
+
+// diffProviders computes the changes between two sorted snapshots of providers.
+// A provider of which only the last update time changed is reported as updated.
+func diffProviders(prev, curr []Provider) []Change {
+	var changes []Change
+	i, j := 0, 0
+	for i < len(prev) || j < len(curr) {
+		switch {
+		case j >= len(curr) || i < len(prev) && prev[i].URL < curr[j].URL:
+			changes = append(changes, Change{
+				Kind:     ProviderRemoved,
+				URL:      prev[i].URL,
+				Previous: &prev[i],
+			})
+			i++
+		case i >= len(prev) || curr[j].URL < prev[i].URL:
+			changes = append(changes, Change{
+				Kind:    ProviderAdded,
+				URL:     curr[j].URL,
+				Current: &curr[j],
+			})
+			j++
+		default:
+			var kind ChangeKind
+			switch {
+			case prev[i].differs(&curr[j]):
+				kind = ProviderChanged
+			case prev[i].updated(&curr[j]):
+				kind = ProviderUpdated
+			}
+			if kind != "" {
+				changes = append(changes, Change{
+					Kind:     kind,
+					URL:      curr[j].URL,
+					Previous: &prev[i],
+					Current:  &curr[j],
+				})
+			}
+			i++
+			j++
+		}
+	}
+	return changes
+}
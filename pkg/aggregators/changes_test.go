@@ -0,0 +1,114 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package aggregators
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffProviders(t *testing.T) {
+	var (
+		t1 = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		t2 = t1.Add(time.Hour)
+	)
+	provider := func(url, name string, lastUpdated *time.Time, mirrors ...string) Provider {
+		return Provider{
+			Name:        name,
+			Role:        "csaf_trusted_provider",
+			URL:         url,
+			Mirrors:     mirrors,
+			LastUpdated: lastUpdated,
+		}
+	}
+	type change struct {
+		kind ChangeKind
+		url  string
+	}
+	for _, x := range []struct {
+		name     string
+		prev     []Provider
+		curr     []Provider
+		expected []change
+	}{{
+		name: "unchanged",
+		prev: []Provider{provider("a", "A", &t1), provider("b", "B", nil)},
+		curr: []Provider{provider("a", "A", &t1), provider("b", "B", nil)},
+	}, {
+		name:     "added",
+		prev:     []Provider{provider("b", "B", nil)},
+		curr:     []Provider{provider("a", "A", nil), provider("b", "B", nil), provider("c", "C", nil)},
+		expected: []change{{ProviderAdded, "a"}, {ProviderAdded, "c"}},
+	}, {
+		name:     "removed",
+		prev:     []Provider{provider("a", "A", nil), provider("b", "B", nil), provider("c", "C", nil)},
+		curr:     []Provider{provider("b", "B", nil)},
+		expected: []change{{ProviderRemoved, "a"}, {ProviderRemoved, "c"}},
+	}, {
+		name:     "from nothing",
+		curr:     []Provider{provider("a", "A", nil)},
+		expected: []change{{ProviderAdded, "a"}},
+	}, {
+		name:     "to nothing",
+		prev:     []Provider{provider("a", "A", nil)},
+		expected: []change{{ProviderRemoved, "a"}},
+	}, {
+		name:     "changed name",
+		prev:     []Provider{provider("a", "A", &t1)},
+		curr:     []Provider{provider("a", "B", &t2)},
+		expected: []change{{ProviderChanged, "a"}},
+	}, {
+		name:     "changed mirrors",
+		prev:     []Provider{provider("a", "A", nil, "m1")},
+		curr:     []Provider{provider("a", "A", nil, "m1", "m2")},
+		expected: []change{{ProviderChanged, "a"}},
+	}, {
+		name:     "updated",
+		prev:     []Provider{provider("a", "A", &t1), provider("b", "B", nil)},
+		curr:     []Provider{provider("a", "A", &t2), provider("b", "B", &t1)},
+		expected: []change{{ProviderUpdated, "a"}, {ProviderUpdated, "b"}},
+	}, {
+		name:     "same update time",
+		prev:     []Provider{provider("a", "A", &t1)},
+		curr:     []Provider{provider("a", "A", new(t1.In(time.FixedZone("X", 3600))))},
+		expected: nil,
+	}, {
+		name: "mixed",
+		prev: []Provider{provider("a", "A", nil), provider("c", "C", &t1), provider("d", "D", nil)},
+		curr: []Provider{provider("b", "B", nil), provider("c", "C", &t2), provider("d", "E", nil)},
+		expected: []change{
+			{ProviderRemoved, "a"},
+			{ProviderAdded, "b"},
+			{ProviderUpdated, "c"},
+			{ProviderChanged, "d"},
+		},
+	}} {
+		t.Run(x.name, func(t *testing.T) {
+			changes := diffProviders(x.prev, x.curr)
+			if len(changes) != len(x.expected) {
+				t.Fatalf("got %d changes, expected %d: %+v", len(changes), len(x.expected), changes)
+			}
+			for i, ch := range changes {
+				if ch.Kind != x.expected[i].kind || ch.URL != x.expected[i].url {
+					t.Errorf("change %d: got %s %q, expected %s %q",
+						i, ch.Kind, ch.URL, x.expected[i].kind, x.expected[i].url)
+				}
+				if (ch.Previous == nil) != (ch.Kind == ProviderAdded) {
+					t.Errorf("change %d: unexpected previous %+v", i, ch.Previous)
+				}
+				if (ch.Current == nil) != (ch.Kind == ProviderRemoved) {
+					t.Errorf("change %d: unexpected current %+v", i, ch.Current)
+				}
+				if ch.needsAttention() == (ch.Kind == ProviderUpdated) {
+					t.Errorf("change %d: unexpected attention for %s", i, ch.Kind)
+				}
+			}
+		})
+	}
+}
@@ -12,14 +12,14 @@ package aggregators
 import (
 	"bytes"
 	"context"
-	"crypto/sha1"
+	"encoding/json"
 	"log/slog"
-	"slices"
 	"sync"
 	"time"
 
 	"github.com/ISDuBA/ISDuBA/pkg/config"
 	"github.com/ISDuBA/ISDuBA/pkg/database"
+	"github.com/ISDuBA/ISDuBA/pkg/sources"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -34,15 +34,18 @@ type Manager struct {
 	fns  chan func(*Manager)
 	cfg  *config.Config
 	db   *database.DB
+	sm   *sources.Manager
 }
 
 // NewManager creates a new aggregators manager.
-func NewManager(cfg *config.Config, db *database.DB) *Manager {
+// The sources manager is used to apply the subscription policies.
+func NewManager(cfg *config.Config, db *database.DB, sm *sources.Manager) *Manager {
 	return &Manager{
 		Cache: newCache(cfg.Aggregators.Timeout),
 		fns:   make(chan func(*Manager)),
 		cfg:   cfg,
 		db:    db,
+		sm:    sm,
 	}
 }
 
@@ -66,29 +69,34 @@ func (m *Manager) Run(ctx context.Context) {
 	}
 }
 
-func aggregatorChecksum(cagg *CachedAggregator) []byte {
-	hash := sha1.New()
-	urls := cagg.SourceURLs()
-	// Ensure same hash on different order
-	slices.Sort(urls)
-	for _, url := range urls {
-		hash.Write([]byte(url))
-	}
-	return hash.Sum(nil)
-}
-
 func (m *Manager) refresh(ctx context.Context) {
 	type aggregator struct {
-		id          int64
-		url         string
-		checksum    []byte
-		newChecksum []byte
+		id           int64
+		name         string
+		url          string
+		policy       subscriptionPolicy
+		checksum     []byte
+		snapshot     []Provider
+		hasSnapshot  bool
+		newChecksum  []byte
+		newSnapshot  []Provider
+		fetchSuccess bool
 	}
 	const (
-		selectSQL = `SELECT id, url, checksum FROM aggregators`
+		selectSQL = `SELECT id, name, url, checksum, ` +
+			`policy::text, policy_roles, policy_tlps, policy_rate, policy_slots, ` +
+			`(SELECT providers FROM aggregator_snapshots ` +
+			`WHERE aggregators_id = aggregators.id ORDER BY id DESC LIMIT 1) ` +
+			`FROM aggregators WHERE active`
 		updateSQL = `UPDATE aggregators ` +
 			`SET (checksum, checksum_updated) = ($1, $2) ` +
 			`WHERE id = $3 AND active = TRUE`
+		updateChecksumSQL = `UPDATE aggregators SET checksum = $1 WHERE id = $2`
+		insertSnapshotSQL = `INSERT INTO aggregator_snapshots (aggregators_id, time, providers) ` +
+			`VALUES ($1, $2, $3) RETURNING id`
+		insertChangeSQL = `INSERT INTO aggregator_changes ` +
+			`(aggregators_id, snapshots_id, time, kind, url, previous, current, acknowledged) ` +
+			`VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
 	)
 	var aggregators []aggregator
 	if err := m.db.Run(
@@ -97,9 +105,25 @@ func (m *Manager) refresh(ctx context.Context) {
 			rows, _ := conn.Query(ctx, selectSQL)
 			var err error
 			aggregators, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (aggregator, error) {
-				var agg aggregator
-				err := row.Scan(&agg.id, &agg.url, &agg.checksum)
-				return agg, err
+				var (
+					agg      aggregator
+					snapshot []byte
+				)
+				if err := row.Scan(
+					&agg.id, &agg.name, &agg.url, &agg.checksum,
+					&agg.policy.policy, &agg.policy.roles, &agg.policy.tlps,
+					&agg.policy.rate, &agg.policy.slots,
+					&snapshot,
+				); err != nil {
+					return agg, err
+				}
+				if snapshot != nil {
+					if err := json.Unmarshal(snapshot, &agg.snapshot); err != nil {
+						return agg, err
+					}
+					agg.hasSnapshot = true
+				}
+				return agg, nil
 			})
 			return err
 		}, 0,
@@ -123,7 +147,9 @@ func (m *Manager) refresh(ctx context.Context) {
 				slog.Warn("fetching aggregator failed", "url", agg.url, "err", err)
 				continue
 			}
-			agg.newChecksum = aggregatorChecksum(cagg)
+			agg.newSnapshot = cagg.Providers()
+			agg.newChecksum = providersChecksum(agg.newSnapshot)
+			agg.fetchSuccess = true
 		}
 	}
 	for range numWorkers {
@@ -135,19 +161,8 @@ func (m *Manager) refresh(ctx context.Context) {
 	}
 	close(toFetch)
 	wg.Wait()
-	var (
-		batch pgx.Batch
-		now   = time.Now()
-	)
-	for i := range aggregators {
-		agg := &aggregators[i]
-		if !bytes.Equal(agg.checksum, agg.newChecksum) {
-			batch.Queue(updateSQL, agg.newChecksum, now, agg.id)
-		}
-	}
-	if batch.Len() == 0 {
-		return
-	}
+	now := time.Now()
+	var pending []pendingSubscription
 	if err := m.db.Run(
 		ctx,
 		func(ctx context.Context, conn *pgxpool.Conn) error {
@@ -156,14 +171,83 @@ func (m *Manager) refresh(ctx context.Context) {
 				return err
 			}
 			defer tx.Rollback(ctx)
-			if err := tx.SendBatch(ctx, &batch).Close(); err != nil {
-				return err
+			pending = pending[:0]
+			for i := range aggregators {
+				agg := &aggregators[i]
+				if !agg.fetchSuccess {
+					continue
+				}
+				// The snapshot is stored if anything changed, even
+				// if it is only the last update time of a provider.
+				var changes []Change
+				if agg.hasSnapshot {
+					if changes = diffProviders(agg.snapshot, agg.newSnapshot); len(changes) == 0 {
+						continue
+					}
+				}
+				snapshot, err := json.Marshal(agg.newSnapshot)
+				if err != nil {
+					return err
+				}
+				var snapshotID int64
+				if err := tx.QueryRow(
+					ctx, insertSnapshotSQL, agg.id, now, snapshot,
+				).Scan(&snapshotID); err != nil {
+					return err
+				}
+				if !agg.hasSnapshot {
+					// Without a previous snapshot there are no changes to record.
+					// Only aggregators never checked before need attention.
+					if agg.checksum == nil {
+						_, err = tx.Exec(ctx, updateSQL, agg.newChecksum, now, agg.id)
+					} else {
+						_, err = tx.Exec(ctx, updateChecksumSQL, agg.newChecksum, agg.id)
+					}
+					if err != nil {
+						return err
+					}
+					continue
+				}
+				// The open changes tell if the aggregator needs attention.
+				var batch pgx.Batch
+				for _, ch := range changes {
+					var acknowledged *time.Time
+					if !ch.needsAttention() {
+						acknowledged = &now
+					}
+					q := batch.Queue(insertChangeSQL,
+						agg.id, snapshotID, now, string(ch.Kind), ch.URL,
+						ch.Previous, ch.Current, acknowledged)
+					if agg.policy.applies(&ch) {
+						q.QueryRow(func(row pgx.Row) error {
+							ps := pendingSubscription{
+								aggregator: agg.name,
+								policy:     &agg.policy,
+								provider:   ch.Current,
+							}
+							if err := row.Scan(&ps.changeID); err != nil {
+								return err
+							}
+							pending = append(pending, ps)
+							return nil
+						})
+					}
+				}
+				if !bytes.Equal(agg.checksum, agg.newChecksum) {
+					batch.Queue(updateChecksumSQL, agg.newChecksum, agg.id)
+				}
+				if err := tx.SendBatch(ctx, &batch).Close(); err != nil {
+					return err
+				}
 			}
 			return tx.Commit(ctx)
 		}, 0,
 	); err != nil {
-		slog.Error("fetching aggregators failed", "error", err)
+		slog.Error("storing aggregator changes failed", "error", err)
+		return
 	}
+	// Creating sources needs the changes to be stored first.
+	m.applyPolicies(ctx, pending)
 }
 
 func (m *Manager) kill() { m.done = true }
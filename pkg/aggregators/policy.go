@@ -0,0 +1,131 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package aggregators
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/ISDuBA/ISDuBA/pkg/sources"
+	"github.com/gocsaf/csaf/v3/csaf"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Policy tells what to do with providers newly listed in an aggregator.
+type Policy string
+
+// The auto-subscription policies.
+const (
+	// PolicyNone only records the new provider.
+	PolicyNone Policy = "none"
+	// PolicyCreateInactive creates an inactive source for the new provider.
+	PolicyCreateInactive Policy = "create_inactive"
+	// PolicyCreateActive creates and activates a source for the new provider.
+	PolicyCreateActive Policy = "create_active"
+)
+
+// ParsePolicy parses a policy from a string.
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case PolicyNone, PolicyCreateInactive, PolicyCreateActive:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid policy %q", s)
+}
+
+// subscriptionPolicy is the auto-subscription policy of an aggregator.
+type subscriptionPolicy struct {
+	policy Policy
+	roles  []string
+	tlps   []string
+	rate   *float64
+	slots  *int
+}
+
+// applies checks if the policy applies to a given change.
+func (sp *subscriptionPolicy) applies(ch *Change) bool {
+	return sp.policy != PolicyNone &&
+		ch.Kind == ProviderAdded &&
+		ch.Current != nil &&
+		slices.Contains(sp.roles, ch.Current.Role)
+}
+
+// pendingSubscription is a change the policy of its aggregator applies to.
+type pendingSubscription struct {
+	changeID   int64
+	aggregator string
+	policy     *subscriptionPolicy
+	provider   *Provider
+}
+
+// subscribe applies the policy by creating a source for the
+// new provider. It returns a description of the action
+// and the id of the created source if any.
+func (ps *pendingSubscription) subscribe(sm *sources.Manager) (string, *int64) {
+	subscribed, err := sm.Subscribed(ps.provider.URL)
+	if err != nil {
+		return fmt.Sprintf("No source created: %v.", err), nil
+	}
+	if subscribed {
+		return "No source created: provider is already subscribed.", nil
+	}
+	tlps := make([]csaf.TLPLabel, len(ps.policy.tlps))
+	for i, tlp := range ps.policy.tlps {
+		tlps[i] = csaf.TLPLabel(tlp)
+	}
+	active := ps.policy.policy == PolicyCreateActive
+	ss, err := sm.Subscribe(&sources.AutoSubscription{
+		Name:    ps.provider.Name,
+		URL:     ps.provider.URL,
+		Mirrors: ps.provider.Mirrors,
+		Active:  active,
+		TLPs:    tlps,
+		Rate:    ps.policy.rate,
+		Slots:   ps.policy.slots,
+		Status:  fmt.Sprintf("Created automatically from aggregator %q.", ps.aggregator),
+	})
+	switch {
+	case ss == nil:
+		return fmt.Sprintf("No source created: %v.", err), nil
+	case err != nil:
+		return fmt.Sprintf("Created inactive source %q but setting it up failed: %v.", ss.Name, err), &ss.ID
+	case ss.Active:
+		return fmt.Sprintf("Created active source %q.", ss.Name), &ss.ID
+	case active:
+		return fmt.Sprintf("Created inactive source %q as no feeds match.", ss.Name), &ss.ID
+	default:
+		return fmt.Sprintf("Created inactive source %q.", ss.Name), &ss.ID
+	}
+}
+
+// applyPolicies creates the sources for the pending subscriptions
+// and records the actions in the change history.
+func (m *Manager) applyPolicies(ctx context.Context, pending []pendingSubscription) {
+	const updateSQL = `UPDATE aggregator_changes ` +
+		`SET (action, sources_id) = ($1, $2) WHERE id = $3`
+	for i := range pending {
+		ps := &pending[i]
+		action, sourceID := ps.subscribe(m.sm)
+		slog.Info("applied aggregator policy",
+			"aggregator", ps.aggregator,
+			"provider", ps.provider.URL,
+			"action", action)
+		if err := m.db.Run(
+			ctx,
+			func(ctx context.Context, conn *pgxpool.Conn) error {
+				_, err := conn.Exec(ctx, updateSQL, action, sourceID, ps.changeID)
+				return err
+			}, 0,
+		); err != nil {
+			slog.Error("recording aggregator policy action failed", "error", err)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+// Package archive reads advisories from zip and tar archives.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrTooLarge is returned if an advisory or a companion file
+// exceeds the size limit when decompressed.
+var ErrTooLarge = errors.New("file too large")
+
+// CompanionExts are the extensions of the signature and hash
+// files lying next to an advisory.
+var CompanionExts = []string{".asc", ".sha512", ".sha256"}
+
+// Member is an advisory of an archive together with
+// the signature and hash files found next to it.
+type Member struct {
+	Name       string            // Name is the path of the advisory in the archive.
+	Data       []byte            // Data is the content of the advisory.
+	Companions map[string][]byte // Companions are the contents of the companion files by extension.
+	Err        error             // Err tells why the advisory could not be read.
+}
+
+// IsAdvisory checks if a file looks like an advisory to import.
+func IsAdvisory(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasSuffix(lower, ".json") || strings.HasSuffix(lower, "json.gz")
+}
+
+// IsArchive checks if a file is an archive of advisories.
+func IsArchive(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz", ".tar.zst", ".tzst"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGzipped checks if an advisory is compressed.
+func isGzipped(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ".gz")
+}
+
+// AdvisoryName strips the compression suffix from the name of an advisory.
+func AdvisoryName(name string) string {
+	if isGzipped(name) {
+		return name[:len(name)-len(".gz")]
+	}
+	return name
+}
+
+// readLimited reads at most limit bytes from r.
+// It returns [ErrTooLarge] if there are more.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, limit)
+	}
+	return data, nil
+}
+
+// Uncompress decompresses the content of an advisory
+// if its name tells that it is gzipped. The decompressed
+// content is limited to limit bytes.
+func Uncompress(name string, data []byte, limit int64) ([]byte, error) {
+	if !isGzipped(name) {
+		return data, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return readLimited(gz, limit)
+}
+
+// splitCompanion splits the name of a companion file into the
+// name of its advisory and its extension.
+func splitCompanion(name string) (string, string, bool) {
+	lower := strings.ToLower(name)
+	for _, ext := range CompanionExts {
+		if !strings.HasSuffix(lower, ext) {
+			continue
+		}
+		if base := name[:len(name)-len(ext)]; IsAdvisory(base) {
+			return AdvisoryName(base), ext, true
+		}
+	}
+	return "", "", false
+}
+
+func newMember(name string) *Member {
+	return &Member{Name: name, Companions: map[string][]byte{}}
+}
+
+// complete checks if all companion files worth waiting for are found.
+func (m *Member) complete() bool {
+	return m.Companions[".asc"] != nil && m.Companions[".sha512"] != nil
+}
+
+// ReadFile reads the advisories of an archive file.
+// See [Read].
+func ReadFile(file string, limit int64, emit func(*Member) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return Read(file, f, fi.Size(), limit, emit)
+}
+
+// Read reads the advisories of an archive and passes them
+// with their companion files to emit. The kind of the archive
+// is derived from its name. An error returned by emit stops the reading.
+// Advisories and companion files larger than limit bytes are not read.
+// These advisories are passed with [ErrTooLarge] in their Err field,
+// these companion files are left out.
+func Read(name string, r io.ReaderAt, size int64, limit int64, emit func(*Member) error) error {
+	lower := strings.ToLower(name)
+	if strings.HasSuffix(lower, ".zip") {
+		return readZip(r, size, limit, emit)
+	}
+	var tr io.Reader = io.NewSectionReader(r, 0, size)
+	switch {
+	case strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz"):
+		gz, err := gzip.NewReader(tr)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		tr = gz
+	case strings.HasSuffix(lower, ".zst") || strings.HasSuffix(lower, ".tzst"):
+		zr, err := zstd.NewReader(tr)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		tr = zr
+	}
+	return readTar(tar.NewReader(tr), limit, emit)
+}
+
+// readZip reads the advisories of a zip archive.
+func readZip(r io.ReaderAt, size int64, limit int64, emit func(*Member) error) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	read := func(f *zip.File) ([]byte, error) {
+		// The declared size may lie so the content is limited, too.
+		if f.UncompressedSize64 > uint64(limit) {
+			return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, limit)
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return readLimited(r, limit)
+	}
+
+	byName := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		byName[path.Clean(f.Name)] = f
+	}
+	for _, f := range zr.File {
+		name := path.Clean(f.Name)
+		if !f.Mode().IsRegular() || !IsAdvisory(name) {
+			continue
+		}
+		m := newMember(name)
+		switch m.Data, err = read(f); {
+		case errors.Is(err, ErrTooLarge):
+			m.Err = err
+		case err != nil:
+			return fmt.Errorf("reading %q failed: %w", name, err)
+		}
+		base := AdvisoryName(name)
+		for _, ext := range CompanionExts {
+			if c := byName[base+ext]; c != nil && m.Err == nil {
+				switch data, err := read(c); {
+				case errors.Is(err, ErrTooLarge):
+				case err != nil:
+					return fmt.Errorf("reading %q failed: %w", c.Name, err)
+				default:
+					m.Companions[ext] = data
+				}
+			}
+		}
+		if err := emit(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxPending is the number of advisories and of orphaned
+// companion files held back at most by the tar grouper.
+const maxPending = 64
+
+// grouper collects the advisories of a tar archive with their
+// companion files. As the members of a tar archive can only be
+// read one after the other the advisories are held back until their
+// companion files are found or the archive moves on to another directory.
+// Only the last [maxPending] advisories are held back so large directories
+// are not kept in memory. The same applies to companion files found
+// before their advisories.
+type grouper struct {
+	emit        func(*Member) error
+	dir         string
+	order       []string
+	pending     map[string]*Member
+	orphanOrder []string
+	orphans     map[string]map[string][]byte
+}
+
+func newGrouper(emit func(*Member) error) *grouper {
+	return &grouper{
+		emit:    emit,
+		pending: map[string]*Member{},
+		orphans: map[string]map[string][]byte{},
+	}
+}
+
+// add adds an advisory or a companion file.
+func (g *grouper) add(name string, data []byte) error {
+	// Advisories and their companion files are in the same directory.
+	if dir := path.Dir(name); dir != g.dir {
+		if err := g.flush(); err != nil {
+			return err
+		}
+		g.dir = dir
+	}
+	if IsAdvisory(name) {
+		base := AdvisoryName(name)
+		m := newMember(name)
+		m.Data = data
+		if companions := g.orphans[base]; companions != nil {
+			m.Companions = companions
+			delete(g.orphans, base)
+		}
+		if m.complete() {
+			return g.emit(m)
+		}
+		g.pending[base] = m
+		g.order = append(g.order, base)
+		return g.limit()
+	}
+	base, ext, ok := splitCompanion(name)
+	if !ok {
+		return nil
+	}
+	if m := g.pending[base]; m != nil {
+		m.Companions[ext] = data
+		if m.complete() {
+			delete(g.pending, base)
+			return g.emit(m)
+		}
+		return nil
+	}
+	companions := g.orphans[base]
+	if companions == nil {
+		companions = map[string][]byte{}
+		g.orphans[base] = companions
+		g.orphanOrder = append(g.orphanOrder, base)
+		g.limitOrphans()
+	}
+	companions[ext] = data
+	return nil
+}
+
+// limit emits the oldest held back advisories
+// if there are more than [maxPending].
+func (g *grouper) limit() error {
+	// Forget the advisories already emitted.
+	if len(g.order) > 2*maxPending {
+		g.order = slices.DeleteFunc(g.order, func(base string) bool {
+			return g.pending[base] == nil
+		})
+	}
+	for len(g.pending) > maxPending {
+		base := g.order[0]
+		g.order = g.order[1:]
+		if m := g.pending[base]; m != nil {
+			delete(g.pending, base)
+			if err := g.emit(m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// limitOrphans drops the oldest companion files without
+// advisories if there are more than [maxPending].
+func (g *grouper) limitOrphans() {
+	// Forget the companion files already taken by their advisories.
+	if len(g.orphanOrder) > 2*maxPending {
+		g.orphanOrder = slices.DeleteFunc(g.orphanOrder, func(base string) bool {
+			return g.orphans[base] == nil
+		})
+	}
+	for len(g.orphans) > maxPending {
+		delete(g.orphans, g.orphanOrder[0])
+		g.orphanOrder = g.orphanOrder[1:]
+	}
+}
+
+// flush emits the held back advisories.
+func (g *grouper) flush() error {
+	for _, base := range g.order {
+		if m := g.pending[base]; m != nil {
+			if err := g.emit(m); err != nil {
+				return err
+			}
+		}
+	}
+	g.order = g.order[:0]
+	g.orphanOrder = g.orphanOrder[:0]
+	clear(g.pending)
+	clear(g.orphans)
+	return nil
+}
+
+// readTar reads the advisories of a tar archive.
+func readTar(tr *tar.Reader, limit int64, emit func(*Member) error) error {
+	g := newGrouper(emit)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return g.flush()
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean(hdr.Name)
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		advisory := IsAdvisory(name)
+		if _, _, ok := splitCompanion(name); !ok && !advisory {
+			continue
+		}
+		data, err := readLimited(tr, limit)
+		switch {
+		case errors.Is(err, ErrTooLarge):
+			// Too large companion files are left out.
+			if advisory {
+				m := newMember(name)
+				m.Err = err
+				if err := emit(m); err != nil {
+					return err
+				}
+			}
+			continue
+		case err != nil:
+			return fmt.Errorf("reading %q failed: %w", name, err)
+		}
+		if err := g.add(name, data); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestReadTarWindow(t *testing.T) {
+	const num = 3 * maxPending
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	add := func(name string) {
+		data := []byte(name)
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0o644,
+			Size:     int64(len(data)),
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The companion files follow after all advisories so
+	// only the last ones still wait for them.
+	for i := range num {
+		add(fmt.Sprintf("white/2026/doc-%03d.json", i))
+	}
+	for i := range num {
+		name := fmt.Sprintf("white/2026/doc-%03d.json", i)
+		add(name + ".asc")
+		add(name + ".sha512")
+	}
+	// A companion file before its advisory.
+	add("white/2025/early.json.asc")
+	add("white/2025/early.json.sha512")
+	add("white/2025/early.json")
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var members []*Member
+	data := buf.Bytes()
+	if err := Read("test.tar", bytes.NewReader(data), int64(len(data)), 1024,
+		func(m *Member) error {
+			members = append(members, m)
+			return nil
+		}); err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != num+1 {
+		t.Fatalf("got %d advisories, expected %d", len(members), num+1)
+	}
+	for i, m := range members[:num] {
+		expected := fmt.Sprintf("white/2026/doc-%03d.json", i)
+		if m.Name != expected {
+			t.Errorf("advisory %d: got %q, expected %q", i, m.Name, expected)
+		}
+		if complete := i >= num-maxPending; m.complete() != complete {
+			t.Errorf("advisory %q: complete %t, expected %t", m.Name, m.complete(), complete)
+		}
+	}
+	if m := members[num]; m.Name != "white/2025/early.json" || !m.complete() {
+		t.Errorf("advisory %q not complete", m.Name)
+	}
+}
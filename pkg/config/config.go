@@ -10,10 +10,12 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -76,6 +78,8 @@ type Database struct {
 	Migrate                 bool          `toml:"migrate"`
 	TerminateAfterMigration bool          `toml:"terminate_after_migration"`
 	MaxQueryDuration        time.Duration `toml:"max_query_duration"`
+	MaxQueryCost            float64       `toml:"max_query_cost"`
+	SlowQueryDuration       time.Duration `toml:"slow_query_duration"`
 }
 
 // TempStore are the config options for the temporary document storage.
@@ -85,6 +89,14 @@ type TempStore struct {
 	StorageDuration time.Duration `toml:"storage_duration"`
 }
 
+// Imports are the config options for the import jobs of uploaded archives.
+type Imports struct {
+	ArchiveUploadLimit HumanSize     `toml:"archive_upload_limit"`
+	JobsUser           int           `toml:"jobs_user"`
+	ParallelJobs       int           `toml:"parallel_jobs"`
+	KeepJobs           time.Duration `toml:"keep_jobs"`
+}
+
 // Sources are the config options for downloading sources.
 type Sources struct {
 	DownloadSlots     int                   `toml:"download_slots"`
@@ -132,6 +144,24 @@ type Aggregators struct {
 	UpdateInterval time.Duration `toml:"update_interval"`
 }
 
+// Mirror are the config options for serving downloaded documents
+// in the layout of a CSAF provider.
+type Mirror struct {
+	Enabled        bool                  `toml:"enabled"`
+	Path           string                `toml:"path"`
+	Sources        []string              `toml:"sources"`
+	PublishersTLPs models.PublishersTLPs `toml:"publishers_tlps"`
+	Publisher      csaf.Publisher        `toml:"publisher"`
+}
+
+// VirtualColumn are the config options of a column whose
+// values are taken from the documents by a JSONPath expression.
+type VirtualColumn struct {
+	Name string `toml:"name"`
+	Path string `toml:"path"`
+	Type string `toml:"type"`
+}
+
 // Client are the config options for the client.
 type Client struct {
 	KeycloakURL      string        `toml:"keycloak_url" json:"keycloak_url"`
@@ -150,11 +180,14 @@ type Config struct {
 	Database        Database                    `toml:"database"`
 	PublishersTLPs  models.PublishersTLPs       `toml:"publishers_tlps"`
 	TempStore       TempStore                   `toml:"temp_storage"`
+	Imports         Imports                     `toml:"imports"`
 	Sources         Sources                     `toml:"sources"`
 	RemoteValidator csaf.RemoteValidatorOptions `toml:"remote_validator"`
 	Client          Client                      `toml:"client"`
 	Forwarder       Forwarder                   `toml:"forwarder"`
 	Aggregators     Aggregators                 `toml:"aggregators"`
+	Mirror          Mirror                      `toml:"mirror"`
+	VirtualColumns  []VirtualColumn             `toml:"virtual_columns"`
 }
 
 func escape(s string) string {
@@ -289,6 +322,8 @@ func Load(file string) (*Config, error) {
 			Migrate:                 defaultDatabaseMigrate,
 			TerminateAfterMigration: defaultDatabaseTerminateAfterMigration,
 			MaxQueryDuration:        defaultMaxQueryDuration,
+			MaxQueryCost:            defaultMaxQueryCost,
+			SlowQueryDuration:       defaultSlowQueryDuration,
 		},
 		PublishersTLPs: defaultPublishersTLPs,
 		TempStore: TempStore{
@@ -296,6 +331,12 @@ func Load(file string) (*Config, error) {
 			FilesUser:       defaultTempStorageFilesUser,
 			StorageDuration: defaultTempStorageDuration,
 		},
+		Imports: Imports{
+			ArchiveUploadLimit: defaultImportsArchiveUploadLimit,
+			JobsUser:           defaultImportsJobsUser,
+			ParallelJobs:       defaultImportsParallelJobs,
+			KeepJobs:           defaultImportsKeepJobs,
+		},
 		Sources: Sources{
 			DownloadSlots:     defaultSourcesDownloadSlots,
 			MaxSlotsPerSource: defaultSourcesMaxSlotsPerSource,
@@ -333,6 +374,11 @@ func Load(file string) (*Config, error) {
 			Timeout:        defaultAggregatorsTimeout,
 			UpdateInterval: defaultAggregatorsUpdateInterval,
 		},
+		Mirror: Mirror{
+			Enabled:        defaultMirrorEnabled,
+			Path:           defaultMirrorPath,
+			PublishersTLPs: defaultMirrorPublishersTLPs,
+		},
 	}
 	if file != "" {
 		md, err := toml.DecodeFile(file, cfg)
@@ -355,7 +401,36 @@ func Load(file string) (*Config, error) {
 }
 
 func (cfg *Config) validate() error {
-	return cfg.Forwarder.validate()
+	if err := cfg.Forwarder.validate(); err != nil {
+		return err
+	}
+	return cfg.Mirror.validate(cfg.Web.ExternalURL)
+}
+
+// validate checks the mirror configuration. The absolute URLs
+// published by the mirror are derived from the external URL.
+func (m *Mirror) validate(externalURL string) error {
+	if !m.Enabled {
+		return nil
+	}
+	if externalURL == "" {
+		return errors.New("mirror needs web external_url to be set")
+	}
+	if u, err := url.Parse(externalURL); err != nil ||
+		(u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("web external_url %q is not an absolute http(s) URL", externalURL)
+	}
+	if !strings.HasPrefix(m.Path, "/") || m.Path == "/" ||
+		m.Path == "/api" || strings.HasPrefix(m.Path, "/api/") {
+		return fmt.Errorf("mirror path %q is invalid", m.Path)
+	}
+	if m.Publisher.Name == nil || *m.Publisher.Name == "" {
+		return errors.New("mirror publisher name is missing")
+	}
+	if m.Publisher.Namespace == nil || *m.Publisher.Namespace == "" {
+		return errors.New("mirror publisher namespace is missing")
+	}
+	return nil
 }
 
 func (f *Forwarder) validate() error {
@@ -399,6 +474,10 @@ func (cfg *Config) presetEmptyDefaults() {
 	if cfg.Client.KeycloakURL == "" {
 		cfg.Client.KeycloakURL = cfg.Keycloak.URL
 	}
+	if cfg.Mirror.Publisher.Category == nil {
+		category := defaultMirrorPublisherCategory
+		cfg.Mirror.Publisher.Category = &category
+	}
 }
 
 func (cfg *Config) fillFromEnv() error {
@@ -441,9 +520,15 @@ func (cfg *Config) fillFromEnv() error {
 		envStore{"ISDUBA_DB_MIGRATE", storeBool(&cfg.Database.Migrate)},
 		envStore{"ISDUBA_DB_TERMINATE_AFTER_MIGRATION", storeBool(&cfg.Database.TerminateAfterMigration)},
 		envStore{"ISDUBA_DB_MAX_QUERY_DURATION", storeDuration(&cfg.Database.MaxQueryDuration)},
+		envStore{"ISDUBA_DB_MAX_QUERY_COST", storeFloat64(&cfg.Database.MaxQueryCost)},
+		envStore{"ISDUBA_DB_SLOW_QUERY_DURATION", storeDuration(&cfg.Database.SlowQueryDuration)},
 		envStore{"ISDUBA_TEMP_STORAGE_FILES_TOTAL", storeInt(&cfg.TempStore.FilesTotal)},
 		envStore{"ISDUBA_TEMP_STORAGE_FILES_USER", storeInt(&cfg.TempStore.FilesUser)},
 		envStore{"ISDUBA_TEMP_STORAGE_DURATION", storeDuration(&cfg.TempStore.StorageDuration)},
+		envStore{"ISDUBA_IMPORTS_ARCHIVE_UPLOAD_LIMIT", storeHumanSize(&cfg.Imports.ArchiveUploadLimit)},
+		envStore{"ISDUBA_IMPORTS_JOBS_USER", storeInt(&cfg.Imports.JobsUser)},
+		envStore{"ISDUBA_IMPORTS_PARALLEL_JOBS", storeInt(&cfg.Imports.ParallelJobs)},
+		envStore{"ISDUBA_IMPORTS_KEEP_JOBS", storeDuration(&cfg.Imports.KeepJobs)},
 		envStore{"ISDUBA_SOURCES_DOWNLOAD_SLOTS", storeInt(&cfg.Sources.DownloadSlots)},
 		envStore{"ISDUBA_SOURCES_MAX_SLOTS_PER_SOURCE", storeInt(&cfg.Sources.MaxSlotsPerSource)},
 		envStore{"ISDUBA_SOURCES_MAX_RATE_PER_SOURCE", storeFloat64(&cfg.Sources.MaxRatePerSource)},
@@ -471,5 +556,7 @@ func (cfg *Config) fillFromEnv() error {
 		envStore{"ISDUBA_FORWARDER_STRATEGY", storeForwarderStrategy(&cfg.Forwarder.Strategy)},
 		envStore{"ISDUBA_AGGREGATORS_TIMEOUT", storeDuration(&cfg.Aggregators.Timeout)},
 		envStore{"ISDUBA_AGGREGATORS_UPDATE_INTERVAL", storeDuration(&cfg.Aggregators.UpdateInterval)},
+		envStore{"ISDUBA_MIRROR_ENABLED", storeBool(&cfg.Mirror.Enabled)},
+		envStore{"ISDUBA_MIRROR_PATH", storeString(&cfg.Mirror.Path)},
 	)
 }
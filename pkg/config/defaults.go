@@ -12,6 +12,8 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/gocsaf/csaf/v3/csaf"
+
 	"github.com/ISDuBA/ISDuBA/pkg/models"
 )
 
@@ -76,6 +78,8 @@ const (
 	defaultDatabaseMigrate                 = false
 	defaultDatabaseTerminateAfterMigration = true
 	defaultMaxQueryDuration                = 30 * time.Second
+	defaultMaxQueryCost                    = 0
+	defaultSlowQueryDuration               = 5 * time.Second
 )
 
 var (
@@ -98,6 +102,13 @@ const (
 	defaultTempStorageDuration   = 30 * time.Minute
 )
 
+const (
+	defaultImportsArchiveUploadLimit = 2 * 1024 * 1024 * 1024
+	defaultImportsJobsUser           = 2
+	defaultImportsParallelJobs       = 1
+	defaultImportsKeepJobs           = 24 * time.Hour
+)
+
 const (
 	defaultSourcesDownloadSlots     = 100
 	defaultSourcesMaxSlotsPerSource = 2
@@ -140,3 +151,13 @@ const (
 	defaultAggregatorsTimeout        = 30 * time.Second
 	defaultAggregatorsUpdateInterval = 1 * time.Hour
 )
+
+const (
+	defaultMirrorEnabled           = false
+	defaultMirrorPath              = "/mirror"
+	defaultMirrorPublisherCategory = csaf.CSAFCategoryOther
+)
+
+var defaultMirrorPublishersTLPs = models.PublishersTLPs{
+	"*": []models.TLP{models.TLPWhite},
+}
@@ -15,7 +15,7 @@ import (
 	"strings"
 )
 
-// HumanSize de-serializes sizes from integer strings
+// HumanSize (de-)serializes sizes from/to integer strings
 // with suffix "k" (1000), "K" (1024), "m", "M", "g", "G".
 // With no suffix given bytes are assumed.
 type HumanSize int64
@@ -75,6 +75,18 @@ noUnits:
 	return nil
 }
 
+// MarshalText implements [encoding.TextMarshaler].
+func (hs HumanSize) MarshalText() ([]byte, error) {
+	x, unit := int64(hs), ""
+	for _, u := range []string{"K", "M", "G"} {
+		if x == 0 || x%1024 != 0 {
+			break
+		}
+		x, unit = x/1024, u
+	}
+	return []byte(strconv.FormatInt(x, 10) + unit), nil
+}
+
 // String implements [fmt.Stringer].
 func (fll FeedLogLevel) String() string {
 	switch fll {
@@ -207,6 +207,52 @@ CREATE TABLE documents_texts (
 CREATE INDEX ON documents_texts(documents_id);
 CREATE INDEX ON documents_texts(txt_id);
 
+-- text_search_config maps the language of a document
+-- to the text search configuration used for stemming.
+CREATE FUNCTION text_search_config(lang text) RETURNS regconfig AS $$
+    SELECT CASE lower(split_part(lang, '-', 1))
+        WHEN 'de' THEN 'german'
+        WHEN 'en' THEN 'english'
+        ELSE 'simple'
+    END::regconfig
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- documents_search holds the stemmed texts of the documents for ranked searches.
+CREATE TABLE documents_search (
+    documents_id int       PRIMARY KEY REFERENCES documents(id) ON DELETE CASCADE,
+    config       regconfig NOT NULL,
+    ts           tsvector  NOT NULL
+);
+
+CREATE INDEX ON documents_search USING gin(ts);
+
+-- update_documents_search (re-)builds the text search vector of a document.
+CREATE FUNCTION update_documents_search(doc_id int) RETURNS void AS $$
+    DECLARE
+        cfg  regconfig;
+        txts text;
+        vec  tsvector;
+    BEGIN
+        SELECT text_search_config(document #>> '{document,lang}')
+            INTO cfg
+            FROM documents WHERE id = doc_id;
+        SELECT coalesce(string_agg(txt, E'\n' ORDER BY num), '')
+            INTO txts
+            FROM documents_texts JOIN unique_texts ON txt_id = unique_texts.id
+            WHERE documents_id = doc_id;
+        BEGIN
+            vec := to_tsvector(cfg, txts);
+        EXCEPTION WHEN program_limit_exceeded THEN
+            -- Only the beginning of very large documents is searchable.
+            vec := to_tsvector(cfg, left(txts, 500000));
+        END;
+        INSERT INTO documents_search (documents_id, config, ts)
+            VALUES (doc_id, cfg, vec)
+            ON CONFLICT (documents_id) DO UPDATE
+            SET config = EXCLUDED.config, ts = EXCLUDED.ts;
+    END;
+$$ LANGUAGE plpgsql;
+
 CREATE TABLE comments (
     id           int PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
     documents_id int NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
@@ -301,6 +347,10 @@ CREATE TYPE stored_queries_roles AS ENUM (
     'editor', 'reviewer', 'auditor', 'source-manager', 'importer', 'admin'
 );
 
+CREATE TYPE stored_queries_syntax AS ENUM (
+    'rpn', 'infix'
+);
+
 CREATE TABLE stored_queries (
     id            int                 PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
     kind          stored_queries_kind NOT NULL DEFAULT 'advisories',
@@ -315,11 +365,30 @@ CREATE TABLE stored_queries (
     dashboard     bool                NOT NULL DEFAULT FALSE,
     default_query bool                NOT NULL DEFAULT FALSE,
     role        stored_queries_roles,
+    parameters    jsonb,
+    syntax        stored_queries_syntax NOT NULL DEFAULT 'rpn',
+    alert         boolean             NOT NULL DEFAULT FALSE,
     CHECK(name <> ''),
     UNIQUE (definer, name),
     UNIQUE (definer, num) DEFERRABLE INITIALLY DEFERRED
 );
 
+CREATE TYPE stored_queries_shares_kind AS ENUM (
+    'user', 'group', 'role'
+);
+
+-- Stored queries shared with users, groups or roles.
+CREATE TABLE stored_queries_shares (
+    stored_queries_id int                        NOT NULL REFERENCES stored_queries(id) ON DELETE CASCADE,
+    kind              stored_queries_shares_kind NOT NULL,
+    name              varchar                    NOT NULL,
+    editable          boolean                    NOT NULL DEFAULT FALSE,
+    CHECK(name <> ''),
+    PRIMARY KEY (stored_queries_id, kind, name)
+);
+
+CREATE INDEX ON stored_queries_shares(kind, name);
+
 CREATE TABLE default_query_exclusion (
     "user"  text    NOT NULL,
     id      int     NOT NULL REFERENCES stored_queries(id) ON DELETE CASCADE,
@@ -333,6 +402,7 @@ CREATE TABLE sources (
     id                     int     PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
     name                   varchar NOT NULL UNIQUE,
     url                    varchar NOT NULL,
+    mirrors                varchar[],
     active                 bool    NOT NULL DEFAULT FALSE,
     rate                   float,
     slots                  int,
@@ -348,6 +418,7 @@ CREATE TABLE sources (
     checksum               bytea,
     checksum_ack           timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP - '1 second'::interval,
     checksum_updated       timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    subscription           varchar, -- How the source was created automatically.
     CHECK(name <> ''),
     CHECK(url <> ''),
     CHECK(rate IS NULL OR rate > 0.0),
@@ -396,10 +467,67 @@ CREATE TABLE downloads (
     remote_failed    bool,
     checksum_failed  bool,
     signature_failed bool,
-    duplicate_failed bool
+    duplicate_failed bool,
+    validation_report jsonb,
+    mirror           varchar -- URL the document was served from if not by the source.
 );
 
 CREATE INDEX ON downloads (time);
+CREATE INDEX ON downloads (documents_id);
+
+-- Documents rejected by the strict mode of the downloader.
+CREATE TABLE quarantine (
+    id          int         PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    feeds_id    int         NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    url         varchar     NOT NULL,
+    updated     timestamptz NOT NULL,
+    time        timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    publisher   text
+                GENERATED ALWAYS AS (document #>> '{document,publisher,name}') STORED,
+    tracking_id text
+                GENERATED ALWAYS AS (document #>> '{document,tracking,id}') STORED,
+    version     text
+                GENERATED ALWAYS AS (document #>> '{document,tracking,version}') STORED,
+    tlp         text
+                GENERATED ALWAYS AS (document #>> '{document,distribution,tlp,label}') STORED,
+    title       text
+                GENERATED ALWAYS AS (document #>> '{document,title}') STORED,
+    -- The results of the checks
+    download_failed   bool,
+    filename_failed   bool,
+    schema_failed     bool,
+    remote_failed     bool,
+    checksum_failed   bool,
+    signature_failed  bool,
+    duplicate_failed  bool,
+    validation_report jsonb,
+    -- The data
+    document    jsonb COMPRESSION lz4 NOT NULL,
+    original    bytea COMPRESSION lz4 NOT NULL,
+    signature   bytea COMPRESSION lz4,
+    filename    varchar     NOT NULL,
+    UNIQUE (url, feeds_id)
+);
+
+CREATE INDEX ON quarantine (time);
+
+CREATE TYPE source_keys_origin AS ENUM (
+    'pmd', 'manual'
+);
+
+-- Pinned and manually added OpenPGP keys of the sources.
+CREATE TABLE source_keys (
+    sources_id  int                NOT NULL REFERENCES sources(id) ON DELETE CASCADE,
+    fingerprint varchar            NOT NULL,
+    approved    bool               NOT NULL DEFAULT FALSE,
+    first_seen  timestamptz        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    origin      source_keys_origin NOT NULL DEFAULT 'pmd',
+    armored     text,
+    reviewed    timestamptz,
+    PRIMARY KEY(sources_id, fingerprint),
+    CHECK(fingerprint <> ''),
+    CHECK(origin <> 'manual' OR armored IS NOT NULL)
+);
 
 -- Track CVEs for documents.
 CREATE TABLE unique_cves (
@@ -456,6 +584,41 @@ CREATE TRIGGER extract_cves_trigger_update AFTER UPDATE
     WHEN (NEW.document <> OLD.document)
     EXECUTE FUNCTION extract_cves();
 
+-- Facts extracted from the vulnerabilities of the documents on import.
+CREATE TABLE documents_cwes (
+    documents_id int     NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    cwe          varchar NOT NULL,
+    PRIMARY KEY(documents_id, cwe)
+);
+
+CREATE INDEX ON documents_cwes(cwe);
+
+CREATE TABLE documents_remediations (
+    documents_id int     NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    category     varchar NOT NULL,
+    PRIMARY KEY(documents_id, category)
+);
+
+CREATE INDEX ON documents_remediations(category);
+
+CREATE TABLE documents_threats (
+    documents_id int     NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    category     varchar NOT NULL,
+    PRIMARY KEY(documents_id, category)
+);
+
+CREATE INDEX ON documents_threats(category);
+
+-- Number of distinct products per product status category.
+CREATE TABLE documents_product_status (
+    documents_id int     NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    status       varchar NOT NULL,
+    products     int     NOT NULL,
+    PRIMARY KEY(documents_id, status)
+);
+
+CREATE INDEX ON documents_product_status(status);
+
 CREATE TABLE ssvc_history (
     actor         varchar,
     changedate    timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -566,6 +729,10 @@ CREATE INDEX ON forwarders_queue(state) WHERE state = 'pending';
 --
 -- aggregators
 --
+CREATE TYPE aggregators_policy AS ENUM (
+    'none', 'create_inactive', 'create_active'
+);
+
 CREATE TABLE aggregators (
     id     int        PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
     active bool       NOT NULL DEFAULT FALSE,
@@ -574,9 +741,78 @@ CREATE TABLE aggregators (
     checksum          bytea,
     checksum_ack      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP - '1 second'::interval,
     checksum_updated  timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    policy            aggregators_policy NOT NULL DEFAULT 'none',
+    policy_roles      varchar[]   NOT NULL DEFAULT '{csaf_trusted_provider}',
+    policy_tlps       varchar[],
+    policy_rate       float,
+    policy_slots      int,
     CHECK(url LIKE '%/aggregator.json')
 );
 
+CREATE TABLE aggregator_snapshots (
+    id             int         PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    aggregators_id int         NOT NULL REFERENCES aggregators(id) ON DELETE CASCADE,
+    time           timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    providers      jsonb       NOT NULL
+);
+
+CREATE INDEX ON aggregator_snapshots(aggregators_id);
+
+CREATE TYPE aggregator_changes_kind AS ENUM (
+    'added', 'removed', 'changed', 'updated'
+);
+
+CREATE TABLE aggregator_changes (
+    id              int                     PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    aggregators_id  int                     NOT NULL REFERENCES aggregators(id) ON DELETE CASCADE,
+    snapshots_id    int                     NOT NULL REFERENCES aggregator_snapshots(id) ON DELETE CASCADE,
+    time            timestamptz             NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    kind            aggregator_changes_kind NOT NULL,
+    url             varchar                 NOT NULL,
+    previous        jsonb,
+    current         jsonb,
+    acknowledged    timestamptz,
+    acknowledged_by varchar,
+    action          varchar,
+    sources_id      int                     REFERENCES sources(id) ON DELETE SET NULL
+);
+
+CREATE INDEX ON aggregator_changes(aggregators_id);
+
+-- User provided queries which took long to tune the database.
+CREATE TABLE slow_queries (
+    id       int         PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    time     timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    actor    varchar,
+    source   text        NOT NULL,
+    sql      text        NOT NULL,
+    duration interval    NOT NULL,
+    cost     float,
+    failed   boolean     NOT NULL DEFAULT false
+);
+
+CREATE INDEX ON slow_queries(time);
+
+-- Documents which matched an alert query and the triggering events.
+CREATE TABLE alert_hits (
+    stored_queries_id int         NOT NULL REFERENCES stored_queries(id) ON DELETE CASCADE,
+    documents_id      int         NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    event             events      NOT NULL,
+    actor             varchar,
+    time              timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (stored_queries_id, documents_id)
+);
+
+CREATE INDEX ON alert_hits(documents_id);
+
+-- The hits before this time are already seen by the user.
+CREATE TABLE alerts_seen (
+    "user"            varchar     NOT NULL,
+    stored_queries_id int         NOT NULL REFERENCES stored_queries(id) ON DELETE CASCADE,
+    time              timestamptz NOT NULL,
+    PRIMARY KEY ("user", stored_queries_id)
+);
+
 --
 -- permissions
 --
@@ -585,21 +821,34 @@ GRANT INSERT, DELETE, SELECT, UPDATE ON advisories              TO {{ .User | sa
 GRANT INSERT, DELETE, SELECT, UPDATE ON documents               TO {{ .User | sanitize }};
 GRANT INSERT, DELETE, SELECT, UPDATE ON documents_texts         TO {{ .User | sanitize }};
 GRANT INSERT, DELETE, SELECT, UPDATE ON unique_texts            TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON documents_search        TO {{ .User | sanitize }};
 GRANT INSERT, DELETE, SELECT, UPDATE ON comments                TO {{ .User | sanitize }};
 GRANT INSERT, DELETE, SELECT, UPDATE ON events_log              TO {{ .User | sanitize }};
 GRANT INSERT, DELETE, SELECT, UPDATE ON stored_queries          TO {{ .User | sanitize }};
 GRANT INSERT, DELETE, SELECT, UPDATE ON default_query_exclusion TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON stored_queries_shares   TO {{ .User | sanitize }};
 GRANT INSERT, DELETE, SELECT, UPDATE ON sources                 TO {{ .User | sanitize }};
 GRANT INSERT, DELETE, SELECT, UPDATE ON feeds                   TO {{ .User | sanitize }};
 GRANT INSERT, DELETE, SELECT, UPDATE ON changes                 TO {{ .User | sanitize }};
 GRANT INSERT, DELETE, SELECT, UPDATE ON feed_logs               TO {{ .User | sanitize }};
 GRANT INSERT, DELETE, SELECT, UPDATE ON downloads               TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON quarantine              TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON source_keys             TO {{ .User | sanitize }};
 GRANT INSERT, DELETE, SELECT, UPDATE ON unique_cves             TO {{ .User | sanitize }};
 GRANT INSERT, DELETE, SELECT, UPDATE ON documents_cves          TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON documents_cwes          TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON documents_remediations  TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON documents_threats       TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON documents_product_status TO {{ .User | sanitize }};
 GRANT INSERT, DELETE, SELECT, UPDATE ON forwarders              TO {{ .User | sanitize }};
 GRANT INSERT, DELETE, SELECT, UPDATE ON forwarders_queue        TO {{ .User | sanitize }};
 GRANT INSERT, DELETE, SELECT, UPDATE ON aggregators             TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON aggregator_snapshots    TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON aggregator_changes      TO {{ .User | sanitize }};
 GRANT INSERT, DELETE, SELECT, UPDATE ON ssvc_history            TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON slow_queries            TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON alert_hits              TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON alerts_seen             TO {{ .User | sanitize }};
 --
 -- default queries
 --
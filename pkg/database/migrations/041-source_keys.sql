@@ -0,0 +1,21 @@
+-- This file is Free Software under the Apache-2.0 License
+-- without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+--
+-- SPDX-License-Identifier: Apache-2.0
+--
+-- SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+-- Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+CREATE TABLE source_keys (
+    sources_id  int         NOT NULL REFERENCES sources(id) ON DELETE CASCADE,
+    fingerprint varchar     NOT NULL,
+    approved    bool        NOT NULL DEFAULT FALSE,
+    first_seen  timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- When a source manager decided about the approval of the key.
+    -- Keys without a decision are a key change pending approval.
+    reviewed    timestamptz,
+    PRIMARY KEY(sources_id, fingerprint),
+    CHECK(fingerprint <> '')
+);
+
+GRANT INSERT, DELETE, SELECT, UPDATE ON source_keys TO {{ .User | sanitize }};
@@ -0,0 +1,16 @@
+-- This file is Free Software under the Apache-2.0 License
+-- without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+--
+-- SPDX-License-Identifier: Apache-2.0
+--
+-- SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+-- Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+CREATE TYPE source_keys_origin AS ENUM (
+    'pmd', 'manual'
+);
+
+ALTER TABLE source_keys
+    ADD COLUMN origin  source_keys_origin NOT NULL DEFAULT 'pmd',
+    ADD COLUMN armored text,
+    ADD CHECK(origin <> 'manual' OR armored IS NOT NULL);
@@ -0,0 +1,38 @@
+-- This file is Free Software under the Apache-2.0 License
+-- without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+--
+-- SPDX-License-Identifier: Apache-2.0
+--
+-- SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+-- Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+CREATE TABLE aggregator_snapshots (
+    id             int         PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    aggregators_id int         NOT NULL REFERENCES aggregators(id) ON DELETE CASCADE,
+    time           timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    providers      jsonb       NOT NULL
+);
+
+CREATE INDEX ON aggregator_snapshots(aggregators_id);
+
+CREATE TYPE aggregator_changes_kind AS ENUM (
+    'added', 'removed', 'changed'
+);
+
+CREATE TABLE aggregator_changes (
+    id              int                     PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    aggregators_id  int                     NOT NULL REFERENCES aggregators(id) ON DELETE CASCADE,
+    snapshots_id    int                     NOT NULL REFERENCES aggregator_snapshots(id) ON DELETE CASCADE,
+    time            timestamptz             NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    kind            aggregator_changes_kind NOT NULL,
+    url             varchar                 NOT NULL,
+    previous        jsonb,
+    current         jsonb,
+    acknowledged    timestamptz,
+    acknowledged_by varchar
+);
+
+CREATE INDEX ON aggregator_changes(aggregators_id);
+
+GRANT INSERT, DELETE, SELECT, UPDATE ON aggregator_snapshots TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON aggregator_changes   TO {{ .User | sanitize }};
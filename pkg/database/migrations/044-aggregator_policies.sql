@@ -0,0 +1,22 @@
+-- This file is Free Software under the Apache-2.0 License
+-- without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+--
+-- SPDX-License-Identifier: Apache-2.0
+--
+-- SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+-- Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+CREATE TYPE aggregators_policy AS ENUM (
+    'none', 'create_inactive', 'create_active'
+);
+
+ALTER TABLE aggregators
+    ADD COLUMN policy       aggregators_policy NOT NULL DEFAULT 'none',
+    ADD COLUMN policy_roles varchar[]          NOT NULL DEFAULT '{csaf_trusted_provider}',
+    ADD COLUMN policy_tlps  varchar[],
+    ADD COLUMN policy_rate  float,
+    ADD COLUMN policy_slots int;
+
+ALTER TABLE aggregator_changes
+    ADD COLUMN action     varchar,
+    ADD COLUMN sources_id int REFERENCES sources(id) ON DELETE SET NULL;
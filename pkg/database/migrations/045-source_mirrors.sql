@@ -0,0 +1,10 @@
+-- This file is Free Software under the Apache-2.0 License
+-- without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+--
+-- SPDX-License-Identifier: Apache-2.0
+--
+-- SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+-- Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+-- Ordered list of PMD URLs of mirrors to fail over to.
+ALTER TABLE sources ADD COLUMN mirrors varchar[];
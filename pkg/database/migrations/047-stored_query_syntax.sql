@@ -0,0 +1,13 @@
+-- This file is Free Software under the Apache-2.0 License
+-- without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+--
+-- SPDX-License-Identifier: Apache-2.0
+--
+-- SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+-- Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+CREATE TYPE stored_queries_syntax AS ENUM (
+    'rpn', 'infix'
+);
+
+ALTER TABLE stored_queries ADD COLUMN syntax stored_queries_syntax NOT NULL DEFAULT 'rpn';
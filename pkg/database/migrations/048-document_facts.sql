@@ -0,0 +1,102 @@
+-- This file is Free Software under the Apache-2.0 License
+-- without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+--
+-- SPDX-License-Identifier: Apache-2.0
+--
+-- SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+-- Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+-- Facts extracted from the vulnerabilities of the documents on import.
+CREATE TABLE documents_cwes (
+    documents_id int     NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    cwe          varchar NOT NULL,
+    PRIMARY KEY(documents_id, cwe)
+);
+
+CREATE INDEX ON documents_cwes(cwe);
+
+CREATE TABLE documents_remediations (
+    documents_id int     NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    category     varchar NOT NULL,
+    PRIMARY KEY(documents_id, category)
+);
+
+CREATE INDEX ON documents_remediations(category);
+
+CREATE TABLE documents_threats (
+    documents_id int     NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    category     varchar NOT NULL,
+    PRIMARY KEY(documents_id, category)
+);
+
+CREATE INDEX ON documents_threats(category);
+
+-- Number of distinct products per product status category.
+CREATE TABLE documents_product_status (
+    documents_id int     NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    status       varchar NOT NULL,
+    products     int     NOT NULL,
+    PRIMARY KEY(documents_id, status)
+);
+
+CREATE INDEX ON documents_product_status(status);
+
+-- Extract the facts of the existing documents the same way
+-- they are extracted on import. Values which are not strings
+-- or are empty are left out.
+CREATE TEMPORARY TABLE vulns ON COMMIT DROP AS
+    SELECT id, vuln
+    FROM documents,
+        jsonb_array_elements(CASE jsonb_typeof(document->'vulnerabilities')
+            WHEN 'array' THEN document->'vulnerabilities' ELSE '[]' END) AS vuln
+    WHERE jsonb_typeof(vuln) = 'object';
+
+-- Returns the elements of a JSON array being objects.
+CREATE FUNCTION pg_temp.objects(jsonb) RETURNS SETOF jsonb AS $$
+    SELECT elem
+    FROM jsonb_array_elements(CASE jsonb_typeof($1) WHEN 'array' THEN $1 ELSE '[]' END) AS elem
+    WHERE jsonb_typeof(elem) = 'object'
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- Returns a JSON value as text if it is a non-empty string.
+CREATE FUNCTION pg_temp.string(jsonb) RETURNS text AS $$
+    SELECT CASE WHEN jsonb_typeof($1) = 'string' THEN NULLIF($1 #>> '{}', '') END
+$$ LANGUAGE SQL IMMUTABLE;
+
+INSERT INTO documents_cwes (documents_id, cwe)
+    SELECT id, cwe
+    FROM (
+        SELECT id, pg_temp.string(vuln->'cwe'->'id') AS cwe
+        FROM vulns
+        WHERE jsonb_typeof(vuln->'cwe') = 'object'
+        UNION
+        SELECT id, pg_temp.string(cwe->'id')
+        FROM vulns, pg_temp.objects(vuln->'cwes') AS cwe
+    ) AS cwes
+    WHERE cwe IS NOT NULL;
+
+INSERT INTO documents_remediations (documents_id, category)
+    SELECT DISTINCT id, pg_temp.string(rem->'category') AS category
+    FROM vulns, pg_temp.objects(vuln->'remediations') AS rem
+    WHERE pg_temp.string(rem->'category') IS NOT NULL;
+
+INSERT INTO documents_threats (documents_id, category)
+    SELECT DISTINCT id, pg_temp.string(threat->'category') AS category
+    FROM vulns, pg_temp.objects(vuln->'threats') AS threat
+    WHERE pg_temp.string(threat->'category') IS NOT NULL;
+
+-- A status category is stored even if it has no products.
+INSERT INTO documents_product_status (documents_id, status, products)
+    SELECT id, ps.key, count(DISTINCT pg_temp.string(product))
+    FROM vulns,
+        jsonb_each(CASE jsonb_typeof(vuln->'product_status')
+            WHEN 'object' THEN vuln->'product_status' ELSE '{}' END) AS ps
+        LEFT JOIN LATERAL jsonb_array_elements(CASE jsonb_typeof(ps.value)
+            WHEN 'array' THEN ps.value ELSE '[]' END) AS product ON true
+    WHERE jsonb_typeof(ps.value) = 'array'
+    GROUP BY id, ps.key;
+
+GRANT INSERT, DELETE, SELECT, UPDATE ON documents_cwes           TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON documents_remediations   TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON documents_threats        TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON documents_product_status TO {{ .User | sanitize }};
@@ -0,0 +1,23 @@
+-- This file is Free Software under the Apache-2.0 License
+-- without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+--
+-- SPDX-License-Identifier: Apache-2.0
+--
+-- SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+-- Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+-- User provided queries which took long to tune the database.
+CREATE TABLE slow_queries (
+    id       int         PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    time     timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    actor    varchar,
+    source   text        NOT NULL,
+    sql      text        NOT NULL,
+    duration interval    NOT NULL,
+    cost     float,
+    failed   boolean     NOT NULL DEFAULT false
+);
+
+CREATE INDEX ON slow_queries(time);
+
+GRANT INSERT, DELETE, SELECT, UPDATE ON slow_queries TO {{ .User | sanitize }};
@@ -0,0 +1,57 @@
+-- This file is Free Software under the Apache-2.0 License
+-- without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+--
+-- SPDX-License-Identifier: Apache-2.0
+--
+-- SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+-- Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+-- text_search_config maps the language of a document
+-- to the text search configuration used for stemming.
+CREATE FUNCTION text_search_config(lang text) RETURNS regconfig AS $$
+    SELECT CASE lower(split_part(lang, '-', 1))
+        WHEN 'de' THEN 'german'
+        WHEN 'en' THEN 'english'
+        ELSE 'simple'
+    END::regconfig
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- documents_search holds the stemmed texts of the documents for ranked searches.
+CREATE TABLE documents_search (
+    documents_id int       PRIMARY KEY REFERENCES documents(id) ON DELETE CASCADE,
+    config       regconfig NOT NULL,
+    ts           tsvector  NOT NULL
+);
+
+CREATE INDEX ON documents_search USING gin(ts);
+
+-- update_documents_search (re-)builds the text search vector of a document.
+CREATE FUNCTION update_documents_search(doc_id int) RETURNS void AS $$
+    DECLARE
+        cfg  regconfig;
+        txts text;
+        vec  tsvector;
+    BEGIN
+        SELECT text_search_config(document #>> '{document,lang}')
+            INTO cfg
+            FROM documents WHERE id = doc_id;
+        SELECT coalesce(string_agg(txt, E'\n' ORDER BY num), '')
+            INTO txts
+            FROM documents_texts JOIN unique_texts ON txt_id = unique_texts.id
+            WHERE documents_id = doc_id;
+        BEGIN
+            vec := to_tsvector(cfg, txts);
+        EXCEPTION WHEN program_limit_exceeded THEN
+            -- Only the beginning of very large documents is searchable.
+            vec := to_tsvector(cfg, left(txts, 500000));
+        END;
+        INSERT INTO documents_search (documents_id, config, ts)
+            VALUES (doc_id, cfg, vec)
+            ON CONFLICT (documents_id) DO UPDATE
+            SET config = EXCLUDED.config, ts = EXCLUDED.ts;
+    END;
+$$ LANGUAGE plpgsql;
+
+SELECT update_documents_search(id) FROM documents;
+
+GRANT INSERT, DELETE, SELECT, UPDATE ON documents_search TO {{ .User | sanitize }};
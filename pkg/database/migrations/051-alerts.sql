@@ -0,0 +1,33 @@
+-- This file is Free Software under the Apache-2.0 License
+-- without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+--
+-- SPDX-License-Identifier: Apache-2.0
+--
+-- SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+-- Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+-- Stored queries which are evaluated on imports and state changes.
+ALTER TABLE stored_queries ADD COLUMN alert boolean NOT NULL DEFAULT FALSE;
+
+-- Documents which matched an alert query and the triggering events.
+CREATE TABLE alert_hits (
+    stored_queries_id int         NOT NULL REFERENCES stored_queries(id) ON DELETE CASCADE,
+    documents_id      int         NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    event             events      NOT NULL,
+    actor             varchar,
+    time              timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (stored_queries_id, documents_id)
+);
+
+CREATE INDEX ON alert_hits(documents_id);
+
+-- The hits before this time are already seen by the user.
+CREATE TABLE alerts_seen (
+    "user"            varchar     NOT NULL,
+    stored_queries_id int         NOT NULL REFERENCES stored_queries(id) ON DELETE CASCADE,
+    time              timestamptz NOT NULL,
+    PRIMARY KEY ("user", stored_queries_id)
+);
+
+GRANT INSERT, DELETE, SELECT, UPDATE ON alert_hits  TO {{ .User | sanitize }};
+GRANT INSERT, DELETE, SELECT, UPDATE ON alerts_seen TO {{ .User | sanitize }};
@@ -0,0 +1,25 @@
+-- This file is Free Software under the Apache-2.0 License
+-- without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+--
+-- SPDX-License-Identifier: Apache-2.0
+--
+-- SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+-- Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+CREATE TYPE stored_queries_shares_kind AS ENUM (
+    'user', 'group', 'role'
+);
+
+-- Stored queries shared with users, groups or roles.
+CREATE TABLE stored_queries_shares (
+    stored_queries_id int                        NOT NULL REFERENCES stored_queries(id) ON DELETE CASCADE,
+    kind              stored_queries_shares_kind NOT NULL,
+    name              varchar                    NOT NULL,
+    editable          boolean                    NOT NULL DEFAULT FALSE,
+    CHECK(name <> ''),
+    PRIMARY KEY (stored_queries_id, kind, name)
+);
+
+CREATE INDEX ON stored_queries_shares(kind, name);
+
+GRANT INSERT, DELETE, SELECT, UPDATE ON stored_queries_shares TO {{ .User | sanitize }};
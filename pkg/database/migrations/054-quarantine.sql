@@ -0,0 +1,45 @@
+-- This file is Free Software under the Apache-2.0 License
+-- without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+--
+-- SPDX-License-Identifier: Apache-2.0
+--
+-- SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+-- Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+-- Documents rejected by the strict mode of the downloader.
+CREATE TABLE quarantine (
+    id          int         PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    feeds_id    int         NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    url         varchar     NOT NULL,
+    updated     timestamptz NOT NULL,
+    time        timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    publisher   text
+                GENERATED ALWAYS AS (document #>> '{document,publisher,name}') STORED,
+    tracking_id text
+                GENERATED ALWAYS AS (document #>> '{document,tracking,id}') STORED,
+    version     text
+                GENERATED ALWAYS AS (document #>> '{document,tracking,version}') STORED,
+    tlp         text
+                GENERATED ALWAYS AS (document #>> '{document,distribution,tlp,label}') STORED,
+    title       text
+                GENERATED ALWAYS AS (document #>> '{document,title}') STORED,
+    -- The results of the checks
+    download_failed   bool,
+    filename_failed   bool,
+    schema_failed     bool,
+    remote_failed     bool,
+    checksum_failed   bool,
+    signature_failed  bool,
+    duplicate_failed  bool,
+    validation_report jsonb,
+    -- The data
+    document    jsonb COMPRESSION lz4 NOT NULL,
+    original    bytea COMPRESSION lz4 NOT NULL,
+    signature   bytea COMPRESSION lz4,
+    filename    varchar     NOT NULL,
+    UNIQUE (url, feeds_id)
+);
+
+CREATE INDEX ON quarantine (time);
+
+GRANT INSERT, DELETE, SELECT, UPDATE ON quarantine TO {{ .User | sanitize }};
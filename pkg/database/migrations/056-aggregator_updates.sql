@@ -0,0 +1,16 @@
+-- This file is Free Software under the Apache-2.0 License
+-- without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+--
+-- SPDX-License-Identifier: Apache-2.0
+--
+-- SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+-- Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+-- Providers of which only the last update time changed.
+ALTER TYPE aggregator_changes_kind ADD VALUE 'updated';
+
+-- The open changes tell by themselves if an aggregator needs attention.
+UPDATE aggregators SET checksum_ack = checksum_updated
+    WHERE checksum_ack < checksum_updated
+    AND EXISTS(SELECT 1 FROM aggregator_changes
+        WHERE aggregators_id = aggregators.id AND acknowledged IS NULL);
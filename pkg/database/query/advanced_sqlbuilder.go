@@ -9,6 +9,7 @@
 package query
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"maps"
@@ -29,6 +30,11 @@ type AdvancedSQLBuilder struct {
 	replToIdx    map[string]int
 	usedSources  columnSource
 	aggregate    bool
+	grouping     *Grouping
+	cursor       *Cursor
+	fullTexts    []*Expr
+	relevance    bool
+	counting     bool
 }
 
 type statementMode interface {
@@ -41,6 +47,8 @@ type statementMode interface {
 	ilikePNameWhere(sb *AdvancedSQLBuilder, e *Expr, b *strings.Builder)
 	ilikePIDWhere(sb *AdvancedSQLBuilder, e *Expr, b *strings.Builder)
 	order(sb *AdvancedSQLBuilder, b *strings.Builder, name string)
+	documentID() string
+	jsonDocument() string
 }
 
 type (
@@ -59,6 +67,9 @@ func (classicMode) projectionCommon(
 		b.WriteString("::text")
 	case "event_state":
 		b.WriteString("events_log.state::text AS event_state")
+	case "relevance":
+		sb.relevanceTerm(b)
+		b.WriteString(" AS relevance")
 	case "versions":
 		b.WriteString(versionsCount + `AS versions`)
 	case "comments":
@@ -89,7 +100,13 @@ func (cm classicMode) projection(sb *AdvancedSQLBuilder, b *strings.Builder, nam
 		b.WriteString(name)
 	case "ssvc":
 		b.WriteString("ssvc_current.ssvc AS ssvc")
+	case "known_affected_count":
+		b.WriteString(knownAffectedCountClassic + ` AS known_affected_count`)
 	default:
+		if term := virtualColumnTerm(name); term != "" {
+			b.WriteString(term + " AS " + name)
+			return
+		}
 		cm.projectionCommon(sb, b, name,
 			versionsCountClassic, commentsCountDocumentsClassic)
 	}
@@ -116,7 +133,7 @@ func (cm cteMode) projection(sb *AdvancedSQLBuilder, b *strings.Builder, name st
 	}
 }
 
-func (classicMode) from(sb *AdvancedSQLBuilder, b *strings.Builder) {
+func (cm classicMode) from(sb *AdvancedSQLBuilder, b *strings.Builder) {
 	switch sb.mode() {
 	case AdvisoryMode, DocumentMode:
 		b.WriteString(`documents ` +
@@ -139,6 +156,7 @@ func (classicMode) from(sb *AdvancedSQLBuilder, b *strings.Builder) {
 		b.WriteString(` JOIN documents_texts ON documents.id = documents_texts.documents_id ` +
 			`JOIN unique_texts ON documents_texts.txt_id = unique_texts.id`)
 	}
+	sb.createFullTextJoins(b, cm.documentID())
 }
 
 // createUnaliasedSearches creates a CROSS JOIN LATERAL to filter searches with no aliases.
@@ -174,6 +192,9 @@ func (sb *AdvancedSQLBuilder) createAliasedSearches(b *strings.Builder) {
 	names := slices.Sorted(maps.Keys(sb.parser.aliases))
 	for _, name := range names {
 		srch := sb.parser.aliases[name]
+		if srch.exprType != search {
+			continue
+		}
 		replacement := sb.replacementIndex(LikeEscape(srch.stringValue)) + 1
 		if sb.aggregate {
 			fmt.Fprintf(b,
@@ -197,7 +218,7 @@ func (sb *AdvancedSQLBuilder) createAliasedSearches(b *strings.Builder) {
 	}
 }
 
-func (cteMode) from(sb *AdvancedSQLBuilder, b *strings.Builder) {
+func (cm cteMode) from(sb *AdvancedSQLBuilder, b *strings.Builder) {
 	switch sb.mode() {
 	case AdvisoryMode, DocumentMode:
 		b.WriteString(`docads`)
@@ -212,6 +233,7 @@ func (cteMode) from(sb *AdvancedSQLBuilder, b *strings.Builder) {
 	// For every search we need a CROSS JOIN LITERAL.
 	sb.createUnaliasedSearches(b)
 	sb.createAliasedSearches(b)
+	sb.createFullTextJoins(b, cm.documentID())
 }
 
 func (classicMode) accessWhereCommon(
@@ -247,7 +269,13 @@ func (cm classicMode) accessWhere(sb *AdvancedSQLBuilder, e *Expr, b *strings.Bu
 		b.WriteString(column)
 	case "ssvc":
 		b.WriteString("ssvc_current.ssvc")
+	case "known_affected_count":
+		b.WriteString(knownAffectedCountClassic)
 	default:
+		if term := virtualColumnTerm(column); term != "" {
+			b.WriteString(term)
+			return
+		}
 		cm.accessWhereCommon(sb, e, b,
 			versionsCountClassic, commentsCountDocumentsClassic)
 	}
@@ -422,20 +450,31 @@ func (classicMode) orderCommon(b *strings.Builder, name string) {
 	}
 }
 
-func (cm classicMode) order(_ *AdvancedSQLBuilder, b *strings.Builder, name string) {
+func (cm classicMode) order(sb *AdvancedSQLBuilder, b *strings.Builder, name string) {
 	switch name {
-	case "tracking_id", "publisher", "id":
+	case "relevance":
+		sb.relevanceTerm(b)
+	case "tracking_id", "publisher":
 		b.WriteString("advisories.")
 		b.WriteString(name)
+	case "id":
+		b.WriteString("documents.")
+		b.WriteString(name)
 	case "ssvc":
 		b.WriteString("ssvc_current.ssvc")
 	default:
+		if term := virtualColumnTerm(name); term != "" {
+			b.WriteString(term)
+			return
+		}
 		cm.orderCommon(b, name)
 	}
 }
 
-func (cm cteMode) order(_ *AdvancedSQLBuilder, b *strings.Builder, name string) {
+func (cm cteMode) order(sb *AdvancedSQLBuilder, b *strings.Builder, name string) {
 	switch name {
+	case "relevance":
+		sb.relevanceTerm(b)
 	case "tracking_id", "publisher", "id":
 		b.WriteString("docads.")
 		b.WriteString(name)
@@ -525,6 +564,19 @@ func (sb *AdvancedSQLBuilder) HasAlias(name string) bool {
 	return sb.alias(name) != nil
 }
 
+// ReferencesText checks if the values of a result column are the ids
+// of texts which have to be loaded separately.
+func (sb *AdvancedSQLBuilder) ReferencesText(name string) bool {
+	srch := sb.alias(name)
+	return sb.aggregate && srch != nil && srch.exprType == search
+}
+
+// isTableColumn checks if a field is a column of the queried tables.
+// Aliases and the relevance are calculated.
+func (sb *AdvancedSQLBuilder) isTableColumn(name string) bool {
+	return name != "relevance" && !sb.HasAlias(name)
+}
+
 // createWhere construct a WHERE clause for a given expression.
 func (sb *AdvancedSQLBuilder) createWhere(b *strings.Builder, sm statementMode) {
 	sb.whereRecurse(sb.expr, b, sm)
@@ -670,6 +722,12 @@ func (sb *AdvancedSQLBuilder) whereRecurse(e *Expr, b *strings.Builder, sm state
 		sm.ilikePNameWhere(sb, e, b)
 	case ilikePID:
 		sm.ilikePIDWhere(sb, e, b)
+	case cve, ilikeCVE, cwe, hasRemediation, hasThreat, hasProductStatus:
+		factWhere(e, b, sm.documentID(), func(child *Expr) { sb.whereRecurse(child, b, sm) })
+	case fullText:
+		fullTextWhere(b, sm.documentID(), sb.replacementIndex(e.stringValue)+1)
+	case jsonPath:
+		jsonPathWhere(e, b, sm.jsonDocument(), func(child *Expr) { sb.whereRecurse(child, b, sm) })
 	case now:
 		sb.nowWhere(b)
 	case add:
@@ -691,23 +749,36 @@ func (sb *AdvancedSQLBuilder) replacementIndex(s string) int {
 	if sb.replToIdx == nil {
 		sb.replToIdx = map[string]int{}
 	}
-	sb.Replacements = append(sb.Replacements, s)
-	idx := len(sb.replToIdx)
+	idx := sb.replacement(s)
 	sb.replToIdx[s] = idx
 	return idx
 }
 
+// replacement adds a replacement without sharing it with equal ones.
+func (sb *AdvancedSQLBuilder) replacement(v any) int {
+	sb.Replacements = append(sb.Replacements, v)
+	return len(sb.Replacements) - 1
+}
+
 // CreateCountSQL returns an SQL count statement to count
 // the number of rows which are possible to fetch by the
 // given filter.
 func (sb *AdvancedSQLBuilder) CreateCountSQL() string {
+	// The ranks and snippets are not needed to count.
+	sb.counting = true
+	defer func() { sb.counting = false }()
 	var b strings.Builder
 	sm := statementMode(classicMode{})
 	if sb.usedSources.contains(documentsTable | advisoriesTable) {
 		sm = cteMode{}
 		sb.prefixCTE(&b)
 	}
-	b.WriteString("SELECT count(*) FROM ")
+	if sb.aggregate {
+		// Count the documents not the search results.
+		b.WriteString("SELECT count(DISTINCT " + sm.documentID() + ") FROM ")
+	} else {
+		b.WriteString("SELECT count(*) FROM ")
+	}
 	sm.from(sb, &b)
 	b.WriteString(" WHERE ")
 	sb.createWhere(&b, sm)
@@ -722,7 +793,7 @@ func (sb *AdvancedSQLBuilder) prefixCTE(b *strings.Builder) {
 			itertools.Filter(
 				slices.Values(sb.fields),
 				// Aliases are not real columns.
-				itertools.Not(sb.HasAlias)),
+				sb.isTableColumn),
 			itertools.Filter(itertools.Apply(
 				slices.Values(sb.orderFields),
 				func(s string) string {
@@ -730,8 +801,11 @@ func (sb *AdvancedSQLBuilder) prefixCTE(b *strings.Builder) {
 					return strings.TrimPrefix(s, "-")
 				}),
 				// Aliases are not real columns.
-				itertools.Not(sb.HasAlias)),
+				sb.isTableColumn),
 			sb.expr.Accesses(),
+			sb.grouping.columns(),
+			// The searches are joined by the id.
+			slices.Values([]string{"id"}),
 		))) {
 		if i > 0 {
 			b.WriteByte(',')
@@ -741,15 +815,20 @@ func (sb *AdvancedSQLBuilder) prefixCTE(b *strings.Builder) {
 			b.WriteString("documents.id AS id")
 		case "versions":
 			b.WriteString(versionsCountClassic + ` AS versions`)
+		case "known_affected_count":
+			b.WriteString(knownAffectedCountClassic + ` AS known_affected_count`)
 		case "ssvc":
 			b.WriteString(`(` +
 				`SELECT ssvc FROM ssvc_history ` +
 				`WHERE documents_id = documents.id ` +
 				`ORDER BY changedate DESC, change_number DESC LIMIT 1)`)
 		default:
-			b.WriteString(field)
+			writeProjection(b, field)
 		}
 	}
+	if sb.expr.usesJSONPath() {
+		b.WriteString(`,` + originalDocument + ` AS original_document`)
+	}
 	b.WriteString(` FROM documents JOIN advisories` +
 		` ON documents.advisories_id = advisories.id)`)
 }
@@ -767,12 +846,21 @@ func (sb *AdvancedSQLBuilder) CreateQuery(
 		sb.prefixCTE(&b)
 	}
 
+	if sb.aggregate {
+		sb.createDocumentWindow(&b, sm, limit, offset)
+		query := b.String()
+		slog.Debug("sql builder", "query", query)
+		return query
+	}
+
 	b.WriteString("SELECT ")
 	sb.createProjectionsWithCasts(&b, sm)
+	sb.createCursorKeys(&b, sm)
 	b.WriteString(" FROM ")
 	sm.from(sb, &b)
 	b.WriteString(" WHERE ")
 	sb.createWhere(&b, sm)
+	sb.createKeyset(&b, sm)
 
 	if len(sb.orderFields) > 0 {
 		b.WriteString(" ORDER BY ")
@@ -793,9 +881,78 @@ func (sb *AdvancedSQLBuilder) CreateQuery(
 	return query
 }
 
+// createDocumentWindow writes a query which pages over the documents
+// instead of the rows as there is a row for each search result.
+func (sb *AdvancedSQLBuilder) createDocumentWindow(
+	b *strings.Builder, sm statementMode,
+	limit, offset int64,
+) {
+	b.WriteString("SELECT ")
+	for i, name := range sb.fields {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+	}
+	for _, name := range sb.CursorFields() {
+		b.WriteByte(',')
+		b.WriteString(name)
+	}
+	b.WriteString(" FROM (SELECT ")
+	sb.createProjectionsWithCasts(b, sm)
+	sb.createCursorKeys(b, sm)
+	b.WriteString(",dense_rank() OVER (ORDER BY ")
+	sb.createOrderFields(b, sm, sb.documentOrders())
+	b.WriteString(") AS _document_rank FROM ")
+	sm.from(sb, b)
+	b.WriteString(" WHERE ")
+	sb.createWhere(b, sm)
+	sb.createKeyset(b, sm)
+	b.WriteString(") AS ranked")
+
+	offset = max(offset, 0)
+	if offset > 0 {
+		b.WriteString(" WHERE _document_rank > ")
+		b.WriteString(strconv.FormatInt(offset, 10))
+	}
+	if limit >= 0 {
+		if offset > 0 {
+			b.WriteString(" AND")
+		} else {
+			b.WriteString(" WHERE")
+		}
+		b.WriteString(" _document_rank <= ")
+		b.WriteString(strconv.FormatInt(offset+limit, 10))
+	}
+
+	// The search results are ordered within the documents.
+	b.WriteString(" ORDER BY _document_rank")
+	for _, field := range sb.orderFields {
+		name, desc := strings.CutPrefix(field, "-")
+		if !sb.HasAlias(name) {
+			continue
+		}
+		b.WriteByte(',')
+		b.WriteString(name)
+		if desc {
+			b.WriteString(" DESC")
+		} else {
+			b.WriteString(" ASC")
+		}
+	}
+}
+
 // createOrder returns a ORDER BY clause for given columns.
 func (sb *AdvancedSQLBuilder) createOrder(b *strings.Builder, sm statementMode) {
-	for i, field := range sb.orderFields {
+	sb.createOrderFields(b, sm, sb.orderFields)
+}
+
+// createOrderFields writes the list of the given order fields.
+func (sb *AdvancedSQLBuilder) createOrderFields(
+	b *strings.Builder, sm statementMode,
+	orderFields []string,
+) {
+	for i, field := range orderFields {
 		desc := strings.HasPrefix(field, "-")
 		if desc {
 			field = field[1:]
@@ -819,6 +976,10 @@ func (sb *AdvancedSQLBuilder) createProjectionsWithCasts(b *strings.Builder, sm
 			b.WriteByte(',')
 		}
 		if srch := sb.alias(name); srch != nil {
+			if srch.exprType == fullText {
+				fmt.Fprintf(b, `_fts_snippet_%d.snippet AS "%s"`, sb.fullTextIndex(srch), name)
+				continue
+			}
 			if sb.aggregate {
 				fmt.Fprintf(b, "_search_join_%d.id AS %s", srch.intValue, name)
 			} else {
@@ -843,12 +1004,22 @@ func (sb *AdvancedSQLBuilder) check() error {
 			sb.usedSources.add(col.sources)
 			return true
 		}
-		if sb.HasAlias(field) {
-			sb.usedSources.add(documentsTable | textTable)
+		if srch := sb.alias(field); srch != nil {
+			if srch.exprType == fullText {
+				sb.usedSources.add(documentsTable)
+			} else {
+				sb.usedSources.add(documentsTable | textTable)
+			}
 			return true
 		}
 		return false
 	}
+	// The full-text searches are numbered for the joins.
+	if sb.expr != nil {
+		sb.fullTexts = slices.Collect(itertools.Filter(sb.expr.all(), func(e *Expr) bool {
+			return e.exprType == fullText
+		}))
+	}
 	// check projections.
 	for _, f := range sb.fields {
 		if !checkField(f) {
@@ -861,6 +1032,31 @@ func (sb *AdvancedSQLBuilder) check() error {
 			return fmt.Errorf("order field %q does not exists", f)
 		}
 	}
+	// check relevance
+	sb.relevance = slices.ContainsFunc(slices.Concat(sb.fields, sb.orderFields), func(f string) bool {
+		return strings.TrimPrefix(f, "-") == "relevance"
+	})
+	if sb.relevance && len(sb.fullTexts) == 0 {
+		return errors.New(`"relevance" needs a fulltext search in the query`)
+	}
+	// check cursor
+	if sb.cursor != nil {
+		if err := sb.checkCursor(); err != nil {
+			return err
+		}
+	}
+	// check grouping
+	if sb.grouping != nil {
+		if err := sb.grouping.check(sb.mode(), sb.usedSources.add); err != nil {
+			return err
+		}
+	}
 	slog.Debug("advanced sqlbuilder", "used sources", sb.usedSources)
 	return nil
 }
+
+func (classicMode) documentID() string { return "documents.id" }
+func (cteMode) documentID() string     { return "docads.id" }
+
+func (classicMode) jsonDocument() string { return originalDocument }
+func (cteMode) jsonDocument() string     { return "docads.original_document" }
@@ -0,0 +1,252 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cursor is the position after the last row of a page
+// in a result set ordered by the order fields.
+// It is passed to the clients as an opaque string.
+type Cursor struct {
+	// Orders are the order fields the cursor was created for.
+	Orders []string `json:"o"`
+	// Values are the values of the order fields in the last row.
+	// nil stands for NULL.
+	Values []*string `json:"v"`
+}
+
+// cursorKeyPrefix prefixes the names of the projected order values.
+const cursorKeyPrefix = "_cursor_"
+
+// ParseCursor decodes a cursor from its string representation.
+// The empty string is the cursor before the first row.
+func ParseCursor(s string) (*Cursor, error) {
+	if s == "" {
+		return &Cursor{}, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if len(c.Orders) != len(c.Values) {
+		return nil, errors.New("invalid cursor: number of orders and values differ")
+	}
+	return &c, nil
+}
+
+// String implements [fmt.Stringer].
+func (c *Cursor) String() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// AdvancedSQLBuilderCursor creates an option to create an advanced SQL builder
+// which pages by the values of the order fields starting after the cursor.
+func AdvancedSQLBuilderCursor(cursor *Cursor) AdvancedSQLBuilderOption {
+	return func(ab *AdvancedSQLBuilder) {
+		ab.cursor = cursor
+	}
+}
+
+// documentOrders returns the order fields which are not search aliases.
+func (sb *AdvancedSQLBuilder) documentOrders() []string {
+	return slices.DeleteFunc(slices.Clone(sb.orderFields), func(f string) bool {
+		return sb.HasAlias(strings.TrimPrefix(f, "-"))
+	})
+}
+
+// checkCursor checks if the cursor can be used with the order fields.
+func (sb *AdvancedSQLBuilder) checkCursor() error {
+	orders := sb.documentOrders()
+	if !sb.aggregate && len(orders) != len(sb.orderFields) {
+		return errors.New("cannot page by cursor if ordered by search results")
+	}
+	if !slices.ContainsFunc(orders, func(f string) bool {
+		return strings.TrimPrefix(f, "-") == "id"
+	}) {
+		return errors.New(`paging by cursor needs "id" as order field`)
+	}
+	if len(sb.cursor.Orders) > 0 && !slices.Equal(sb.cursor.Orders, orders) {
+		return errors.New("cursor does not match the order fields")
+	}
+	return nil
+}
+
+// CursorFields returns the names of the additional result columns
+// needed to create the next cursor.
+func (sb *AdvancedSQLBuilder) CursorFields() []string {
+	if sb.cursor == nil {
+		return nil
+	}
+	orders := sb.documentOrders()
+	fields := make([]string, len(orders))
+	for i := range orders {
+		fields[i] = cursorKeyPrefix + strconv.Itoa(i)
+	}
+	return fields
+}
+
+// NextCursor removes the values of the cursor fields from a result row
+// and returns the cursor to continue after this row. If the row does
+// not contain the values nil is returned.
+func (sb *AdvancedSQLBuilder) NextCursor(row map[string]any) *Cursor {
+	fields := sb.CursorFields()
+	if len(fields) == 0 {
+		return nil
+	}
+	next := &Cursor{
+		Orders: sb.documentOrders(),
+		Values: make([]*string, len(fields)),
+	}
+	for i, field := range fields {
+		value, ok := row[field]
+		if !ok {
+			return nil
+		}
+		delete(row, field)
+		var s string
+		switch v := value.(type) {
+		case nil:
+			continue
+		case time.Time:
+			s = v.Format(time.RFC3339Nano)
+		case float64:
+			s = strconv.FormatFloat(v, 'g', -1, 64)
+		case float32:
+			s = strconv.FormatFloat(float64(v), 'g', -1, 32)
+		default:
+			s = fmt.Sprint(v)
+		}
+		next.Values[i] = &s
+	}
+	return next
+}
+
+// orderKeyType returns the type of the value an order field is ordered by.
+func (sb *AdvancedSQLBuilder) orderKeyType(name string) valueType {
+	if name == "version" {
+		return intType
+	}
+	return findDocumentColumn(name, sb.mode()).valueType
+}
+
+// createCursorKeys writes the projections of the order values.
+func (sb *AdvancedSQLBuilder) createCursorKeys(b *strings.Builder, sm statementMode) {
+	for i, field := range sb.documentOrders() {
+		name := strings.TrimPrefix(field, "-")
+		b.WriteByte(',')
+		switch sb.orderKeyType(name) {
+		case workflowType, eventsType, statusType:
+			b.WriteByte('(')
+			sm.order(sb, b, name)
+			b.WriteString(")::text")
+		default:
+			sm.order(sb, b, name)
+		}
+		fmt.Fprintf(b, " AS %s%d", cursorKeyPrefix, i)
+	}
+}
+
+// cursorValue writes the i-th value of the cursor as a typed parameter.
+func (sb *AdvancedSQLBuilder) cursorValue(b *strings.Builder, i int, name string) {
+	// Not shared as the same value may be used with other types.
+	fmt.Fprintf(b, "$%d::", sb.replacement(*sb.cursor.Values[i])+1)
+	switch sb.orderKeyType(name) {
+	case intType:
+		b.WriteString("bigint")
+	case floatType:
+		b.WriteString("float8")
+	case timeType:
+		b.WriteString("timestamptz")
+	case workflowType:
+		b.WriteString("workflow")
+	case eventsType:
+		b.WriteString("events")
+	case statusType:
+		b.WriteString("status")
+	case boolType:
+		b.WriteString("boolean")
+	default:
+		b.WriteString("text")
+	}
+}
+
+// createKeyset writes the condition to select the rows after the cursor.
+// Ascending orders sort NULLs last, descending orders sort them first.
+func (sb *AdvancedSQLBuilder) createKeyset(b *strings.Builder, sm statementMode) {
+	if sb.cursor == nil || len(sb.cursor.Values) == 0 {
+		return
+	}
+	var terms []string
+	for i, field := range sb.cursor.Orders {
+		name, desc := strings.CutPrefix(field, "-")
+		value := sb.cursor.Values[i]
+		if value == nil && !desc {
+			// Nothing comes after NULLs.
+			continue
+		}
+		var t strings.Builder
+		// All previous order values are equal.
+		for j, prev := range sb.cursor.Orders[:i] {
+			prev = strings.TrimPrefix(prev, "-")
+			t.WriteByte('(')
+			sm.order(sb, &t, prev)
+			if sb.cursor.Values[j] == nil {
+				t.WriteString(" IS NULL")
+			} else {
+				t.WriteString(" = ")
+				sb.cursorValue(&t, j, prev)
+			}
+			t.WriteString(") AND ")
+		}
+		// This order value is behind.
+		switch {
+		case value == nil:
+			t.WriteByte('(')
+			sm.order(sb, &t, name)
+			t.WriteString(" IS NOT NULL)")
+		case desc:
+			t.WriteByte('(')
+			sm.order(sb, &t, name)
+			t.WriteString(" < ")
+			sb.cursorValue(&t, i, name)
+			t.WriteByte(')')
+		default:
+			t.WriteByte('(')
+			sm.order(sb, &t, name)
+			t.WriteString(" > ")
+			sb.cursorValue(&t, i, name)
+			t.WriteString(" OR ")
+			sm.order(sb, &t, name)
+			t.WriteString(" IS NULL)")
+		}
+		terms = append(terms, "("+t.String()+")")
+	}
+	b.WriteString(" AND ")
+	if len(terms) == 0 {
+		b.WriteString("FALSE")
+		return
+	}
+	b.WriteByte('(')
+	b.WriteString(strings.Join(terms, " OR "))
+	b.WriteByte(')')
+}
@@ -0,0 +1,235 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package query
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ParseError is an error found while parsing a query
+// located at the token which caused it.
+type ParseError struct {
+	// Message describes the error.
+	Message string `json:"message"`
+	// Token is the failing token. Empty at the end of the input.
+	Token string `json:"token,omitempty"`
+	// TokenIndex is the index of the failing token. At the end
+	// of the input it is the number of tokens.
+	TokenIndex int `json:"token_index"`
+	// Offset is the character offset of the failing token.
+	Offset int `json:"offset"`
+	// Expected are the value types expected by the failing operation.
+	Expected []string `json:"expected,omitempty"`
+	// Stack are the value types on the stack before the failing token.
+	Stack []string `json:"stack"`
+	// Suggestions are possible corrections of misspelled tokens.
+	Suggestions []Suggestion `json:"suggestions,omitempty"`
+
+	located bool
+}
+
+// Suggestion proposes replacements for a probably misspelled token.
+type Suggestion struct {
+	// Token is the probably misspelled token.
+	Token string `json:"token"`
+	// TokenIndex is the index of the token.
+	TokenIndex int `json:"token_index"`
+	// Offset is the character offset of the token.
+	Offset int `json:"offset"`
+	// Candidates are the proposed replacements.
+	Candidates []string `json:"candidates"`
+}
+
+// Error implements [error].
+func (pe *ParseError) Error() string {
+	var b strings.Builder
+	b.WriteString(pe.Message)
+	if pe.located {
+		fmt.Fprintf(&b, " at position %d", pe.Offset+1)
+	}
+	for i := range pe.Suggestions {
+		s := &pe.Suggestions[i]
+		quoted := make([]string, len(s.Candidates))
+		for j, c := range s.Candidates {
+			quoted[j] = fmt.Sprintf("%q", c)
+		}
+		fmt.Fprintf(&b, "; did you mean %s instead of %q?",
+			strings.Join(quoted, " or "), s.Token)
+	}
+	return b.String()
+}
+
+// fail raises a parse error expecting the given types.
+func fail(msg string, expected ...valueType) {
+	pe := &ParseError{Message: msg}
+	for _, vt := range expected {
+		pe.Expected = append(pe.Expected, vt.String())
+	}
+	panic(pe)
+}
+
+// toParseError converts a recovered panic to a parse error.
+func toParseError(x any) (*ParseError, bool) {
+	switch err := x.(type) {
+	case *ParseError:
+		return err, true
+	case parseError:
+		return &ParseError{Message: string(err)}, true
+	}
+	return nil, false
+}
+
+// locate sets the location of the error if it is not already located.
+func (pe *ParseError) locate(token string, index, offset int, st stack) *ParseError {
+	if pe.located {
+		return pe
+	}
+	pe.located = true
+	pe.Token = token
+	pe.TokenIndex = index
+	pe.Offset = offset
+	pe.Stack = st.types()
+	return pe
+}
+
+// types returns the value types of the stack from the bottom to the top.
+func (st stack) types() []string {
+	types := make([]string, len(st))
+	for i, e := range st {
+		types[i] = e.valueType.String()
+	}
+	return types
+}
+
+// editDistance returns the edit distance between two strings
+// counting the transposition of neighboring characters as one edit.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// similar returns up to three of the candidates which are
+// close to the given word, the closest first.
+func similar(word string, candidates []string) []string {
+	limit := 2
+	if len([]rune(word)) <= 4 {
+		limit = 1
+	}
+	type scored struct {
+		name     string
+		distance int
+	}
+	var found []scored
+	for _, c := range candidates {
+		if d := editDistance(strings.ToLower(word), c); d <= limit && d > 0 {
+			found = append(found, scored{c, d})
+		}
+	}
+	slices.SortFunc(found, func(a, b scored) int {
+		return cmp.Or(cmp.Compare(a.distance, b.distance), cmp.Compare(a.name, b.name))
+	})
+	names := make([]string, 0, 3)
+	for i := 0; i < len(found) && i < 3; i++ {
+		names = append(names, found[i].name)
+	}
+	return names
+}
+
+// columnNames returns the names of the columns usable in filters of the given mode.
+func columnNames(mode ParserMode) []string {
+	var names []string
+	for i := range documentColumns {
+		if col := &documentColumns[i]; !col.projectionOnly && slices.Contains(col.modes, mode) {
+			names = append(names, col.name)
+		}
+	}
+	return names
+}
+
+// operatorNames returns the names of the word like operators.
+func operatorNames(mode ParserMode) []string {
+	var names []string
+	for name := range maps.Keys(action[mode]) {
+		if r := []rune(name); len(r) > 1 && unicode.IsLetter(r[0]) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// unknownColumn raises an error for a column not usable in the parser mode.
+func (p *Parser) unknownColumn(name string) {
+	if existsAnyColumn(name) {
+		fail(fmt.Sprintf("column %q cannot be used in %s queries", name, p.Mode))
+	}
+	pe := &ParseError{Message: fmt.Sprintf("unknown column %q", name)}
+	if candidates := similar(name, columnNames(p.Mode)); len(candidates) > 0 {
+		pe.Suggestions = []Suggestion{{Token: name, Candidates: candidates}}
+	}
+	panic(pe)
+}
+
+// remember records an unquoted token which was taken as a string.
+// If parsing fails it is checked if it is a misspelled operator.
+func (p *Parser) remember(token string, index, offset int) {
+	if r := []rune(token); len(r) > 1 && unicode.IsLetter(r[0]) {
+		p.bareWords = append(p.bareWords, Suggestion{
+			Token:      token,
+			TokenIndex: index,
+			Offset:     offset,
+		})
+	}
+}
+
+// withSuggestions attaches suggestions for the remembered tokens
+// looking like misspelled operators to an error. It also fills in the
+// position of the suggestions created at the failing token.
+func (p *Parser) withSuggestions(pe *ParseError) *ParseError {
+	for i := range pe.Suggestions {
+		if s := &pe.Suggestions[i]; s.TokenIndex == 0 && s.Offset == 0 {
+			s.TokenIndex, s.Offset = pe.TokenIndex, pe.Offset
+		}
+	}
+	if len(pe.Suggestions) > 0 || len(p.bareWords) == 0 {
+		return pe
+	}
+	operators := operatorNames(p.Mode)
+	for _, bw := range p.bareWords {
+		if bw.Candidates = similar(bw.Token, operators); len(bw.Candidates) > 0 {
+			pe.Suggestions = append(pe.Suggestions, bw)
+		}
+	}
+	return pe
+}
@@ -43,6 +43,14 @@ const (
 	sub
 	mul
 	div
+	cve
+	ilikeCVE
+	cwe
+	hasRemediation
+	hasThreat
+	hasProductStatus
+	fullText
+	jsonPath
 )
 
 type valueType int
@@ -190,6 +198,22 @@ func (et exprType) String() string {
 		return "*"
 	case div:
 		return "/"
+	case cve:
+		return "cve"
+	case ilikeCVE:
+		return "ilikecve"
+	case cwe:
+		return "cwe"
+	case hasRemediation:
+		return "has_remediation"
+	case hasThreat:
+		return "has_threat"
+	case hasProductStatus:
+		return "has_product_status"
+	case fullText:
+		return "fulltext"
+	case jsonPath:
+		return "jsonpath"
 	default:
 		return fmt.Sprintf("unknown expression type %d", et)
 	}
@@ -220,12 +244,22 @@ func (e *Expr) Accesses() iter.Seq[string] {
 	))
 }
 
+// usesJSONPath checks if the expression tree contains a JSONPath test.
+func (e *Expr) usesJSONPath() bool {
+	for x := range e.all() {
+		if x.exprType == jsonPath {
+			return true
+		}
+	}
+	return false
+}
+
 // Aliases returns a sequence over all search aliases in the expression tree.
 func (e *Expr) Aliases() iter.Seq[string] {
 	return itertools.Unique(itertools.Apply(itertools.Filter(
 		e.all(),
 		func(e *Expr) bool {
-			return e.exprType == search && e.alias != ""
+			return (e.exprType == search || e.exprType == fullText) && e.alias != ""
 		}),
 		(*Expr).getAlias,
 	))
@@ -339,8 +373,7 @@ func True() *Expr {
 
 func (e *Expr) checkValueType(vt valueType) {
 	if e.valueType != vt {
-		panic(parseError(
-			fmt.Sprintf("value type mismatch: %q %q", e.valueType, vt)))
+		fail(fmt.Sprintf("value type mismatch: %q %q", e.valueType, vt), vt)
 	}
 }
 
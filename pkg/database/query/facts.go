@@ -0,0 +1,46 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package query
+
+import "strings"
+
+// factWhere writes a test against the facts extracted from the vulnerabilities
+// of the document identified by the docID column. recurse writes the operand.
+func factWhere(e *Expr, b *strings.Builder, docID string, recurse func(*Expr)) {
+	switch e.exprType {
+	case cve:
+		b.WriteString(`EXISTS(SELECT 1 FROM documents_cves ` +
+			`JOIN unique_cves ON documents_cves.cve_id = unique_cves.id ` +
+			`WHERE documents_cves.documents_id = ` + docID + ` AND unique_cves.cve = `)
+		recurse(e.children[0])
+	case ilikeCVE:
+		b.WriteString(`EXISTS(SELECT 1 FROM documents_cves ` +
+			`JOIN unique_cves ON documents_cves.cve_id = unique_cves.id ` +
+			`WHERE documents_cves.documents_id = ` + docID + ` AND unique_cves.cve ILIKE ` + ilikePrefix)
+		recurse(e.children[0])
+		b.WriteString(ilikeSuffix)
+	case cwe:
+		b.WriteString(`EXISTS(SELECT 1 FROM documents_cwes ` +
+			`WHERE documents_cwes.documents_id = ` + docID + ` AND cwe = `)
+		recurse(e.children[0])
+	case hasRemediation:
+		b.WriteString(`EXISTS(SELECT 1 FROM documents_remediations ` +
+			`WHERE documents_remediations.documents_id = ` + docID + ` AND category = `)
+		recurse(e.children[0])
+	case hasThreat:
+		b.WriteString(`EXISTS(SELECT 1 FROM documents_threats ` +
+			`WHERE documents_threats.documents_id = ` + docID + ` AND category = `)
+		recurse(e.children[0])
+	case hasProductStatus:
+		b.WriteString(`EXISTS(SELECT 1 FROM documents_product_status ` +
+			`WHERE documents_product_status.documents_id = ` + docID + ` AND status = `)
+		recurse(e.children[0])
+	}
+	b.WriteByte(')')
+}
@@ -0,0 +1,251 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package query
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// operatorSymbols are the tokens of the operators in both notations.
+var operatorSymbols = map[exprType]string{
+	and:        "and",
+	or:         "or",
+	not:        "not",
+	eq:         "=",
+	ne:         "!=",
+	gt:         ">",
+	lt:         "<",
+	ge:         ">=",
+	le:         "<=",
+	search:     "search",
+	fullText:   "fulltext",
+	mentioned:  "mentioned",
+	involved:   "involved",
+	ilike:      "ilike",
+	ilikePName: "ilikepname",
+	ilikePID:   "ilikepid",
+	now:        "now",
+	add:        "+",
+	sub:        "-",
+	mul:        "*",
+	div:        "/",
+
+	cve:              "cve",
+	ilikeCVE:         "ilikecve",
+	cwe:              "cwe",
+	hasRemediation:   "has_remediation",
+	hasThreat:        "has_threat",
+	hasProductStatus: "has_product_status",
+	jsonPath:         "jsonpath",
+}
+
+// Precedences of the operators in the infix notation.
+const (
+	precOr = iota + 1
+	precAnd
+	precNot
+	precCmp
+	precSum
+	precProduct
+	precPrimary
+)
+
+// Format formats the expression in the given syntax.
+// Parsing the result again results in the same expression.
+func (e *Expr) Format(syntax Syntax) string {
+	var b strings.Builder
+	if syntax == InfixSyntax {
+		e.writeInfix(&b, 0)
+	} else {
+		e.writeRPN(&b)
+	}
+	return b.String()
+}
+
+// RPN formats the expression in reverse polish notation.
+func (e *Expr) RPN() string { return e.Format(RPNSyntax) }
+
+// Infix formats the expression in infix notation.
+func (e *Expr) Infix() string { return e.Format(InfixSyntax) }
+
+// quote quotes a string so that it is read back as a string in both notations.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		if r == '"' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// literal returns the text of a constant without its type
+// and if it needs an explicit cast in the infix notation.
+func (e *Expr) literal() (string, bool) {
+	switch e.valueType {
+	case boolType:
+		return strconv.FormatBool(e.boolValue), false
+	case intType:
+		return strconv.FormatInt(e.intValue, 10), false
+	case floatType:
+		if math.IsInf(e.floatValue, 0) || math.IsNaN(e.floatValue) {
+			return strconv.FormatFloat(e.floatValue, 'g', -1, 64), true
+		}
+		s := strconv.FormatFloat(e.floatValue, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s, false
+	case timeType:
+		s := e.timeValue.Format(time.RFC3339Nano)
+		return s, infixTimeRe.FindString(s) != s
+	case durationType:
+		return e.durationValue.String(), false
+	case stringType:
+		return e.stringValue, false
+	default: // enums
+		return e.stringValue, true
+	}
+}
+
+func (e *Expr) writeRPN(b *strings.Builder) {
+	switch e.exprType {
+	case cnst:
+		lit, _ := e.literal()
+		switch e.valueType {
+		case boolType:
+			b.WriteString(lit)
+		case stringType:
+			b.WriteString(quote(lit))
+		default:
+			b.WriteString(lit)
+			b.WriteByte(' ')
+			b.WriteString(e.valueType.String())
+		}
+	case cast:
+		e.children[0].writeRPN(b)
+		b.WriteByte(' ')
+		b.WriteString(e.valueType.String())
+	case access:
+		b.WriteByte('$')
+		b.WriteString(e.stringValue)
+	case search, mentioned, involved, fullText:
+		b.WriteString(quote(e.stringValue))
+		b.WriteByte(' ')
+		b.WriteString(operatorSymbols[e.exprType])
+		if e.alias != "" {
+			b.WriteByte(' ')
+			b.WriteString(quote(e.alias))
+			b.WriteString(" as")
+		}
+	default:
+		for _, child := range e.children {
+			child.writeRPN(b)
+			b.WriteByte(' ')
+		}
+		b.WriteString(operatorSymbols[e.exprType])
+	}
+}
+
+// precedence returns the binding strength of the expression in infix notation.
+func (e *Expr) precedence() int {
+	switch e.exprType {
+	case or:
+		return precOr
+	case and:
+		return precAnd
+	case not:
+		return precNot
+	case eq, ne, gt, lt, ge, le, ilike:
+		return precCmp
+	case add, sub:
+		return precSum
+	case mul, div:
+		return precProduct
+	default:
+		return precPrimary
+	}
+}
+
+func (e *Expr) writeInfix(b *strings.Builder, minPrec int) {
+	prec := e.precedence()
+	if prec < minPrec {
+		b.WriteByte('(')
+		defer b.WriteByte(')')
+	}
+	switch e.exprType {
+	case cnst:
+		lit, needsCast := e.literal()
+		switch {
+		case e.valueType == stringType:
+			b.WriteString(quote(lit))
+		case needsCast:
+			b.WriteString(e.valueType.String())
+			b.WriteByte('(')
+			b.WriteString(quote(lit))
+			b.WriteByte(')')
+		case strings.HasPrefix(lit, "-") && minPrec > precSum:
+			// Negative literals as operands of products.
+			b.WriteByte('(')
+			b.WriteString(lit)
+			b.WriteByte(')')
+		default:
+			b.WriteString(lit)
+		}
+	case cast:
+		b.WriteString(e.valueType.String())
+		b.WriteByte('(')
+		e.children[0].writeInfix(b, 0)
+		b.WriteByte(')')
+	case access:
+		// Columns named like operators need the explicit prefix.
+		if _, ok := baseAction[e.stringValue]; ok {
+			b.WriteByte('$')
+		}
+		b.WriteString(e.stringValue)
+	case now:
+		b.WriteString(operatorSymbols[now])
+	case search, mentioned, involved, fullText:
+		b.WriteString(operatorSymbols[e.exprType])
+		b.WriteByte('(')
+		b.WriteString(quote(e.stringValue))
+		b.WriteByte(')')
+		if e.alias != "" {
+			b.WriteString(" as ")
+			b.WriteString(quote(e.alias))
+		}
+	case ilikePName, ilikePID,
+		cve, ilikeCVE, cwe, hasRemediation, hasThreat, hasProductStatus, jsonPath:
+		b.WriteString(operatorSymbols[e.exprType])
+		b.WriteByte('(')
+		e.children[0].writeInfix(b, 0)
+		b.WriteByte(')')
+	case not:
+		b.WriteString("not ")
+		e.children[0].writeInfix(b, precNot)
+	case eq, ne, gt, lt, ge, le, ilike:
+		e.children[0].writeInfix(b, precCmp+1)
+		b.WriteByte(' ')
+		b.WriteString(operatorSymbols[e.exprType])
+		b.WriteByte(' ')
+		e.children[1].writeInfix(b, precCmp+1)
+	default: // Left associative binary operators
+		e.children[0].writeInfix(b, prec)
+		b.WriteByte(' ')
+		b.WriteString(operatorSymbols[e.exprType])
+		b.WriteByte(' ')
+		e.children[1].writeInfix(b, prec+1)
+	}
+}
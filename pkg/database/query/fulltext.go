@@ -0,0 +1,167 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package query
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// textSearchConfigs are the text search configurations the
+// documents are stemmed with. They have to match the
+// text_search_config function in the database.
+var textSearchConfigs = []string{"english", "german", "simple"}
+
+// headlineOptions configure the highlighted snippets.
+const headlineOptions = `'MaxFragments=2, MaxWords=20, MinWords=5'`
+
+// fullTextTerms splits a full-text search into the terms and phrases
+// which have to be found and the excluded ones.
+// The syntax follows the websearch_to_tsquery function of PostgreSQL.
+func fullTextTerms(s string) (included, excluded []string) {
+	for s = strings.TrimLeftFunc(s, unicode.IsSpace); s != ""; s = strings.TrimLeftFunc(s, unicode.IsSpace) {
+		exclude := false
+		if rest, ok := strings.CutPrefix(s, "-"); ok {
+			exclude, s = true, rest
+		}
+		var term string
+		if rest, ok := strings.CutPrefix(s, `"`); ok {
+			phrase, after, _ := strings.Cut(rest, `"`)
+			s = after
+			if phrase = strings.TrimSpace(phrase); phrase == "" {
+				continue
+			}
+			term = `"` + phrase + `"`
+		} else {
+			end := strings.IndexFunc(s, unicode.IsSpace)
+			if end < 0 {
+				end = len(s)
+			}
+			term, s = s[:end], s[end:]
+			// "or" is an operator.
+			if term == "" || (!exclude && strings.EqualFold(term, "or")) {
+				continue
+			}
+		}
+		if exclude {
+			excluded = append(excluded, term)
+		} else {
+			included = append(included, term)
+		}
+	}
+	return included, excluded
+}
+
+// highlightQuery returns the query to find the texts of a document
+// to highlight. These are the texts containing any of the included terms.
+func highlightQuery(s string) (string, error) {
+	included, _ := fullTextTerms(s)
+	if len(included) == 0 {
+		return "", errors.New("full-text search needs at least one term which is not excluded")
+	}
+	return strings.Join(included, " or "), nil
+}
+
+// pushFullText pushes a ranked full-text search.
+func (p *Parser) pushFullText(st *stack) {
+	term := st.pop()
+	term.checkValueType(stringType)
+	// Parameters are only type checked without arguments.
+	if p.Arguments != nil || term.stringValue != "" {
+		p.checkSearchLength(term.stringValue)
+		if _, err := highlightQuery(term.stringValue); err != nil {
+			panic(parseError(err.Error()))
+		}
+	}
+	p.UsedSources.add(documentsTable)
+	st.push(&Expr{
+		exprType:    fullText,
+		valueType:   boolType,
+		stringValue: term.stringValue,
+	})
+}
+
+// fullTextWhere writes the test if the document identified by
+// the docID column matches the full-text search in the replacement.
+// There is a test per text search configuration to use the index.
+func fullTextWhere(b *strings.Builder, docID string, replacement int) {
+	b.WriteString(docID + ` IN (SELECT documents_id FROM documents_search WHERE `)
+	for i, config := range textSearchConfigs {
+		if i > 0 {
+			b.WriteString(" OR ")
+		}
+		fmt.Fprintf(b,
+			`(config = '%[1]s'::regconfig AND ts @@ websearch_to_tsquery('%[1]s', $%[2]d))`,
+			config, replacement)
+	}
+	b.WriteByte(')')
+}
+
+// fullTextIndex returns the number of a full-text search in the query.
+func (sb *AdvancedSQLBuilder) fullTextIndex(e *Expr) int {
+	return slices.Index(sb.fullTexts, e)
+}
+
+// createFullTextJoins joins the ranks of the full-text searches if the
+// relevance is needed and the highlighted snippets of the projected aliases.
+func (sb *AdvancedSQLBuilder) createFullTextJoins(b *strings.Builder, docID string) {
+	if sb.counting {
+		return
+	}
+	if sb.relevance {
+		for i, ft := range sb.fullTexts {
+			replacement := sb.replacementIndex(ft.stringValue) + 1
+			// 1: divide by 1 + the logarithm of the length, 32: scale to [0, 1).
+			fmt.Fprintf(b,
+				` LEFT JOIN LATERAL (`+
+					`SELECT ts_rank_cd(ts, websearch_to_tsquery(config, $%[1]d), 33)::float8 AS rank`+
+					` FROM documents_search WHERE documents_id = %[2]s`+
+					` AND ts @@ websearch_to_tsquery(config, $%[1]d)) _fts_rank_%[3]d ON TRUE`,
+				replacement, docID, i)
+		}
+	}
+	for _, name := range sb.fields {
+		ft := sb.alias(name)
+		if ft == nil || ft.exprType != fullText {
+			continue
+		}
+		// The query was checked by the parser.
+		highlight, _ := highlightQuery(ft.stringValue)
+		replacement := sb.replacementIndex(highlight) + 1
+		fmt.Fprintf(b,
+			` LEFT JOIN LATERAL (`+
+				`SELECT ts_headline(ds.config, txt, q, `+headlineOptions+`) AS snippet`+
+				` FROM documents_search ds CROSS JOIN LATERAL websearch_to_tsquery(ds.config, $%d) AS q`+
+				` JOIN documents_texts ON documents_texts.documents_id = ds.documents_id`+
+				` JOIN unique_texts ON unique_texts.id = documents_texts.txt_id`+
+				` WHERE ds.documents_id = %s AND to_tsvector(ds.config, txt) @@ q`+
+				` ORDER BY ts_rank_cd(to_tsvector(ds.config, txt), q) DESC`,
+			replacement, docID)
+		// Without aggregation there is only one row per document.
+		if !sb.aggregate {
+			b.WriteString(" LIMIT 1")
+		}
+		fmt.Fprintf(b, ") _fts_snippet_%d ON TRUE", sb.fullTextIndex(ft))
+	}
+}
+
+// relevanceTerm writes the sum of the ranks of the full-text searches.
+func (sb *AdvancedSQLBuilder) relevanceTerm(b *strings.Builder) {
+	b.WriteByte('(')
+	for i := range sb.fullTexts {
+		if i > 0 {
+			b.WriteByte('+')
+		}
+		fmt.Fprintf(b, "COALESCE(_fts_rank_%d.rank,0)", i)
+	}
+	b.WriteByte(')')
+}
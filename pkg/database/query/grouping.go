@@ -0,0 +1,322 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package query
+
+import (
+	"fmt"
+	"iter"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Group is a term to group the rows by. It is a column
+// whose timestamps may be truncated to a date bucket.
+type Group struct {
+	// Column is the name of the column.
+	Column string
+	// Bucket is the precision to truncate a timestamp column to.
+	// One of "day", "week", "month", "quarter" or "year" or empty.
+	Bucket string
+}
+
+// Aggregate is a function applied to the rows of a group.
+type Aggregate struct {
+	// Func is one of "count", "min", "max" or "avg".
+	Func string
+	// Column is the column the function is applied to.
+	// Only "count" may be used without a column to count the rows.
+	Column string
+}
+
+// Grouping describes an aggregating query.
+type Grouping struct {
+	// Groups are the terms to group by.
+	Groups []Group
+	// Aggregates are the functions calculated per group.
+	Aggregates []Aggregate
+	// Orders are names of groups or aggregates prefixed
+	// with '-' for descending order.
+	Orders []string
+}
+
+var validBuckets = []string{"day", "week", "month", "quarter", "year"}
+
+// ParseGroup parses a group from "column" or "column:bucket".
+func ParseGroup(s string) (Group, error) {
+	column, bucket, _ := strings.Cut(s, ":")
+	if column == "" {
+		return Group{}, fmt.Errorf("missing column in group %q", s)
+	}
+	if bucket != "" && !slices.Contains(validBuckets, bucket) {
+		return Group{}, fmt.Errorf("invalid bucket %q (valid: %s)",
+			bucket, strings.Join(validBuckets, ", "))
+	}
+	return Group{Column: column, Bucket: bucket}, nil
+}
+
+// ParseAggregate parses an aggregate from "count" or "function:column".
+func ParseAggregate(s string) (Aggregate, error) {
+	fn, column, _ := strings.Cut(s, ":")
+	switch fn {
+	case "count":
+	case "min", "max", "avg":
+		if column == "" {
+			return Aggregate{}, fmt.Errorf("aggregate %q needs a column", fn)
+		}
+	default:
+		return Aggregate{}, fmt.Errorf("unknown aggregate function %q", fn)
+	}
+	return Aggregate{Func: fn, Column: column}, nil
+}
+
+// Name returns the name of the group in the results.
+func (g *Group) Name() string {
+	if g.Bucket == "" {
+		return g.Column
+	}
+	return g.Column + "_" + g.Bucket
+}
+
+// Name returns the name of the aggregate in the results.
+func (a *Aggregate) Name() string {
+	if a.Column == "" {
+		return a.Func
+	}
+	return a.Func + "_" + a.Column
+}
+
+// Fields returns the names of the result columns.
+func (g *Grouping) Fields() []string {
+	fields := make([]string, 0, len(g.Groups)+len(g.Aggregates))
+	for i := range g.Groups {
+		fields = append(fields, g.Groups[i].Name())
+	}
+	for i := range g.Aggregates {
+		fields = append(fields, g.Aggregates[i].Name())
+	}
+	return fields
+}
+
+// columns returns a sequence over the accessed columns.
+func (g *Grouping) columns() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		if g == nil {
+			return
+		}
+		for i := range g.Groups {
+			if !yield(g.Groups[i].Column) {
+				return
+			}
+		}
+		for i := range g.Aggregates {
+			if c := g.Aggregates[i].Column; c != "" && !yield(c) {
+				return
+			}
+		}
+	}
+}
+
+// check tests if the columns exist and are of suitable types.
+func (g *Grouping) check(mode ParserMode, add func(columnSource)) error {
+	if len(g.Aggregates) == 0 {
+		return fmt.Errorf("grouping needs at least one aggregate")
+	}
+	column := func(name string) (*documentColumn, error) {
+		col := findDocumentColumn(name, mode)
+		if col == nil || col.projectionOnly {
+			return nil, fmt.Errorf("column %q cannot be aggregated", name)
+		}
+		add(col.sources)
+		return col, nil
+	}
+	for i := range g.Groups {
+		grp := &g.Groups[i]
+		col, err := column(grp.Column)
+		if err != nil {
+			return err
+		}
+		if grp.Bucket != "" && col.valueType != timeType {
+			return fmt.Errorf("column %q is not a timestamp to be bucketed", grp.Column)
+		}
+	}
+	for i := range g.Aggregates {
+		agg := &g.Aggregates[i]
+		if agg.Column == "" {
+			continue
+		}
+		col, err := column(agg.Column)
+		if err != nil {
+			return err
+		}
+		var valid []valueType
+		switch agg.Func {
+		case "avg":
+			valid = []valueType{intType, floatType}
+		case "min", "max":
+			valid = []valueType{intType, floatType, timeType}
+		}
+		if valid != nil && !slices.Contains(valid, col.valueType) {
+			return fmt.Errorf("%s cannot be applied to column %q of type %q",
+				agg.Func, agg.Column, col.valueType)
+		}
+	}
+	fields := g.Fields()
+	for i, f := range fields {
+		if slices.Contains(fields[:i], f) {
+			return fmt.Errorf("duplicate result column %q", f)
+		}
+	}
+	for _, o := range g.Orders {
+		if !slices.Contains(fields, strings.TrimPrefix(o, "-")) {
+			return fmt.Errorf("order field %q is neither a group nor an aggregate", o)
+		}
+	}
+	return nil
+}
+
+// AdvancedSQLBuilderGrouping creates an option to create an advanced SQL builder
+// for an aggregating query.
+func AdvancedSQLBuilderGrouping(grouping *Grouping) AdvancedSQLBuilderOption {
+	return func(ab *AdvancedSQLBuilder) {
+		ab.grouping = grouping
+	}
+}
+
+// GroupFields returns the names of the result columns of the aggregating query.
+func (sb *AdvancedSQLBuilder) GroupFields() []string {
+	return sb.grouping.Fields()
+}
+
+// groupTerm writes the SQL expression of a group.
+func (sb *AdvancedSQLBuilder) groupTerm(b *strings.Builder, sm statementMode, grp *Group) {
+	access := &Expr{exprType: access, stringValue: grp.Column}
+	if grp.Bucket != "" {
+		b.WriteString("date_trunc('")
+		b.WriteString(grp.Bucket)
+		b.WriteString("', ")
+		sm.accessWhere(sb, access, b)
+		b.WriteByte(')')
+		return
+	}
+	switch col := findDocumentColumn(grp.Column, sb.mode()); col.valueType {
+	case workflowType, eventsType, statusType:
+		b.WriteByte('(')
+		sm.accessWhere(sb, access, b)
+		b.WriteString(")::text")
+	default:
+		sm.accessWhere(sb, access, b)
+	}
+}
+
+// aggregateTerm writes the SQL expression of an aggregate.
+func (sb *AdvancedSQLBuilder) aggregateTerm(b *strings.Builder, sm statementMode, agg *Aggregate) {
+	if agg.Column == "" {
+		b.WriteString("count(*)")
+		return
+	}
+	b.WriteString(agg.Func)
+	b.WriteByte('(')
+	sm.accessWhere(sb, &Expr{exprType: access, stringValue: agg.Column}, b)
+	b.WriteByte(')')
+	if agg.Func == "avg" {
+		b.WriteString("::float")
+	}
+}
+
+// createGroupQuery writes the aggregating query without order and limits.
+func (sb *AdvancedSQLBuilder) createGroupQuery(b *strings.Builder) {
+	sm := statementMode(classicMode{})
+	if sb.usedSources.contains(documentsTable | advisoriesTable) {
+		sm = cteMode{}
+		sb.prefixCTE(b)
+	}
+	b.WriteString("SELECT ")
+	fields := sb.grouping.Fields()
+	for i := range sb.grouping.Groups {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		sb.groupTerm(b, sm, &sb.grouping.Groups[i])
+		fmt.Fprintf(b, ` AS "%s"`, fields[i])
+	}
+	for i := range sb.grouping.Aggregates {
+		if i+len(sb.grouping.Groups) > 0 {
+			b.WriteByte(',')
+		}
+		sb.aggregateTerm(b, sm, &sb.grouping.Aggregates[i])
+		fmt.Fprintf(b, ` AS "%s"`, fields[i+len(sb.grouping.Groups)])
+	}
+	b.WriteString(" FROM ")
+	sm.from(sb, b)
+	b.WriteString(" WHERE ")
+	sb.createWhere(b, sm)
+	if len(sb.grouping.Groups) > 0 {
+		b.WriteString(" GROUP BY ")
+		for i := range sb.grouping.Groups {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.Itoa(i + 1))
+		}
+	}
+}
+
+// CreateGroupCountSQL returns an SQL statement to count the groups.
+func (sb *AdvancedSQLBuilder) CreateGroupCountSQL() string {
+	var b strings.Builder
+	b.WriteString("SELECT count(*) FROM (")
+	sb.createGroupQuery(&b)
+	b.WriteString(") AS groups")
+	return b.String()
+}
+
+// CreateGroupQuery creates an SQL statement to aggregate the
+// filtered rows per group. Without explicit orders the result
+// is ordered by the groups.
+// WARN: Make sure that the input is vetted against injections.
+func (sb *AdvancedSQLBuilder) CreateGroupQuery(limit, offset int64) string {
+	var b strings.Builder
+	sb.createGroupQuery(&b)
+
+	orders := sb.grouping.Orders
+	if len(orders) == 0 {
+		for i := range sb.grouping.Groups {
+			orders = append(orders, sb.grouping.Groups[i].Name())
+		}
+	}
+	for i, o := range orders {
+		if i == 0 {
+			b.WriteString(" ORDER BY ")
+		} else {
+			b.WriteByte(',')
+		}
+		name, desc := strings.CutPrefix(o, "-")
+		fmt.Fprintf(&b, `"%s"`, name)
+		if desc {
+			b.WriteString(" DESC")
+		} else {
+			b.WriteString(" ASC")
+		}
+	}
+
+	if limit >= 0 {
+		b.WriteString(" LIMIT ")
+		b.WriteString(strconv.FormatInt(limit, 10))
+	}
+	if offset > 0 {
+		b.WriteString(" OFFSET ")
+		b.WriteString(strconv.FormatInt(offset, 10))
+	}
+
+	query := b.String()
+	slog.Debug("sql builder", "group query", query)
+	return query
+}
@@ -0,0 +1,585 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Syntax is the notation a query is written in.
+type Syntax int
+
+const (
+	// RPNSyntax is the reverse polish notation.
+	RPNSyntax Syntax = iota
+	// InfixSyntax is the infix notation with operator precedence and parentheses.
+	InfixSyntax
+)
+
+// ParseSyntax parses a syntax from a string.
+// An empty string is the reverse polish notation.
+func ParseSyntax(s string) (Syntax, error) {
+	var syntax Syntax
+	if s == "" {
+		return syntax, nil
+	}
+	err := syntax.UnmarshalText([]byte(s))
+	return syntax, err
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+func (s *Syntax) UnmarshalText(text []byte) error {
+	switch x := string(text); x {
+	case "rpn":
+		*s = RPNSyntax
+	case "infix":
+		*s = InfixSyntax
+	default:
+		return fmt.Errorf("unknown syntax %q", x)
+	}
+	return nil
+}
+
+// MarshalText implements [encoding.TextMarshaler].
+func (s Syntax) MarshalText() ([]byte, error) {
+	switch s {
+	case RPNSyntax, InfixSyntax:
+		return []byte(s.String()), nil
+	default:
+		return nil, fmt.Errorf("unknown syntax %d", s)
+	}
+}
+
+// String implements [fmt.Stringer].
+func (s Syntax) String() string {
+	switch s {
+	case RPNSyntax:
+		return "rpn"
+	case InfixSyntax:
+		return "infix"
+	default:
+		return fmt.Sprintf("Unknown syntax: %d", s)
+	}
+}
+
+// Scan implements [sql.Scanner].
+func (s *Syntax) Scan(src any) error {
+	if x, ok := src.(string); ok {
+		return s.UnmarshalText([]byte(x))
+	}
+	return fmt.Errorf("unsupported type %T", src)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenParam
+	tokenString
+	tokenInteger
+	tokenFloat
+	tokenTime
+	tokenDuration
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+// String implements [fmt.Stringer].
+func (tk tokenKind) String() string {
+	switch tk {
+	case tokenEOF:
+		return "end of input"
+	case tokenIdent:
+		return "identifier"
+	case tokenParam:
+		return "parameter"
+	case tokenString:
+		return "string"
+	case tokenInteger:
+		return "integer"
+	case tokenFloat:
+		return "float"
+	case tokenTime:
+		return "timestamp"
+	case tokenDuration:
+		return "duration"
+	case tokenOperator:
+		return "operator"
+	case tokenLParen:
+		return "'('"
+	case tokenRParen:
+		return "')'"
+	case tokenComma:
+		return "','"
+	default:
+		return fmt.Sprintf("unknown token %d", tk)
+	}
+}
+
+// token is a lexical element of an infix query.
+type token struct {
+	kind  tokenKind
+	value string
+	// pos is the character offset of the token in the input.
+	pos int
+	// index is the index of the token.
+	index int
+}
+
+var (
+	infixTimeRe = regexp.MustCompile(
+		`^\d{4}-\d{2}-\d{2}(?:T\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?)?`)
+	infixDurationRe = regexp.MustCompile(`^(?:\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h))+`)
+	infixFloatRe    = regexp.MustCompile(`^\d+(?:\.\d+(?:[eE][+-]?\d+)?|[eE][+-]?\d+)`)
+	infixIntegerRe  = regexp.MustCompile(`^\d+`)
+)
+
+// infixOperators are the symbolic operators sorted so that longer ones match first.
+var infixOperators = []string{"!=", "<=", ">=", "=", "<", ">", "+", "-", "*", "/"}
+
+// infixNullary are the keywords which are used without parentheses.
+var infixNullary = []string{"true", "false", "now", "me"}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// lexInfix splits an infix query into tokens.
+func lexInfix(input string) []token {
+	rs := []rune(input)
+	// offsets maps character positions to byte offsets in the input.
+	offsets := make([]int, len(rs)+1)
+	for i, o := 0, 0; i < len(rs); i++ {
+		offsets[i] = o
+		o += len(string(rs[i]))
+	}
+	offsets[len(rs)] = len(input)
+
+	var tokens []token
+	// lexFail raises an error for the token starting at position i.
+	lexFail := func(i int, msg string) {
+		panic((&ParseError{Message: msg}).locate(
+			string(rs[i:min(i+1, len(rs))]), len(tokens), i, nil))
+	}
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, value: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, value: ")", pos: i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, value: ",", pos: i})
+			i++
+		case r == '"' || r == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(rs) && rs[j] != r; j++ {
+				if rs[j] == '\\' && j+1 < len(rs) {
+					j++
+				}
+				b.WriteRune(rs[j])
+			}
+			if j >= len(rs) {
+				lexFail(i, "unterminated string")
+			}
+			tokens = append(tokens, token{kind: tokenString, value: b.String(), pos: i})
+			i = j + 1
+		case r == '?' || r == '$' || r == '_' || unicode.IsLetter(r):
+			j := i + 1
+			for j < len(rs) && isIdentRune(rs[j]) {
+				j++
+			}
+			kind := tokenIdent
+			if r == '?' {
+				kind = tokenParam
+			}
+			if j == i+1 && (r == '?' || r == '$') {
+				lexFail(i, fmt.Sprintf("missing name after %q", r))
+			}
+			tokens = append(tokens, token{kind: kind, value: string(rs[i:j]), pos: i})
+			i = j
+		case r >= '0' && r <= '9':
+			rest := input[offsets[i]:]
+			var kind tokenKind
+			var lit string
+			for _, x := range []struct {
+				kind tokenKind
+				re   *regexp.Regexp
+			}{
+				{tokenTime, infixTimeRe},
+				{tokenDuration, infixDurationRe},
+				{tokenFloat, infixFloatRe},
+				{tokenInteger, infixIntegerRe},
+			} {
+				if lit = x.re.FindString(rest); lit != "" {
+					kind = x.kind
+					break
+				}
+			}
+			j := i + len([]rune(lit))
+			if j < len(rs) && (isIdentRune(rs[j]) || rs[j] == '.') {
+				end := j
+				for end < len(rs) && (isIdentRune(rs[end]) || rs[end] == '.') {
+					end++
+				}
+				lexFail(i, fmt.Sprintf("invalid literal %q", string(rs[i:end])))
+			}
+			tokens = append(tokens, token{kind: kind, value: lit, pos: i})
+			i = j
+		default:
+			var op string
+			for _, o := range infixOperators {
+				if strings.HasPrefix(input[offsets[i]:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				lexFail(i, fmt.Sprintf("unexpected character %q", r))
+			}
+			tokens = append(tokens, token{kind: tokenOperator, value: op, pos: i})
+			i += len(op)
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(rs)})
+	for i := range tokens {
+		tokens[i].index = i
+	}
+	return tokens
+}
+
+// infixParser compiles an infix query by feeding the
+// actions of the reverse polish notation in the right order.
+// This ensures that both notations result in the same expressions.
+type infixParser struct {
+	p      *Parser
+	acts   map[string]func(*Parser, *stack)
+	st     stack
+	tokens []token
+	next   int
+}
+
+// peek returns the current token.
+func (ip *infixParser) peek() *token {
+	return &ip.tokens[ip.next]
+}
+
+// advance consumes the current token and returns it.
+func (ip *infixParser) advance() *token {
+	t := &ip.tokens[ip.next]
+	if t.kind != tokenEOF {
+		ip.next++
+	}
+	return t
+}
+
+// isKeyword checks if the current token is the given keyword.
+func (ip *infixParser) isKeyword(kw string) bool {
+	t := ip.peek()
+	return t.kind == tokenIdent && strings.EqualFold(t.value, kw)
+}
+
+// expect consumes a token of the given kind or fails.
+func (ip *infixParser) expect(kind tokenKind) *token {
+	t := ip.peek()
+	if t.kind != kind {
+		ip.fail(t, fmt.Sprintf("expected %s but found %s", kind, describeToken(t)))
+	}
+	return ip.advance()
+}
+
+// fail raises a parse error located at the given token.
+func (ip *infixParser) fail(t *token, msg string) {
+	ip.failWith(t, &ParseError{Message: msg})
+}
+
+// failWith raises the given parse error located at the given token.
+func (ip *infixParser) failWith(t *token, pe *ParseError) {
+	panic(pe.locate(t.value, t.index, t.pos, ip.st))
+}
+
+// at runs fn and locates the parse errors raised by it at the given token.
+func (ip *infixParser) at(t *token, fn func()) {
+	before := slices.Clone(ip.st)
+	defer func() {
+		if x := recover(); x != nil {
+			if pe, ok := toParseError(x); ok {
+				panic(pe.locate(t.value, t.index, t.pos, before))
+			}
+			panic(x)
+		}
+	}()
+	fn()
+}
+
+// act applies the action of the reverse polish notation with the given name.
+func (ip *infixParser) act(t *token, name string) {
+	act := ip.acts[name]
+	if act == nil {
+		ip.fail(t, fmt.Sprintf("unknown operator %q", name))
+	}
+	ip.at(t, func() { act(ip.p, &ip.st) })
+}
+
+func describeToken(t *token) string {
+	if t.kind == tokenEOF {
+		return t.kind.String()
+	}
+	return fmt.Sprintf("%s %q", t.kind, t.value)
+}
+
+// parseInfix parses a query in infix notation.
+// The type of the resulting expression is not checked.
+func (p *Parser) parseInfix(input string) (*Expr, int) {
+	p.aliases = nil
+	p.bareWords = nil
+
+	ip := infixParser{
+		p:      p,
+		acts:   action[p.Mode],
+		tokens: lexInfix(input),
+	}
+	end := len(ip.tokens) - 1
+	if end == 0 {
+		return True(), end
+	}
+	ip.parseOr()
+	if t := ip.peek(); t.kind != tokenEOF {
+		ip.fail(t, "unexpected "+describeToken(t))
+	}
+	return ip.st.top(), end
+}
+
+func (ip *infixParser) parseOr() {
+	ip.parseAnd()
+	for ip.isKeyword("or") {
+		t := ip.advance()
+		ip.parseAnd()
+		ip.act(t, "or")
+	}
+}
+
+func (ip *infixParser) parseAnd() {
+	ip.parseNot()
+	for ip.isKeyword("and") {
+		t := ip.advance()
+		ip.parseNot()
+		ip.act(t, "and")
+	}
+}
+
+func (ip *infixParser) parseNot() {
+	if ip.isKeyword("not") {
+		t := ip.advance()
+		ip.parseNot()
+		ip.act(t, "not")
+		return
+	}
+	ip.parseCmp()
+}
+
+func (ip *infixParser) parseCmp() {
+	ip.parseSum()
+	t := ip.peek()
+	var op string
+	switch {
+	case t.kind == tokenOperator &&
+		(t.value == "=" || t.value == "!=" ||
+			t.value == "<" || t.value == "<=" ||
+			t.value == ">" || t.value == ">="):
+		op = t.value
+	case ip.isKeyword("ilike"):
+		op = "ilike"
+	default:
+		return
+	}
+	ip.advance()
+	ip.parseSum()
+	ip.at(t, ip.coerce)
+	ip.act(t, op)
+}
+
+func (ip *infixParser) parseSum() {
+	ip.parseProduct()
+	for t := ip.peek(); t.kind == tokenOperator && (t.value == "+" || t.value == "-"); t = ip.peek() {
+		ip.advance()
+		ip.parseProduct()
+		ip.act(t, t.value)
+	}
+}
+
+func (ip *infixParser) parseProduct() {
+	ip.parsePostfix()
+	for t := ip.peek(); t.kind == tokenOperator && (t.value == "*" || t.value == "/"); t = ip.peek() {
+		ip.advance()
+		ip.parsePostfix()
+		ip.act(t, t.value)
+	}
+}
+
+// parsePostfix handles the aliasing of searches with 'as'.
+func (ip *infixParser) parsePostfix() {
+	ip.parsePrimary()
+	for ip.isKeyword("as") {
+		t := ip.advance()
+		alias := ip.peek()
+		if alias.kind != tokenIdent && alias.kind != tokenString {
+			ip.fail(alias, "expected alias but found "+describeToken(alias))
+		}
+		ip.advance()
+		ip.st.pushString(alias.value)
+		ip.act(t, "as")
+	}
+}
+
+func (ip *infixParser) parsePrimary() {
+	t := ip.advance()
+	switch t.kind {
+	case tokenLParen:
+		ip.parseOr()
+		ip.expect(tokenRParen)
+	case tokenString:
+		ip.st.pushString(t.value)
+	case tokenInteger, tokenFloat, tokenTime, tokenDuration:
+		ip.pushLiteral(t, "")
+	case tokenOperator:
+		// A minus sign directly in front of a number or a duration.
+		if lit := ip.peek(); t.value == "-" &&
+			(lit.kind == tokenInteger || lit.kind == tokenFloat || lit.kind == tokenDuration) &&
+			lit.pos == t.pos+1 {
+			ip.advance()
+			ip.pushLiteral(lit, "-")
+			return
+		}
+		ip.fail(t, "unexpected "+describeToken(t))
+	case tokenParam:
+		ip.at(t, func() { ip.p.pushParameter(&ip.st, t.value[1:]) })
+	case tokenIdent:
+		ip.parseIdent(t)
+	default:
+		ip.fail(t, "unexpected "+describeToken(t))
+	}
+}
+
+// pushLiteral pushes a typed literal.
+func (ip *infixParser) pushLiteral(t *token, sign string) {
+	ip.st.pushString(sign + t.value)
+	switch t.kind {
+	case tokenInteger:
+		ip.act(t, "integer")
+	case tokenFloat:
+		ip.act(t, "float")
+	case tokenTime:
+		ip.act(t, "timestamp")
+	case tokenDuration:
+		ip.act(t, "duration")
+	}
+}
+
+// parseIdent handles columns, function calls and keywords.
+func (ip *infixParser) parseIdent(t *token) {
+	name := strings.ToLower(t.value)
+	// Function call
+	if ip.peek().kind == tokenLParen && !strings.HasPrefix(name, "$") {
+		ip.advance()
+		if ip.peek().kind != tokenRParen {
+			ip.parseOr()
+			for ip.peek().kind == tokenComma {
+				ip.advance()
+				ip.parseOr()
+			}
+		}
+		ip.expect(tokenRParen)
+		if ip.acts[name] == nil || ip.acts["$"+name] != nil {
+			pe := &ParseError{Message: fmt.Sprintf("unknown function %q", t.value)}
+			if candidates := similar(name, operatorNames(ip.p.Mode)); len(candidates) > 0 {
+				pe.Suggestions = []Suggestion{{
+					Token:      t.value,
+					TokenIndex: t.index,
+					Offset:     t.pos,
+					Candidates: candidates,
+				}}
+			}
+			ip.failWith(t, pe)
+		}
+		ip.act(t, name)
+		return
+	}
+	column := strings.TrimPrefix(t.value, "$")
+	if act := ip.acts["$"+column]; act != nil {
+		ip.at(t, func() { act(ip.p, &ip.st) })
+		return
+	}
+	for _, kw := range infixNullary {
+		if name == kw {
+			ip.act(t, kw)
+			return
+		}
+	}
+	ip.at(t, func() { ip.p.unknownColumn(column) })
+}
+
+// existsAnyColumn checks if there is a column with the given name in any mode.
+func existsAnyColumn(name string) bool {
+	for i := range documentColumns {
+		if documentColumns[i].name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// coerce converts constants of the two top most operands to the type
+// of the other operand if they differ. This allows comparisons like
+// 'cvss_v3_score >= 5' or 'state = "review"' without explicit casts.
+func (ip *infixParser) coerce() {
+	right, left := ip.st.topN(0), ip.st.topN(1)
+	if left.valueType == right.valueType {
+		return
+	}
+	n := len(ip.st)
+	if right.exprType == cnst {
+		ip.st[n-1] = ip.convert(right, left.valueType)
+	} else if left.exprType == cnst {
+		ip.st[n-2] = ip.convert(left, right.valueType)
+	}
+}
+
+// convert converts a constant to the given type if this is possible.
+func (ip *infixParser) convert(e *Expr, vt valueType) *Expr {
+	switch {
+	case e.valueType == intType && vt == floatType:
+		return &Expr{
+			exprType:   cnst,
+			valueType:  floatType,
+			floatValue: float64(e.intValue),
+		}
+	case e.valueType == stringType:
+		switch vt {
+		case intType, floatType, timeType, durationType,
+			workflowType, eventsType, statusType:
+			st := stack{e}
+			ip.acts[vt.String()](ip.p, &st)
+			return st.pop()
+		}
+	}
+	return e
+}
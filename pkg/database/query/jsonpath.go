@@ -0,0 +1,201 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package query
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// VirtualColumn is a column whose values are taken from
+// the documents by a JSONPath expression.
+type VirtualColumn struct {
+	// Name is the name of the column usable as $name.
+	Name string
+	// Path is the JSONPath expression selecting the value.
+	// If it selects more than one item the first is taken.
+	Path string
+	// Type is the type of the values.
+	// One of "string" (default), "integer", "float", "timestamp" or "bool".
+	Type string
+}
+
+var virtualColumnRe = regexp.MustCompile(`^[a-z_][a-z_0-9]*$`)
+
+var (
+	// registerMu guards the registration of the virtual columns.
+	registerMu sync.Mutex
+	// registered is set if the virtual columns are registered.
+	registered bool
+	// virtualColumns are the registered virtual columns.
+	virtualColumns []VirtualColumn
+)
+
+// virtualColumnTypes maps the types of virtual columns to
+// their value types and the casts of the selected texts.
+var virtualColumnTypes = map[string]struct {
+	valueType valueType
+	cast      string
+}{
+	"":          {stringType, ""},
+	"string":    {stringType, ""},
+	"integer":   {intType, "::numeric::bigint"},
+	"float":     {floatType, "::float8"},
+	"timestamp": {timeType, "::timestamptz"},
+	"bool":      {boolType, "::boolean"},
+}
+
+// RegisterVirtualColumns adds the virtual columns to the columns
+// usable in all queries. It has to be called before any query is parsed
+// and can only be called successfully once.
+func RegisterVirtualColumns(vcs []VirtualColumn) error {
+	registerMu.Lock()
+	defer registerMu.Unlock()
+	if registered {
+		return errors.New("virtual columns are already registered")
+	}
+	columns := slices.Clone(documentColumns)
+	for i := range vcs {
+		vc := &vcs[i]
+		if !virtualColumnRe.MatchString(vc.Name) {
+			return fmt.Errorf("invalid name %q of virtual column", vc.Name)
+		}
+		if _, ok := virtualColumnTypes[vc.Type]; !ok {
+			return fmt.Errorf("virtual column %q has unknown type %q", vc.Name, vc.Type)
+		}
+		if strings.TrimSpace(vc.Path) == "" {
+			return fmt.Errorf("virtual column %q has no path", vc.Name)
+		}
+		if err := checkJSONPath(vc.Path); err != nil {
+			return fmt.Errorf("virtual column %q has invalid path: %w", vc.Name, err)
+		}
+		for j := range columns {
+			if columns[j].name == vc.Name {
+				return fmt.Errorf("virtual column %q is already a column", vc.Name)
+			}
+		}
+		columns = append(columns, documentColumn{
+			name:      vc.Name,
+			valueType: virtualColumnTypes[vc.Type].valueType,
+			modes:     docAdvEvtModes,
+			sources:   documentsTable,
+		})
+	}
+	documentColumns = columns
+	virtualColumns = slices.Clone(vcs)
+	for mode := range action {
+		action[mode] = buildActions(mode)
+	}
+	registered = true
+	return nil
+}
+
+// originalDocument is the document as uploaded. In documents.document
+// the strings are replaced by the indices into unique_texts.
+const originalDocument = `convert_from(documents.original, 'UTF8')::jsonb`
+
+// virtualColumnTerm returns the SQL expression of a virtual column
+// on the documents table. If the column is not virtual it returns
+// the empty string.
+func virtualColumnTerm(name string) string {
+	for i := range virtualColumns {
+		if vc := &virtualColumns[i]; vc.Name == name {
+			return "(jsonb_path_query_first(" + originalDocument + ", '" +
+				strings.ReplaceAll(vc.Path, "'", "''") +
+				"') #>> '{}')" + virtualColumnTypes[vc.Type].cast
+		}
+	}
+	return ""
+}
+
+// writeColumn writes the SQL expression of a virtual column
+// or the name of the column.
+func writeColumn(b *strings.Builder, name string) {
+	if term := virtualColumnTerm(name); term != "" {
+		b.WriteString(term)
+	} else {
+		b.WriteString(name)
+	}
+}
+
+// writeProjection writes the projection of a virtual column
+// or the name of the column.
+func writeProjection(b *strings.Builder, name string) {
+	if term := virtualColumnTerm(name); term != "" {
+		b.WriteString(term + " AS " + name)
+	} else {
+		b.WriteString(name)
+	}
+}
+
+// checkJSONPath does a basic syntax check of a JSONPath expression
+// so that obvious errors are found before the query is run.
+// The string literals have to be terminated and the brackets
+// have to be balanced. The expression has to refer to the document by $.
+func checkJSONPath(path string) error {
+	var (
+		stack   []rune
+		dollar  bool
+		quote   rune
+		escaped bool
+	)
+	closing := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	for _, r := range path {
+		switch {
+		case quote != 0:
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+		case r == '"':
+			quote = r
+		case r == '$':
+			dollar = true
+		case r == '(' || r == '[' || r == '{':
+			stack = append(stack, r)
+		case closing[r] != 0:
+			if len(stack) == 0 || stack[len(stack)-1] != closing[r] {
+				return fmt.Errorf("unbalanced %q", r)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	switch {
+	case quote != 0:
+		return errors.New("unterminated string")
+	case len(stack) > 0:
+		return fmt.Errorf("unclosed %q", stack[len(stack)-1])
+	case !dollar:
+		return errors.New("missing $")
+	}
+	return nil
+}
+
+// jsonPathWhere writes the test of a JSONPath expression against the
+// given document. A predicate check decides by its result, other
+// expressions by selecting any item.
+func jsonPathWhere(e *Expr, b *strings.Builder, document string, recurse func(*Expr)) {
+	path := func() {
+		recurse(e.children[0])
+		b.WriteString("::text::jsonpath")
+	}
+	b.WriteString(`COALESCE(jsonb_path_match(` + document + `, `)
+	path()
+	b.WriteString(`, '{}', true), jsonb_path_exists(` + document + `, `)
+	path()
+	b.WriteString(`, '{}', true))`)
+}
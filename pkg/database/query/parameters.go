@@ -0,0 +1,152 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// Parameter declares a named and typed placeholder
+// which can be used as ?name in a query.
+type Parameter struct {
+	Name    string  `json:"name"`
+	Type    string  `json:"type"`
+	Default *string `json:"default,omitempty"`
+}
+
+// parameterTypes are the types a parameter can have.
+var parameterTypes = map[string]valueType{
+	intType.String():      intType,
+	floatType.String():    floatType,
+	boolType.String():     boolType,
+	stringType.String():   stringType,
+	timeType.String():     timeType,
+	workflowType.String(): workflowType,
+	durationType.String(): durationType,
+	eventsType.String():   eventsType,
+	statusType.String():   statusType,
+}
+
+var parameterNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z_0-9]*$`)
+
+// ValidateParameters checks if the given parameter declarations
+// have valid and unique names, known types and default values
+// matching these types.
+func ValidateParameters(params []Parameter) (err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			if pe, ok := toParseError(x); ok {
+				err = pe
+			} else {
+				panic(x)
+			}
+		}
+	}()
+	for i := range params {
+		prm := &params[i]
+		if !parameterNameRe.MatchString(prm.Name) {
+			return parseError(fmt.Sprintf("invalid parameter name %q", prm.Name))
+		}
+		if slices.ContainsFunc(params[:i], func(o Parameter) bool { return o.Name == prm.Name }) {
+			return parseError(fmt.Sprintf("duplicate parameter %q", prm.Name))
+		}
+		vt, ok := parameterTypes[prm.Type]
+		if !ok {
+			return parseError(fmt.Sprintf("parameter %q has unknown type %q", prm.Name, prm.Type))
+		}
+		if prm.Default != nil {
+			var st stack
+			(*Parser).pushArgument(nil, &st, vt, *prm.Default)
+		}
+	}
+	return nil
+}
+
+// findParameter looks up the declaration of a parameter.
+func (p *Parser) findParameter(name string) *Parameter {
+	for i := range p.Parameters {
+		if prm := &p.Parameters[i]; prm.Name == name {
+			return prm
+		}
+	}
+	return nil
+}
+
+// pushParameter pushes the value of a parameter converted to its declared type.
+// If no arguments are given only a typed placeholder is pushed for type checking.
+func (p *Parser) pushParameter(st *stack, name string) {
+	prm := p.findParameter(name)
+	if prm == nil {
+		panic(parseError(fmt.Sprintf("undeclared parameter %q", name)))
+	}
+	vt, ok := parameterTypes[prm.Type]
+	if !ok {
+		panic(parseError(fmt.Sprintf("parameter %q has unknown type %q", prm.Name, prm.Type)))
+	}
+	switch value, ok := p.Arguments[name]; {
+	case ok:
+		p.pushArgument(st, vt, value)
+	case prm.Default != nil:
+		p.pushArgument(st, vt, *prm.Default)
+	case p.Arguments == nil:
+		st.push(&Expr{
+			exprType:  cnst,
+			valueType: vt,
+		})
+	default:
+		panic(parseError(fmt.Sprintf("missing value for parameter %q", name)))
+	}
+}
+
+// pushArgument pushes a parameter value converted to the given type.
+func (p *Parser) pushArgument(st *stack, vt valueType, value string) {
+	switch vt {
+	case boolType:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			panic(parseError(fmt.Sprintf("%q is not a bool: %v", value, err)))
+		}
+		if b {
+			st.push(True())
+		} else {
+			st.push(False())
+		}
+		return
+	case stringType:
+		st.pushString(value)
+		return
+	}
+	st.pushString(value)
+	switch vt {
+	case intType:
+		p.pushInteger(st)
+	case floatType:
+		p.pushFloat(st)
+	case timeType:
+		p.pushTimestamp(st)
+	case durationType:
+		p.pushDuration(st)
+	case workflowType:
+		pushEnum(workflowType, parseWorkflow)(p, st)
+	case eventsType:
+		pushEnum(eventsType, parseEvents)(p, st)
+	case statusType:
+		pushEnum(statusType, parseStatus)(p, st)
+	}
+}
+
+// Equal checks if two parameter declarations are the same.
+func (p Parameter) Equal(o Parameter) bool {
+	return p.Name == o.Name && p.Type == o.Type &&
+		(p.Default == nil) == (o.Default == nil) &&
+		(p.Default == nil || *p.Default == *o.Default)
+}
@@ -18,6 +18,7 @@ import (
 	"strings"
 	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/gocsaf/csaf/v3/csaf"
 )
@@ -42,12 +43,21 @@ type Parser struct {
 	MinSearchLength int
 	// Me is a replacement text for the "me" keyword.
 	Me string
+	// Syntax is the notation of the parsed queries.
+	Syntax Syntax
+	// Parameters are the declared parameters usable as ?name.
+	Parameters []Parameter
+	// Arguments are the values of the parameters.
+	// If nil the parameters are only type checked.
+	Arguments map[string]string
 
 	// UsedSources are the sources found during parsing.
 	UsedSources columnSource
 
 	// aliases defined by 'as'.
 	aliases map[string]*Expr
+	// bareWords are unquoted tokens taken as strings.
+	bareWords []Suggestion
 }
 
 // columnSource is a type to accumulate the tables needed to build an SQL query.
@@ -115,6 +125,8 @@ var documentColumns = []documentColumn{
 	{"four_cves", stringType, docAdvEvtModes, true, documentsTable},
 	{"comments", intType, docAdvEvtModes, false, documentsTable},
 	{"tracking_status", statusType, docAdvEvtModes, false, documentsTable},
+	{"known_affected_count", intType, docAdvEvtModes, false, documentsTable},
+	{"relevance", floatType, docAdvEvtModes, true, documentsTable},
 	// Advisories only
 	{"state", workflowType, advModes, false, advisoriesTable},
 	{"recent", timeType, advModes, false, advisoriesTable},
@@ -152,6 +164,9 @@ var (
 		"ilike":      (*Parser).pushILike,
 		"ilikepname": pushTypedILike(ilikePName),
 		"ilikepid":   pushTypedILike(ilikePID),
+		"cve":        pushFact(cve),
+		"ilikecve":   pushFact(ilikeCVE),
+		"cwe":        pushFact(cwe),
 		"now":        (*Parser).pushNow,
 		"duration":   (*Parser).pushDuration,
 		"+":          curry3((*Parser).pushBinary, add),
@@ -162,7 +177,14 @@ var (
 		"mentioned":  (*Parser).pushMentioned,
 		"involved":   (*Parser).pushInvolved,
 		"search":     (*Parser).pushSearch,
+		"fulltext":   (*Parser).pushFullText,
 		"as":         (*Parser).pushAs,
+		// Facts extracted from the vulnerabilities.
+		"has_remediation":    pushFact(hasRemediation),
+		"has_threat":         pushFact(hasThreat),
+		"has_product_status": pushFact(hasProductStatus),
+		// Predicates on the documents.
+		"jsonpath": (*Parser).pushJSONPath,
 	}
 	// action is for fast looking up actions along the parser mode.
 	action = map[ParserMode]map[string]func(*Parser, *stack){
@@ -337,9 +359,15 @@ func (*Parser) pushBinary(st *stack, et exprType) {
 		right:    right.valueType,
 	}]
 	if !ok {
-		panic(parseError(
-			fmt.Sprintf("invalid binary operation: %q %q %q",
-				left.valueType, et, right.valueType)))
+		var expected []valueType
+		for bc := range binaryCompatMatrix {
+			if bc.left == left.valueType && bc.operator == et {
+				expected = append(expected, bc.right)
+			}
+		}
+		slices.Sort(expected)
+		fail(fmt.Sprintf("invalid binary operation: %q %q %q",
+			left.valueType, et, right.valueType), expected...)
 	}
 	st.push(&Expr{
 		exprType:  et,
@@ -373,7 +401,7 @@ func (*Parser) pushFloat(st *stack) {
 		case intType:
 			st.push(&Expr{
 				exprType:   cnst,
-				valueType:  intType,
+				valueType:  floatType,
 				floatValue: float64(e.intValue),
 			})
 		}
@@ -457,9 +485,8 @@ func (*Parser) pushCmp(st *stack, et exprType) {
 	right := st.pop()
 	left := st.pop()
 	if right.valueType != left.valueType {
-		panic(parseError(
-			fmt.Sprintf("incompatible types: left %q right %q",
-				left.valueType, right.valueType)))
+		fail(fmt.Sprintf("incompatible types: left %q right %q",
+			left.valueType, right.valueType), left.valueType)
 	}
 	st.push(&Expr{
 		exprType:  et,
@@ -568,6 +595,31 @@ func pushTypedILike(typ exprType) func(*Parser, *stack) {
 	}
 }
 
+func pushFact(typ exprType) func(*Parser, *stack) {
+	return func(p *Parser, st *stack) {
+		value := st.pop()
+		value.checkValueType(stringType)
+		p.UsedSources.add(documentsTable)
+		st.push(&Expr{
+			exprType:  typ,
+			valueType: boolType,
+			children:  []*Expr{value},
+		})
+	}
+}
+
+func (p *Parser) pushJSONPath(st *stack) {
+	value := st.top()
+	value.checkValueType(stringType)
+	if value.exprType == cnst {
+		if err := checkJSONPath(value.stringValue); err != nil {
+			panic(parseError(
+				fmt.Sprintf("invalid JSONPath %q: %v", value.stringValue, err)))
+		}
+	}
+	pushFact(jsonPath)(p, st)
+}
+
 func (*Parser) pushNow(st *stack) {
 	st.push(&Expr{
 		exprType:  now,
@@ -607,7 +659,7 @@ func (p *Parser) pushAs(st *stack) {
 	alias := st.pop()
 	srch := st.top()
 	alias.checkValueType(stringType)
-	srch.checkExprType(search) // TODO: Add csearch?
+	srch.checkExprType(search, fullText) // TODO: Add csearch?
 	validAlias(alias.stringValue)
 	if p.aliases == nil {
 		p.aliases = map[string]*Expr{}
@@ -615,24 +667,49 @@ func (p *Parser) pushAs(st *stack) {
 	if p.aliases[alias.stringValue] != nil {
 		panic(parseError(fmt.Sprintf("duplicate alias %q", alias.stringValue)))
 	}
-	p.UsedSources.add(documentsTable | textTable)
+	if srch.exprType == search {
+		p.UsedSources.add(documentsTable | textTable)
+	}
 	srch.alias = alias.stringValue
 	srch.intValue = int64(len(p.aliases))
 	p.aliases[alias.stringValue] = srch
 }
 
-func (p *Parser) parse(input string) (*Expr, error) {
+// parse parses a query in reverse polish notation.
+// The type of the resulting expression is not checked.
+func (p *Parser) parse(input string) (*Expr, int) {
 	p.aliases = nil
+	p.bareWords = nil
 
 	st := stack{}
 	acts := action[p.Mode]
 
-	split(input, func(field string, isString bool) {
+	tokens := 0
+	splitPos(input, func(field string, isString bool, offset int) {
+		index := tokens
+		tokens++
+		before := slices.Clone(st)
+		defer func() {
+			if x := recover(); x != nil {
+				if pe, ok := toParseError(x); ok {
+					panic(pe.locate(field, index, offset, before))
+				}
+				panic(x)
+			}
+		}()
 		if !isString {
 			if act := acts[field]; act != nil {
 				act(p, &st)
 				return
 			}
+			if name, ok := strings.CutPrefix(field, "?"); ok && name != "" {
+				p.pushParameter(&st, name)
+				return
+			}
+			if name, ok := strings.CutPrefix(field, "$"); ok && name != "" {
+				p.unknownColumn(name)
+			}
+			p.remember(field, index, offset)
 		}
 		st.pushString(field)
 	})
@@ -642,28 +719,73 @@ func (p *Parser) parse(input string) (*Expr, error) {
 	st.andReduce()
 
 	if len(st) != 1 {
-		return nil, parseError(fmt.Sprintf(
-			"invalid number of expression roots: expected 1 have %d", len(st)))
+		panic((&ParseError{Message: fmt.Sprintf(
+			"invalid number of expression roots: expected 1 have %d", len(st)),
+		}).locate("", tokens, utf8.RuneCountInString(input), st))
 	}
-	e := st.top()
-	e.checkValueType(boolType)
-	return e, nil
+	return st.top(), tokens
 }
 
-// Parse returns an expression.
-func (p *Parser) Parse(input string) (expr *Expr, err error) {
-	defer func() {
-		if x := recover(); x != nil {
-			if pe, ok := x.(parseError); ok {
-				err = pe
-			} else {
-				panic(x)
-			}
+// recoverParseError turns a recovered panic into a parse error.
+func (p *Parser) recoverParseError(err *error) {
+	if x := recover(); x != nil {
+		if pe, ok := toParseError(x); ok {
+			*err = p.withSuggestions(pe)
+		} else {
+			panic(x)
 		}
-	}()
+	}
+}
+
+// parseRoot parses the input in the configured syntax.
+// It returns the expression and the number of tokens.
+func (p *Parser) parseRoot(input string) (*Expr, int) {
+	if p.Syntax == InfixSyntax {
+		return p.parseInfix(input)
+	}
 	return p.parse(input)
 }
 
+// Parse returns an expression.
+// The errors returned are of type [*ParseError].
+func (p *Parser) Parse(input string) (expr *Expr, err error) {
+	defer p.recoverParseError(&err)
+	e, tokens := p.parseRoot(input)
+	if e.valueType != boolType {
+		panic((&ParseError{
+			Message:  fmt.Sprintf("query is of type %q but must be a filter", e.valueType),
+			Expected: []string{boolType.String()},
+		}).locate("", tokens, utf8.RuneCountInString(input), stack{e}))
+	}
+	return e, nil
+}
+
+// Inspection describes a query.
+type Inspection struct {
+	// Type is the inferred value type of the query.
+	Type string `json:"type"`
+	// Columns are the referenced columns.
+	Columns []string `json:"columns"`
+	// RPN is the query in reverse polish notation.
+	RPN string `json:"rpn"`
+	// Infix is the query in infix notation.
+	Infix string `json:"infix"`
+}
+
+// Inspect parses a query without requiring it to be a filter
+// and describes it.
+// The errors returned are of type [*ParseError].
+func (p *Parser) Inspect(input string) (insp *Inspection, err error) {
+	defer p.recoverParseError(&err)
+	e, _ := p.parseRoot(input)
+	return &Inspection{
+		Type:    e.valueType.String(),
+		Columns: slices.Sorted(e.Accesses()),
+		RPN:     e.RPN(),
+		Infix:   e.Infix(),
+	}, nil
+}
+
 func (st *stack) push(v *Expr) {
 	*st = append(*st, v)
 }
@@ -675,7 +797,7 @@ func (st *stack) pop() *Expr {
 		*st = (*st)[:l-1]
 		return x
 	}
-	panic(parseError("stack empty"))
+	panic(parseError("not enough arguments"))
 }
 
 func (st stack) top() *Expr { return st.topN(0) }
@@ -684,7 +806,7 @@ func (st stack) topN(n int) *Expr {
 	if l := len(st); l > n {
 		return st[l-n-1]
 	}
-	panic(parseError("stack empty"))
+	panic(parseError("not enough arguments"))
 }
 
 func (st *stack) pushString(s string) {
@@ -790,11 +912,21 @@ func validAlias(s string) {
 }
 
 func split(input string, fn func(string, bool)) {
+	splitPos(input, func(field string, isString bool, _ int) {
+		fn(field, isString)
+	})
+}
+
+// splitPos splits the input into fields and passes them
+// with the character offsets of their starts to fn.
+func splitPos(input string, fn func(string, bool, int)) {
 	var b strings.Builder
-	state := 0
+	state, start, pos := 0, 0, -1
 	for _, r := range input {
+		pos++
 		switch state {
 		case 0: // white space
+			start = pos
 			switch r {
 			case '"':
 				state = 1
@@ -811,7 +943,7 @@ func split(input string, fn func(string, bool)) {
 			case '\\':
 				state = 5
 			case '"':
-				fn(b.String(), true)
+				fn(b.String(), true, start)
 				b.Reset()
 				state = 0
 			default:
@@ -824,7 +956,7 @@ func split(input string, fn func(string, bool)) {
 			if r == '\\' {
 				state = 4
 			} else if unicode.IsSpace(r) {
-				fn(b.String(), false)
+				fn(b.String(), false, start)
 				b.Reset()
 				state = 0
 			} else {
@@ -839,6 +971,6 @@ func split(input string, fn func(string, bool)) {
 		}
 	}
 	if state != 0 {
-		fn(b.String(), state == 1 || state == 5)
+		fn(b.String(), state == 1 || state == 5, start)
 	}
 }
@@ -10,6 +10,8 @@ package query
 
 import (
 	"reflect"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -43,3 +45,490 @@ func TestSplit(t *testing.T) {
 		}
 	}
 }
+
+func TestParameters(t *testing.T) {
+	five, word := "5", "five"
+	params := []Parameter{
+		{Name: "score", Type: "float", Default: &five},
+		{Name: "since", Type: "timestamp"},
+	}
+	if err := ValidateParameters(params); err != nil {
+		t.Fatalf("valid parameters rejected: %v", err)
+	}
+	const q = `$cvss_v3_score ?score >= $current_release_date ?since > and`
+	for _, x := range []struct {
+		args    map[string]string
+		failing bool
+	}{
+		{nil, false},
+		{map[string]string{"since": "2024-01-01"}, false},
+		{map[string]string{"since": "2024-01-01", "score": "7.5"}, false},
+		{map[string]string{"score": "7.5"}, true},
+		{map[string]string{"since": "yesterday"}, true},
+	} {
+		p := Parser{Parameters: params, Arguments: x.args}
+		if _, err := p.Parse(q); (err != nil) != x.failing {
+			t.Errorf("args %v: expected failing %t got %v", x.args, x.failing, err)
+		}
+	}
+	for _, bad := range [][]Parameter{
+		{{Name: "1st", Type: "string"}},
+		{{Name: "a", Type: "string"}, {Name: "a", Type: "integer"}},
+		{{Name: "a", Type: "unknown"}},
+		{{Name: "a", Type: "integer", Default: &word}},
+	} {
+		if err := ValidateParameters(bad); err == nil {
+			t.Errorf("invalid parameters %v accepted", bad)
+		}
+	}
+	p := Parser{Parameters: params}
+	if _, err := p.Parse(`$title ?missing ilike`); err == nil {
+		t.Error("undeclared parameter accepted")
+	}
+}
+
+func TestInfix(t *testing.T) {
+	for _, x := range []struct {
+		infix string
+		rpn   string
+	}{
+		{
+			`cvss_v3_score >= 5.0 and current_release_date > 2023-12-31`,
+			`$cvss_v3_score 5 float >= $current_release_date 2023-12-31 timestamp > and`,
+		},
+		{
+			`cvss_v3_score >= 5 and (state = "review" or not search("foo bar") as s1)`,
+			`$cvss_v3_score 5 float >= $state review workflow = "foo bar" search s1 as not or and`,
+		},
+		{
+			`now - 24h * 3 < recent or title ilike "%x\"y%" or versions - -3 > 2 * (1 - 5)`,
+			`now 24h duration 3 integer * - $recent < $title "%x\"y%" ilike or ` +
+				`$versions -3 integer - 2 integer 1 integer 5 integer - * > or`,
+		},
+	} {
+		ip := Parser{Mode: AdvisoryMode, Syntax: InfixSyntax}
+		ie, err := ip.Parse(x.infix)
+		if err != nil {
+			t.Errorf("infix %q: %v", x.infix, err)
+			continue
+		}
+		rp := Parser{Mode: AdvisoryMode}
+		re, err := rp.Parse(x.rpn)
+		if err != nil {
+			t.Errorf("rpn %q: %v", x.rpn, err)
+			continue
+		}
+		if ie.RPN() != re.RPN() {
+			t.Errorf("infix %q: expected %q got %q", x.infix, re.RPN(), ie.RPN())
+		}
+		// Round trip through both notations.
+		for _, syntax := range []Syntax{RPNSyntax, InfixSyntax} {
+			p := Parser{Mode: AdvisoryMode, Syntax: syntax}
+			e, err := p.Parse(ie.Format(syntax))
+			if err != nil {
+				t.Errorf("%s round trip of %q: %v", syntax, ie.Format(syntax), err)
+				continue
+			}
+			if e.Infix() != ie.Infix() {
+				t.Errorf("%s round trip: expected %q got %q", syntax, ie.Infix(), e.Infix())
+			}
+		}
+	}
+	for _, x := range []struct {
+		infix string
+		err   string
+	}{
+		{`cvss_v3_score >= `, `unexpected end of input at position 18`},
+		{`(cvss_v3_score >= 5`, `expected ')' but found end of input at position 20`},
+		{`state = review`, `unknown column "review" at position 9`},
+		{`5.x > 3`, `invalid literal "5.x" at position 1`},
+		{`title = 'abc`, `unterminated string at position 9`},
+	} {
+		p := Parser{Mode: AdvisoryMode, Syntax: InfixSyntax}
+		if _, err := p.Parse(x.infix); err == nil || err.Error() != x.err {
+			t.Errorf("infix %q: expected error %q got %v", x.infix, x.err, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, x := range []struct {
+		syntax     Syntax
+		query      string
+		index      int
+		offset     int
+		expected   []string
+		stack      []string
+		candidates []string
+	}{
+		{RPNSyntax, `$cvss_v3_score 5 >=`, 2, 17, []string{"float"}, []string{"float", "string"}, nil},
+		{RPNSyntax, `$cvss_v3_scor 5 float >=`, 0, 0, nil, []string{}, []string{"cvss_v3_score", "cvss_v2_score"}},
+		{RPNSyntax, `$title "x" ilkie`, 3, 16, nil, []string{"string", "string", "string"}, []string{"ilike"}},
+		{RPNSyntax, `now 1 integer +`, 3, 14, []string{"duration"}, []string{"timestamp", "integer"}, nil},
+		{InfixSyntax, `serch("abc")`, 0, 0, nil, []string{"string"}, []string{"search"}},
+		{InfixSyntax, `cvss_v3_score + 1`, 3, 17, []string{"bool"}, []string{"float"}, nil},
+	} {
+		p := Parser{Mode: AdvisoryMode, Syntax: x.syntax}
+		_, err := p.Parse(x.query)
+		pe, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("%q: expected parse error got %v", x.query, err)
+			continue
+		}
+		var candidates []string
+		if len(pe.Suggestions) > 0 {
+			candidates = pe.Suggestions[0].Candidates
+		}
+		if pe.TokenIndex != x.index || pe.Offset != x.offset ||
+			!reflect.DeepEqual(pe.Expected, x.expected) ||
+			!reflect.DeepEqual(pe.Stack, x.stack) ||
+			!reflect.DeepEqual(candidates, x.candidates) {
+			t.Errorf("%q: unexpected error %+v", x.query, pe)
+		}
+	}
+
+	p := Parser{Mode: DocumentMode}
+	insp, err := p.Inspect(`$cvss_v3_score $rev_history_length +`)
+	if err != nil {
+		t.Fatalf("inspect failed: %v", err)
+	}
+	if insp.Type != "float" || !reflect.DeepEqual(insp.Columns, []string{"cvss_v3_score", "rev_history_length"}) {
+		t.Errorf("unexpected inspection %+v", insp)
+	}
+}
+
+func TestGrouping(t *testing.T) {
+	grouping := func(groups []string, aggregates ...string) *Grouping {
+		g := &Grouping{}
+		for _, s := range groups {
+			grp, err := ParseGroup(s)
+			if err != nil {
+				t.Fatalf("group %q: %v", s, err)
+			}
+			g.Groups = append(g.Groups, grp)
+		}
+		for _, s := range aggregates {
+			agg, err := ParseAggregate(s)
+			if err != nil {
+				t.Fatalf("aggregate %q: %v", s, err)
+			}
+			g.Aggregates = append(g.Aggregates, agg)
+		}
+		return g
+	}
+	p := Parser{Mode: AdvisoryMode}
+	expr, err := p.Parse(`$critical 5 float >`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := grouping([]string{"publisher", "state", "current_release_date:month"}, "count", "avg:critical")
+	g.Orders = []string{"-count"}
+	sb, err := NewAdvancedSQLBuilder(
+		AdvancedSQLBuilderExpr(expr),
+		AdvancedSQLBuilderParser(&p),
+		AdvancedSQLBuilderGrouping(g))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields := sb.GroupFields(); !reflect.DeepEqual(fields, []string{
+		"publisher", "state", "current_release_date_month", "count", "avg_critical",
+	}) {
+		t.Errorf("unexpected fields %q", fields)
+	}
+	const expected = `WITH docads AS (SELECT critical,publisher,state,current_release_date,documents.id AS id ` +
+		`FROM documents JOIN advisories ON documents.advisories_id = advisories.id)` +
+		`SELECT docads.publisher AS "publisher",(state)::text AS "state",` +
+		`date_trunc('month', current_release_date) AS "current_release_date_month",` +
+		`count(*) AS "count",avg(critical)::float AS "avg_critical" ` +
+		`FROM docads WHERE (((critical)>(5))) GROUP BY 1,2,3 ORDER BY "count" DESC LIMIT 10`
+	if sql := sb.CreateGroupQuery(10, 0); sql != expected {
+		t.Errorf("unexpected SQL:\n%s\nexpected:\n%s", sql, expected)
+	}
+
+	for _, bad := range []*Grouping{
+		grouping([]string{"title:month"}, "count"),
+		grouping([]string{"publisher"}, "avg:title"),
+		grouping([]string{"publisher"}),
+		grouping([]string{"unknown"}, "count"),
+		grouping([]string{"event"}, "count"),
+		{Groups: []Group{{Column: "publisher"}}, Aggregates: []Aggregate{{Func: "count"}}, Orders: []string{"title"}},
+	} {
+		if _, err := NewAdvancedSQLBuilder(
+			AdvancedSQLBuilderExpr(expr),
+			AdvancedSQLBuilderParser(&p),
+			AdvancedSQLBuilderGrouping(bad)); err == nil {
+			t.Errorf("invalid grouping %+v accepted", bad)
+		}
+	}
+	for _, s := range []string{"title:hour", ":day"} {
+		if _, err := ParseGroup(s); err == nil {
+			t.Errorf("invalid group %q accepted", s)
+		}
+	}
+	for _, s := range []string{"sum:critical", "avg"} {
+		if _, err := ParseAggregate(s); err == nil {
+			t.Errorf("invalid aggregate %q accepted", s)
+		}
+	}
+}
+
+func TestFacts(t *testing.T) {
+	const q = `"CVE-2024-%" ilikecve "CWE-79" cwe and vendor_fix has_remediation and ` +
+		`exploit_status has_threat $known_affected_count 2 integer > or and`
+	for _, mode := range []ParserMode{DocumentMode, AdvisoryMode, EventMode} {
+		p := Parser{Mode: mode}
+		expr, err := p.Parse(q)
+		if err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		if infix := expr.Infix(); infix != `ilikecve("CVE-2024-%") and cwe("CWE-79") and `+
+			`has_remediation("vendor_fix") and (has_threat("exploit_status") or known_affected_count > 2)` {
+			t.Errorf("%s: unexpected infix %q", mode, infix)
+		}
+		sb, err := NewAdvancedSQLBuilder(
+			AdvancedSQLBuilderExpr(expr),
+			AdvancedSQLBuilderParser(&p),
+			AdvancedSQLBuilderFields([]string{"id"}))
+		if err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		sql := sb.CreateQuery(-1, -1)
+		for _, table := range []string{
+			"unique_cves.cve ILIKE", "documents_cwes", "documents_remediations",
+			"documents_threats", "known_affected",
+		} {
+			if !strings.Contains(sql, table) {
+				t.Errorf("%s: %q missing in %s", mode, table, sql)
+			}
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	p := Parser{Mode: DocumentMode}
+	expr, err := p.Parse(`$critical 5 float >`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	build := func(cursor *Cursor, orders ...string) (*AdvancedSQLBuilder, error) {
+		return NewAdvancedSQLBuilder(
+			AdvancedSQLBuilderExpr(expr),
+			AdvancedSQLBuilderParser(&p),
+			AdvancedSQLBuilderFields([]string{"id", "title"}),
+			AdvancedSQLBuilderOrderFields(orders),
+			AdvancedSQLBuilderCursor(cursor))
+	}
+	if _, err := build(&Cursor{}, "title"); err == nil {
+		t.Error("cursor without id order accepted")
+	}
+	first, err := build(&Cursor{}, "-current_release_date", "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql := first.CreateQuery(10, 0); !strings.Contains(sql, "current_release_date AS _cursor_0,documents.id AS _cursor_1") ||
+		strings.Contains(sql, "$1") {
+		t.Errorf("unexpected first page SQL %s", sql)
+	}
+	row := map[string]any{
+		"id": int64(42), "title": "x", "_cursor_0": nil, "_cursor_1": int64(42),
+	}
+	next := first.NextCursor(row)
+	if len(row) != 2 || next == nil {
+		t.Fatalf("unexpected cursor %+v from row %v", next, row)
+	}
+	parsed, err := ParseCursor(next.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, next) {
+		t.Errorf("cursor round trip failed: %+v != %+v", parsed, next)
+	}
+	if _, err := build(parsed, "title", "id"); err == nil {
+		t.Error("cursor with different orders accepted")
+	}
+	second, err := build(parsed, "-current_release_date", "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const keyset = ` AND (((current_release_date IS NOT NULL)) OR ` +
+		`((current_release_date IS NULL) AND (documents.id > $1::bigint OR documents.id IS NULL)))`
+	if sql := second.CreateQuery(10, 0); !strings.Contains(sql, keyset) {
+		t.Errorf("missing keyset in %s", sql)
+	}
+	if !reflect.DeepEqual(second.Replacements, []any{"42"}) {
+		t.Errorf("unexpected replacements %v", second.Replacements)
+	}
+}
+
+func TestCursorCount(t *testing.T) {
+	p := Parser{Mode: DocumentMode}
+	expr, err := p.Parse(`$title "kernel" ilike`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := "42"
+	cursor := &Cursor{Orders: []string{"id"}, Values: []*string{&id}}
+	sb, err := NewAdvancedSQLBuilder(
+		AdvancedSQLBuilderExpr(expr),
+		AdvancedSQLBuilderParser(&p),
+		AdvancedSQLBuilderFields([]string{"id", "title"}),
+		AdvancedSQLBuilderOrderFields([]string{"id"}),
+		AdvancedSQLBuilderAggregate(true),
+		AdvancedSQLBuilderCursor(cursor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The count has to be created before the query
+	// as the cursor adds its values to the replacements.
+	countSQL := sb.CreateCountSQL()
+	countArgs := slices.Clone(sb.Replacements)
+	sql := sb.CreateQuery(10, 0)
+	if strings.Contains(countSQL, "$2") || len(countArgs) != 1 {
+		t.Errorf("count %s with %v uses the cursor", countSQL, countArgs)
+	}
+	if !strings.Contains(sql, "$2") || len(sb.Replacements) != 2 {
+		t.Errorf("query %s with %v misses the cursor", sql, sb.Replacements)
+	}
+}
+
+func TestFullText(t *testing.T) {
+	included, excluded := fullTextTerms(`kernel "remote code" -windows or  linux -"denial of service"`)
+	if !reflect.DeepEqual(included, []string{"kernel", `"remote code"`, "linux"}) {
+		t.Errorf("unexpected included terms %q", included)
+	}
+	if !reflect.DeepEqual(excluded, []string{"windows", `"denial of service"`}) {
+		t.Errorf("unexpected excluded terms %q", excluded)
+	}
+	p := Parser{Mode: DocumentMode}
+	if _, err := p.Parse(`"-windows" fulltext`); err == nil {
+		t.Error("full-text search without included terms accepted")
+	}
+	expr, err := p.Parse(`"kernel -windows" fulltext hits as $critical 5 float > and`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if infix := expr.Infix(); infix != `fulltext("kernel -windows") as "hits" and critical > 5.0` {
+		t.Errorf("unexpected infix %q", infix)
+	}
+	sb, err := NewAdvancedSQLBuilder(
+		AdvancedSQLBuilderExpr(expr),
+		AdvancedSQLBuilderParser(&p),
+		AdvancedSQLBuilderFields([]string{"id", "hits", "relevance"}),
+		AdvancedSQLBuilderOrderFields([]string{"-relevance", "id"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql := sb.CreateQuery(10, 0)
+	for _, part := range []string{
+		`_fts_snippet_0.snippet AS "hits"`,
+		`(COALESCE(_fts_rank_0.rank,0)) AS relevance`,
+		`ORDER BY (COALESCE(_fts_rank_0.rank,0)) DESC`,
+		`websearch_to_tsquery('german', $1)`,
+		`DESC LIMIT 1) _fts_snippet_0`,
+	} {
+		if !strings.Contains(sql, part) {
+			t.Errorf("%q missing in %s", part, sql)
+		}
+	}
+	if !reflect.DeepEqual(sb.Replacements, []any{"kernel -windows", "kernel"}) {
+		t.Errorf("unexpected replacements %q", sb.Replacements)
+	}
+	plain, err := p.Parse(`$critical 5 float >`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewAdvancedSQLBuilder(
+		AdvancedSQLBuilderExpr(plain),
+		AdvancedSQLBuilderParser(&p),
+		AdvancedSQLBuilderFields([]string{"id", "relevance"}),
+	); err == nil {
+		t.Error("relevance without full-text search accepted")
+	}
+}
+
+func TestJSONPath(t *testing.T) {
+	// Restore the registered columns for the other tests.
+	columns := documentColumns
+	t.Cleanup(func() {
+		documentColumns, virtualColumns, registered = columns, nil, false
+		for mode := range action {
+			action[mode] = buildActions(mode)
+		}
+	})
+	if err := RegisterVirtualColumns([]VirtualColumn{{Name: "Bad"}}); err == nil {
+		t.Error("invalid virtual column name accepted")
+	}
+	if err := RegisterVirtualColumns([]VirtualColumn{
+		{Name: "broken", Path: "$.document.notes[0"},
+	}); err == nil {
+		t.Error("virtual column with invalid path accepted")
+	}
+	if err := RegisterVirtualColumns([]VirtualColumn{
+		{Name: "severity_text", Path: "$.document.aggregate_severity.text"},
+		{Name: "flags", Path: "$.vulnerabilities.flags.size()", Type: "integer"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterVirtualColumns([]VirtualColumn{
+		{Name: "other", Path: "$.document.title"},
+	}); err == nil {
+		t.Error("second registration accepted")
+	}
+	p := Parser{Mode: DocumentMode, Syntax: InfixSyntax}
+	for _, bad := range []string{
+		`jsonpath("$.document.notes[*] ? (@.category == \"summary\"")`,
+		`jsonpath("$.document.title == \"open")`,
+		`jsonpath("document.title")`,
+	} {
+		if _, err := p.Parse(bad); err == nil {
+			t.Errorf("invalid JSONPath %s accepted", bad)
+		} else if _, ok := err.(*ParseError); !ok {
+			t.Errorf("%s: unexpected error type %T", bad, err)
+		}
+	}
+	expr, err := p.Parse(`jsonpath("$.document.notes[*] ? (@.category == \"summary\")") and severity_text = "high"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Parse(expr.Infix()); err != nil {
+		t.Errorf("round trip of %q: %v", expr.Infix(), err)
+	}
+	sb, err := NewAdvancedSQLBuilder(
+		AdvancedSQLBuilderExpr(expr),
+		AdvancedSQLBuilderParser(&p),
+		AdvancedSQLBuilderFields([]string{"id", "flags"}),
+		AdvancedSQLBuilderOrderFields([]string{"-flags"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql := sb.CreateQuery(-1, -1)
+	for _, part := range []string{
+		`jsonb_path_match(` + originalDocument + `, ($1)::text::jsonpath, '{}', true)`,
+		`(jsonb_path_query_first(` + originalDocument + `, '$.document.aggregate_severity.text') #>> '{}')`,
+		`(jsonb_path_query_first(` + originalDocument + `, '$.vulnerabilities.flags.size()') #>> '{}')` +
+			`::numeric::bigint AS flags`,
+	} {
+		if !strings.Contains(sql, part) {
+			t.Errorf("%q missing in %s", part, sql)
+		}
+	}
+}
+
+func TestPushFloat(t *testing.T) {
+	var st stack
+	st.push(&Expr{exprType: cnst, valueType: intType, intValue: 5})
+	(*Parser).pushFloat(nil, &st)
+	if e := st.top(); e.exprType != cnst || e.valueType != floatType || e.floatValue != 5 {
+		t.Errorf("expected float constant 5 got %+v", e)
+	}
+	for _, q := range []string{
+		`$cvss_v3_score 5 integer float >=`,
+		`$cvss_v3_score 5 integer float < $cvss_v3_score 9 integer float > or`,
+		`$cvss_v3_score 2 integer float float =`,
+	} {
+		p := Parser{Mode: AdvisoryMode}
+		if _, err := p.Parse(q); err != nil {
+			t.Errorf("%q: %v", q, err)
+		}
+	}
+}
@@ -233,6 +233,8 @@ const (
 		`comments.documents_id = docads.id)`
 	commentsCountEvents = `(SELECT count(*) FROM comments WHERE ` +
 		`comments.documents_id = documents_id)`
+	knownAffectedCountClassic = `COALESCE((SELECT products FROM documents_product_status WHERE ` +
+		`documents_product_status.documents_id = documents.id AND status = 'known_affected'), 0)`
 )
 
 func (sb *SQLBuilder) accessWhere(e *Expr, b *strings.Builder) {
@@ -245,6 +247,8 @@ func (sb *SQLBuilder) accessWhere(e *Expr, b *strings.Builder) {
 		b.WriteString(column)
 	case "versions":
 		b.WriteString(versionsCountClassic)
+	case "known_affected_count":
+		b.WriteString(knownAffectedCountClassic)
 	case "comments":
 		switch sb.Mode {
 		case AdvisoryMode:
@@ -259,7 +263,7 @@ func (sb *SQLBuilder) accessWhere(e *Expr, b *strings.Builder) {
 	case "ssvc":
 		b.WriteString("ssvc_current.ssvc")
 	default:
-		b.WriteString(column)
+		writeColumn(b, column)
 	}
 }
 
@@ -367,6 +371,12 @@ func (sb *SQLBuilder) whereRecurse(e *Expr, b *strings.Builder) {
 		sb.ilikePNameWhere(e, b)
 	case ilikePID:
 		sb.ilikePIDWhere(e, b)
+	case cve, ilikeCVE, cwe, hasRemediation, hasThreat, hasProductStatus:
+		factWhere(e, b, "documents.id", func(child *Expr) { sb.whereRecurse(child, b) })
+	case fullText:
+		fullTextWhere(b, "documents.id", sb.replacementIndex(e.stringValue)+1)
+	case jsonPath:
+		jsonPathWhere(e, b, originalDocument, func(child *Expr) { sb.whereRecurse(child, b) })
 	case now:
 		sb.nowWhere(e, b)
 	case add:
@@ -460,7 +470,7 @@ func (sb *SQLBuilder) CreateOrder(fields []string) (string, error) {
 			b.WriteString(
 				`CASE WHEN version ~ '^[[:digit:]]+$' THEN version::int END`)
 		default:
-			b.WriteString(field)
+			writeColumn(&b, field)
 		}
 
 		if desc {
@@ -544,6 +554,8 @@ func (sb *SQLBuilder) projectionsWithCasts(b *strings.Builder, proj []string) {
 			b.WriteString("events_log.state::text AS event_state")
 		case "versions":
 			b.WriteString(versionsCountClassic + `AS versions`)
+		case "known_affected_count":
+			b.WriteString(knownAffectedCountClassic + ` AS known_affected_count`)
 		case "ssvc":
 			b.WriteString("ssvc_current.ssvc AS ssvc")
 		case "comments":
@@ -556,7 +568,7 @@ func (sb *SQLBuilder) projectionsWithCasts(b *strings.Builder, proj []string) {
 				b.WriteString(commentsCountEvents + `AS comments`)
 			}
 		default:
-			b.WriteString(p)
+			writeProjection(b, p)
 		}
 	}
 }
@@ -0,0 +1,236 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package imports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ISDuBA/ISDuBA/pkg/archive"
+	"github.com/ISDuBA/ISDuBA/pkg/models"
+)
+
+// Status is the state of an import job.
+type Status string
+
+// The states of an import job.
+const (
+	Queued    Status = "queued"    // Queued jobs wait for a free slot.
+	Running   Status = "running"   // Running jobs import the advisories.
+	Finished  Status = "finished"  // Finished jobs have read the whole archive.
+	Aborted   Status = "aborted"   // Aborted jobs failed reading the archive.
+	Cancelled Status = "cancelled" // Cancelled jobs were stopped by a user.
+)
+
+// FileStatus is the outcome of the import of an advisory.
+type FileStatus string
+
+// The outcomes of the import of an advisory.
+const (
+	Imported    FileStatus = "imported"     // Imported advisories are stored.
+	Duplicate   FileStatus = "duplicate"    // Duplicate advisories are already stored.
+	SchemaError FileStatus = "schema_error" // SchemaError advisories are not valid.
+	TLPRejected FileStatus = "tlp_rejected" // TLPRejected advisories are not allowed for the user.
+	Failed      FileStatus = "failed"       // Failed advisories could not be stored.
+)
+
+// Result is the outcome of the import of an advisory of an archive.
+type Result struct {
+	File   string                   `json:"file"`
+	Status FileStatus               `json:"status"`
+	ID     *int64                   `json:"id,omitempty"`
+	Error  string                   `json:"error,omitempty"`
+	Report *models.ValidationReport `json:"report,omitempty"`
+}
+
+// Job is an import job of an uploaded archive.
+type Job struct {
+	ID       int64              `json:"id"`
+	User     string             `json:"user"`
+	Filename string             `json:"filename"`
+	Status   Status             `json:"status"`
+	Error    string             `json:"error,omitempty"`
+	Created  time.Time          `json:"created"`
+	Started  *time.Time         `json:"started,omitempty"`
+	Finished *time.Time         `json:"finished,omitempty"`
+	Counts   map[FileStatus]int `json:"counts"`
+	Results  []Result           `json:"results,omitempty"`
+}
+
+// job is the internal state of an import job.
+type job struct {
+	Job
+	file   string
+	actor  *string
+	pstlps models.PublishersTLPs
+	cancel context.CancelFunc
+}
+
+// active checks if the job is queued or running.
+func (j *job) active() bool {
+	return j.Status == Queued || j.Status == Running
+}
+
+// snapshot returns a copy of the job. The results are
+// only included if requested.
+func (j *job) snapshot(results bool) Job {
+	cp := j.Job
+	cp.Counts = maps.Clone(j.Counts)
+	if results {
+		cp.Results = slices.Clone(j.Results)
+	} else {
+		cp.Results = nil
+	}
+	return cp
+}
+
+// add adds the result of an advisory.
+func (j *job) add(r Result) {
+	j.Results = append(j.Results, r)
+	j.Counts[r.Status]++
+}
+
+// finish sets the final state of the job.
+func (j *job) finish(err error) {
+	now := time.Now().UTC()
+	j.Finished = &now
+	switch {
+	case j.Status == Cancelled:
+	case err != nil:
+		j.Status = Aborted
+		j.Error = err.Error()
+	default:
+		j.Status = Finished
+	}
+}
+
+// run imports the advisories of the archive.
+func (m *Manager) run(ctx context.Context, j *job) {
+	defer os.Remove(j.file)
+	err := func() error {
+		f, err := os.Open(j.file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		return archive.Read(j.Filename, f, fi.Size(), m.limit, func(member *archive.Member) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			// The current advisory is stored even if cancelled.
+			if r, ok := m.importMember(context.WithoutCancel(ctx), j, member); ok {
+				m.inManager(func(*Manager) { j.add(r) })
+			}
+			return nil
+		})
+	}()
+	if errors.Is(err, context.Canceled) {
+		err = nil
+	}
+	if err != nil {
+		slog.Warn("import job aborted", "job", j.ID, "err", err)
+	}
+	m.inManager(func(m *Manager) {
+		j.finish(err)
+		j.cancel()
+		m.running--
+	})
+}
+
+// importMember imports an advisory of the archive.
+// Other JSON files found in the archive are ignored.
+func (m *Manager) importMember(ctx context.Context, j *job, member *archive.Member) (Result, bool) {
+	result := Result{File: member.Name}
+	fail := func(status FileStatus, err error) (Result, bool) {
+		result.Status, result.Error = status, err.Error()
+		return result, true
+	}
+
+	if member.Err != nil {
+		return fail(Failed, member.Err)
+	}
+	data, err := archive.Uncompress(member.Name, member.Data, m.limit)
+	if err != nil {
+		return fail(Failed, err)
+	}
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return fail(SchemaError, err)
+	}
+	// Archives of provider directories contain other JSON files, too.
+	if obj, ok := document.(map[string]any); !ok || obj["document"] == nil {
+		return result, false
+	}
+	report := models.NewValidationReport()
+	if err := report.CheckSchema(document); err != nil {
+		return fail(Failed, err)
+	}
+	if report.SchemaFailed() {
+		result.Report = report
+		return fail(SchemaError, report.SchemaErr())
+	}
+	if m.val != nil {
+		rvr, err := m.val.Validate(document)
+		if err != nil {
+			return fail(Failed, err)
+		}
+		if report.AddRemote(rvr, nil); report.RemoteFailed() {
+			result.Report = report
+			return fail(SchemaError, report.RemoteErr())
+		}
+	}
+	filename := path.Base(archive.AdvisoryName(member.Name))
+	report.CheckFilename(document, filename)
+
+	// Store stats in database.
+	storeStats := func(ctx context.Context, tx pgx.Tx, docID int64, duplicate bool) error {
+		if duplicate {
+			return nil
+		}
+		const insertSQL = `INSERT INTO downloads ` +
+			`(documents_id, feeds_id, validation_report) VALUES ($1, ` +
+			`(SELECT id FROM feeds WHERE sources_id = 0 AND label = 'bulk'), $2)`
+		_, err := tx.Exec(ctx, insertSQL, docID, report)
+		return err
+	}
+
+	var id int64
+	switch err := m.db.Run(ctx, func(rctx context.Context, conn *pgxpool.Conn) error {
+		var err error
+		id, err = models.ImportDocumentData(
+			rctx, conn, document, data,
+			j.actor, j.pstlps,
+			models.ChainInTx(storeStats, models.StoreFilename(filename)),
+			false)
+		return err
+	}, 0); {
+	case errors.Is(err, models.ErrAlreadyInDatabase):
+		return fail(Duplicate, err)
+	case errors.Is(err, models.ErrNotAllowed):
+		return fail(TLPRejected, errors.New("wrong publisher/tlp"))
+	case err != nil:
+		return fail(Failed, err)
+	}
+	result.Status, result.ID = Imported, &id
+	return result, true
+}
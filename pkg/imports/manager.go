@@ -0,0 +1,256 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+// Package imports implements background jobs importing
+// the advisories of uploaded archives.
+package imports
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/gocsaf/csaf/v3/csaf"
+
+	"github.com/ISDuBA/ISDuBA/pkg/config"
+	"github.com/ISDuBA/ISDuBA/pkg/database"
+	"github.com/ISDuBA/ISDuBA/pkg/models"
+)
+
+const cleanupDuration = 5 * time.Minute
+
+var (
+	// ErrTooManyJobs is returned by Submit if the user
+	// has too many queued or running jobs.
+	ErrTooManyJobs = errors.New("too many import jobs")
+	// ErrJobNotFound is returned if the job does not exist
+	// or belongs to another user.
+	ErrJobNotFound = errors.New("import job not found")
+	// ErrJobDone is returned by Cancel if the job is already done.
+	ErrJobDone = errors.New("import job already done")
+	// ErrStopped is returned if the manager is not running any more.
+	ErrStopped = errors.New("import manager stopped")
+)
+
+// Manager queues and runs the import jobs.
+type Manager struct {
+	cfg     *config.Imports
+	limit   int64
+	db      *database.DB
+	val     csaf.RemoteValidator
+	fns     chan func(*Manager)
+	stopped chan struct{}
+	done    bool
+	nextID  int64
+	running int
+	jobs    map[int64]*job
+	queue   []*job
+}
+
+// NewManager returns a new import job manager.
+// limit is the maximal size of a decompressed advisory.
+func NewManager(
+	cfg *config.Imports,
+	limit int64,
+	db *database.DB,
+	val csaf.RemoteValidator,
+) *Manager {
+	return &Manager{
+		cfg:     cfg,
+		limit:   limit,
+		db:      db,
+		val:     val,
+		fns:     make(chan func(*Manager)),
+		stopped: make(chan struct{}),
+		jobs:    map[int64]*job{},
+	}
+}
+
+// Run runs the manager. To be used in a Go routine.
+func (m *Manager) Run(ctx context.Context) {
+	defer close(m.stopped)
+	ticker := time.NewTicker(cleanupDuration)
+	defer ticker.Stop()
+out:
+	for !m.done {
+		m.startJobs(ctx)
+		select {
+		case fn := <-m.fns:
+			fn(m)
+		case <-ctx.Done():
+			break out
+		case t := <-ticker.C:
+			m.cleanup(t)
+		}
+	}
+	// Remove the archives of the jobs which were not started.
+	for _, j := range m.queue {
+		os.Remove(j.file)
+	}
+}
+
+func (m *Manager) kill() { m.done = true }
+
+// Kill shuts down the manager.
+func (m *Manager) Kill() { m.inManager((*Manager).kill) }
+
+// inManager runs fn in the manager and waits for it.
+// It returns false if the manager is not running any more.
+func (m *Manager) inManager(fn func(*Manager)) bool {
+	done := make(chan struct{})
+	select {
+	case m.fns <- func(m *Manager) { defer close(done); fn(m) }:
+		<-done
+		return true
+	case <-m.stopped:
+		return false
+	}
+}
+
+// startJobs starts queued jobs if there are free slots.
+func (m *Manager) startJobs(ctx context.Context) {
+	for m.running < max(m.cfg.ParallelJobs, 1) && len(m.queue) > 0 {
+		j := m.queue[0]
+		m.queue = m.queue[1:]
+		now := time.Now().UTC()
+		j.Status, j.Started = Running, &now
+		var jctx context.Context
+		jctx, j.cancel = context.WithCancel(ctx)
+		m.running++
+		go m.run(jctx, j)
+	}
+}
+
+// cleanup removes the jobs done before the keep duration.
+func (m *Manager) cleanup(now time.Time) {
+	limit := now.Add(-m.cfg.KeepJobs)
+	for id, j := range m.jobs {
+		if !j.active() && j.Finished != nil && j.Finished.Before(limit) {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// Submit queues a new job importing the advisories of an archive
+// on behalf of a user. The archive is read from file which is
+// removed when the job is done. The name of the archive
+// tells which kind of archive it is.
+func (m *Manager) Submit(
+	user string,
+	actor *string,
+	pstlps models.PublishersTLPs,
+	filename string,
+	file string,
+) (int64, error) {
+	var (
+		id  int64
+		err error
+	)
+	if !m.inManager(func(m *Manager) {
+		active := 0
+		for _, j := range m.jobs {
+			if j.User == user && j.active() {
+				active++
+			}
+		}
+		if active >= m.cfg.JobsUser {
+			err = ErrTooManyJobs
+			return
+		}
+		m.nextID++
+		id = m.nextID
+		j := &job{
+			Job: Job{
+				ID:       id,
+				User:     user,
+				Filename: filename,
+				Status:   Queued,
+				Created:  time.Now().UTC(),
+				Counts:   map[FileStatus]int{},
+			},
+			file:   file,
+			actor:  actor,
+			pstlps: pstlps,
+		}
+		m.jobs[id] = j
+		m.queue = append(m.queue, j)
+	}) {
+		return 0, ErrStopped
+	}
+	return id, err
+}
+
+// visible checks if the job is visible to the user.
+// An empty user sees all jobs.
+func (j *job) visible(user string) bool {
+	return user == "" || j.User == user
+}
+
+// Jobs returns the jobs of a user without their results,
+// the newest first. An empty user returns the jobs of all users.
+func (m *Manager) Jobs(user string) []Job {
+	var jobs []Job
+	m.inManager(func(m *Manager) {
+		jobs = make([]Job, 0, len(m.jobs))
+		for _, j := range m.jobs {
+			if j.visible(user) {
+				jobs = append(jobs, j.snapshot(false))
+			}
+		}
+	})
+	slices.SortFunc(jobs, func(a, b Job) int { return cmp.Compare(b.ID, a.ID) })
+	return jobs
+}
+
+// Job returns a job of a user with its results.
+// An empty user may see the jobs of all users.
+func (m *Manager) Job(id int64, user string) (Job, error) {
+	var (
+		result Job
+		err    = ErrJobNotFound
+	)
+	if !m.inManager(func(m *Manager) {
+		if j := m.jobs[id]; j != nil && j.visible(user) {
+			result, err = j.snapshot(true), nil
+		}
+	}) {
+		return Job{}, ErrStopped
+	}
+	return result, err
+}
+
+// Cancel cancels a queued or running job of a user.
+// An empty user may cancel the jobs of all users.
+// The advisories imported so far are kept.
+func (m *Manager) Cancel(id int64, user string) error {
+	var err error
+	if !m.inManager(func(m *Manager) {
+		j := m.jobs[id]
+		switch {
+		case j == nil || !j.visible(user):
+			err = ErrJobNotFound
+		case !j.active():
+			err = ErrJobDone
+		case j.Status == Queued:
+			m.queue = slices.DeleteFunc(m.queue, func(q *job) bool { return q == j })
+			os.Remove(j.file)
+			j.Status = Cancelled
+			j.finish(nil)
+		default:
+			// The running job finishes itself.
+			j.Status = Cancelled
+			j.cancel()
+		}
+	}) {
+		return ErrStopped
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ISDuBA/ISDuBA/pkg/database/query"
+)
+
+// ErrAlertKind is returned if a stored query of the events kind
+// should be used as an alert.
+var ErrAlertKind = errors.New("only queries of kind documents or advisories can be alerts")
+
+// ErrAlertParameters is returned if a stored query with parameters
+// without default values should be used as an alert.
+var ErrAlertParameters = errors.New("parameters of alerts need default values")
+
+// CheckAlert checks if a stored query of the given kind
+// with the given parameters can be used as an alert.
+// Alerts are evaluated without arguments.
+func CheckAlert(kind query.ParserMode, params []query.Parameter) error {
+	if kind == query.EventMode {
+		return ErrAlertKind
+	}
+	for i := range params {
+		if params[i].Default == nil {
+			return ErrAlertParameters
+		}
+	}
+	return nil
+}
+
+// alertQuery is a stored query marked as an alert.
+type alertQuery struct {
+	id         int64
+	kind       query.ParserMode
+	definer    string
+	query      string
+	parameters []query.Parameter
+	syntax     query.Syntax
+}
+
+// matches checks if the document matches the alert query.
+func (aq *alertQuery) matches(ctx context.Context, conn *pgxpool.Conn, documentID int64) (bool, error) {
+	// The parameters take their defaults.
+	parser := query.Parser{
+		Mode:       aq.kind,
+		Me:         aq.definer,
+		Syntax:     aq.syntax,
+		Parameters: aq.parameters,
+		Arguments:  map[string]string{},
+	}
+	expr, err := parser.Parse(aq.query)
+	if err != nil {
+		return false, err
+	}
+	// In advisory mode only the latest document is considered.
+	if aq.kind == query.AdvisoryMode {
+		expr = expr.And(query.BoolField("latest"))
+	}
+	expr = expr.And(query.FieldEqInt("id", documentID))
+	builder, err := query.NewAdvancedSQLBuilder(
+		query.AdvancedSQLBuilderExpr(expr),
+		query.AdvancedSQLBuilderParser(&parser))
+	if err != nil {
+		return false, err
+	}
+	var count int64
+	if err := conn.QueryRow(
+		ctx, builder.CreateCountSQL(), builder.Replacements...,
+	).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// EvaluateAlerts checks if the given document matches stored queries
+// marked as alerts and records the hits together with the triggering event.
+// A document is only recorded once per alert query.
+// Broken alert queries do not stop the evaluation of the others.
+func EvaluateAlerts(
+	ctx context.Context,
+	conn *pgxpool.Conn,
+	documentID int64,
+	event Event,
+	actor *string,
+) error {
+	const (
+		selectSQL = `SELECT id, kind::text, definer, query, parameters, syntax::text ` +
+			`FROM stored_queries ` +
+			`WHERE alert AND kind <> 'events' AND NOT EXISTS (` +
+			`SELECT 1 FROM alert_hits ` +
+			`WHERE stored_queries_id = stored_queries.id AND documents_id = $1)`
+		insertSQL = `INSERT INTO alert_hits ` +
+			`(stored_queries_id, documents_id, event, actor) ` +
+			`VALUES ($1, $2, $3::events, $4) ` +
+			`ON CONFLICT DO NOTHING`
+	)
+	rows, _ := conn.Query(ctx, selectSQL, documentID)
+	alerts, err := pgx.CollectRows(rows,
+		func(row pgx.CollectableRow) (*alertQuery, error) {
+			var aq alertQuery
+			err := row.Scan(
+				&aq.id,
+				&aq.kind,
+				&aq.definer,
+				&aq.query,
+				&aq.parameters,
+				&aq.syntax,
+			)
+			return &aq, err
+		})
+	if err != nil {
+		return fmt.Errorf("loading alert queries failed: %w", err)
+	}
+	var errs []error
+	for _, aq := range alerts {
+		match, err := aq.matches(ctx, conn, documentID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("evaluating alert query %d failed: %w", aq.id, err))
+			continue
+		}
+		if !match {
+			continue
+		}
+		if _, err := conn.Exec(
+			ctx, insertSQL, aq.id, documentID, string(event), actor,
+		); err != nil {
+			errs = append(errs, fmt.Errorf("storing hit of alert query %d failed: %w", aq.id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
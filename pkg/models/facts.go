@@ -0,0 +1,123 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// documentFacts are facts about the vulnerabilities of a document.
+// They are stored in extraction tables to be used in queries.
+type documentFacts struct {
+	cwes          map[string]struct{}
+	remediations  map[string]struct{}
+	threats       map[string]struct{}
+	productStatus map[string]map[string]struct{}
+}
+
+// extractFacts extracts the facts from the vulnerabilities of a document.
+func extractFacts(document any) *documentFacts {
+	df := &documentFacts{
+		cwes:          map[string]struct{}{},
+		remediations:  map[string]struct{}{},
+		threats:       map[string]struct{}{},
+		productStatus: map[string]map[string]struct{}{},
+	}
+	doc, _ := document.(map[string]any)
+	vulns, _ := doc["vulnerabilities"].([]any)
+	for _, v := range vulns {
+		vuln, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		// CSAF 2.0 has a single CWE, CSAF 2.1 a list of them.
+		if cwe, ok := vuln["cwe"].(map[string]any); ok {
+			addString(df.cwes, cwe["id"])
+		}
+		for _, cwe := range objects(vuln["cwes"]) {
+			addString(df.cwes, cwe["id"])
+		}
+		for _, rem := range objects(vuln["remediations"]) {
+			addString(df.remediations, rem["category"])
+		}
+		for _, threat := range objects(vuln["threats"]) {
+			addString(df.threats, threat["category"])
+		}
+		status, _ := vuln["product_status"].(map[string]any)
+		for category, products := range status {
+			list, ok := products.([]any)
+			if !ok {
+				continue
+			}
+			set := df.productStatus[category]
+			if set == nil {
+				set = map[string]struct{}{}
+				df.productStatus[category] = set
+			}
+			for _, product := range list {
+				addString(set, product)
+			}
+		}
+	}
+	return df
+}
+
+// objects returns the objects of a JSON array.
+func objects(x any) []map[string]any {
+	list, _ := x.([]any)
+	objs := make([]map[string]any, 0, len(list))
+	for _, e := range list {
+		if obj, ok := e.(map[string]any); ok {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
+
+// addString adds a value to a set if it is a non-empty string.
+func addString(set map[string]struct{}, x any) {
+	if s, ok := x.(string); ok && s != "" {
+		set[s] = struct{}{}
+	}
+}
+
+// store stores the facts of the document with the given id.
+func (df *documentFacts) store(ctx context.Context, tx pgx.Tx, id int64) error {
+	const (
+		insertCWE           = `INSERT INTO documents_cwes (documents_id, cwe) VALUES ($1, $2)`
+		insertRemediation   = `INSERT INTO documents_remediations (documents_id, category) VALUES ($1, $2)`
+		insertThreat        = `INSERT INTO documents_threats (documents_id, category) VALUES ($1, $2)`
+		insertProductStatus = `INSERT INTO documents_product_status (documents_id, status, products) ` +
+			`VALUES ($1, $2, $3)`
+	)
+	batch := &pgx.Batch{}
+	queue := func(sql string, set map[string]struct{}) {
+		for _, s := range slices.Sorted(maps.Keys(set)) {
+			batch.Queue(sql, id, s)
+		}
+	}
+	queue(insertCWE, df.cwes)
+	queue(insertRemediation, df.remediations)
+	queue(insertThreat, df.threats)
+	for _, status := range slices.Sorted(maps.Keys(df.productStatus)) {
+		batch.Queue(insertProductStatus, id, status, len(df.productStatus[status]))
+	}
+	if batch.Len() == 0 {
+		return nil
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("inserting document facts failed: %w", err)
+	}
+	return nil
+}
@@ -15,11 +15,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"slices"
-	"strings"
 	"sync"
 
-	"github.com/gocsaf/csaf/v3/csaf"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -275,22 +274,36 @@ func ImportDocument(
 	inTx DocumentStoreChainFunc,
 	dry bool,
 ) (int64, error) {
+	document, raw, err := ReadDocument(r)
+	if err != nil {
+		return 0, err
+	}
+	return ImportDocumentData(ctx, conn, document, raw, actor, pstlps, inTx, dry)
+}
+
+// ReadDocument decodes an advisory and validates it against the schema.
+// It returns the decoded document and its raw bytes.
+func ReadDocument(r io.Reader) (any, []byte, error) {
 	var buf bytes.Buffer
 	tee := io.TeeReader(r, &buf)
 
 	var document any
 	if err := json.NewDecoder(tee).Decode(&document); err != nil {
-		return 0, err
+		return nil, nil, err
 	}
-
-	msgs, err := csaf.ValidateCSAF(document)
-	if err != nil {
-		return 0, fmt.Errorf("schema validation failed: %w", err)
+	if err := ValidateDocument(document); err != nil {
+		return nil, nil, err
 	}
-	if len(msgs) > 0 {
-		return 0, errors.New("schema validation failed: " + strings.Join(msgs, ", "))
+	return document, buf.Bytes(), nil
+}
+
+// ValidateDocument validates a decoded advisory against the schema.
+func ValidateDocument(document any) error {
+	vr := NewValidationReport()
+	if err := vr.CheckSchema(document); err != nil {
+		return fmt.Errorf("schema validation failed: %w", err)
 	}
-	return ImportDocumentData(ctx, conn, document, buf.Bytes(), actor, pstlps, inTx, dry)
+	return vr.SchemaErr()
 }
 
 // ImportDocumentData imports a given advisory into the database.
@@ -313,6 +326,9 @@ func ImportDocumentData(
 
 	idxer := newIndexer[string]()
 
+	// Extract the facts before the texts are replaced by indices.
+	facts := extractFacts(document)
+
 	var reps []replacer
 
 	transformJSON(document, chainReplacers(
@@ -352,7 +368,8 @@ func ImportDocumentData(
 	// There are transaction serialization issues with the unique texts.
 	// TODO: This has to be investigated!
 	globalInsertLock.Lock()
-	defer globalInsertLock.Unlock()
+	unlock := sync.OnceFunc(globalInsertLock.Unlock)
+	defer unlock()
 
 	tx, err := conn.Begin(ctx)
 	if err != nil {
@@ -371,6 +388,7 @@ func ImportDocumentData(
 		queryText            = `SELECT id FROM unique_texts WHERE txt = $1`
 		insertText           = `INSERT INTO unique_texts (txt) VALUES ($1) RETURNING id`
 		insertDocText        = `INSERT INTO documents_texts (documents_id, num, txt_id) VALUES ($1, $2, $3)`
+		updateSearch         = `SELECT update_documents_search($1)`
 		loadTexts            = `SELECT u.id, txt FROM documents d JOIN documents_texts t ` +
 			`ON d.id = t.documents_id JOIN unique_texts u ` +
 			`ON t.txt_id = u.id ` +
@@ -438,6 +456,10 @@ func ImportDocumentData(
 		return 0, fmt.Errorf("inserting log failed: %w", err)
 	}
 
+	if err := facts.store(ctx, tx, id); err != nil {
+		return 0, err
+	}
+
 	txtIDs := make([]int64, len(idxer.elements))
 	for i := range txtIDs {
 		txtIDs[i] = -1
@@ -507,6 +529,11 @@ func ImportDocumentData(
 		return 0, fmt.Errorf("inserting txt failed: %w", err)
 	}
 
+	// Make the texts available to ranked searches.
+	if _, err := tx.Exec(ctx, updateSearch, id); err != nil {
+		return 0, fmt.Errorf("updating search vector failed: %w", err)
+	}
+
 	if inTx != nil {
 		if err := inTx(ctx, tx, id, false); err != nil {
 			return 0, fmt.Errorf("in transaction failed: %w", err)
@@ -516,5 +543,12 @@ func ImportDocumentData(
 	if err := tx.Commit(ctx); err != nil {
 		return 0, fmt.Errorf("commiting transaction failed: %w", err)
 	}
+	// The alerts run queries of their own which must not block other inserts.
+	unlock()
+
+	// The import was successful even if the alerts fail.
+	if err := EvaluateAlerts(ctx, conn, id, ImportDocumentEvent, actor); err != nil {
+		slog.Error("evaluating alerts failed", "document", id, "err", err)
+	}
 	return id, nil
 }
@@ -9,22 +9,130 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/ISDuBA/ISDuBA/pkg/database/query"
 )
 
 // StoredQuery represents a stored query.
 type StoredQuery struct {
-	ID           int64            `json:"id"`
-	Kind         query.ParserMode `json:"kind"`
-	Definer      string           `json:"definer"`
-	Global       bool             `json:"global"`
-	Name         string           `json:"name"`
-	Description  *string          `json:"description,omitempty"`
-	Query        string           `json:"query"`
-	Num          int64            `json:"num"`
-	Columns      []string         `json:"columns"`
-	Orders       *[]string        `json:"orders,omitempty"`
-	Dashboard    bool             `json:"dashboard"`
-	Role         *WorkflowRole    `json:"role,omitempty"`
-	DefaultQuery bool             `json:"default_query"`
+	ID           int64             `json:"id"`
+	Kind         query.ParserMode  `json:"kind"`
+	Definer      string            `json:"definer"`
+	Global       bool              `json:"global"`
+	Name         string            `json:"name"`
+	Description  *string           `json:"description,omitempty"`
+	Query        string            `json:"query"`
+	Num          int64             `json:"num"`
+	Columns      []string          `json:"columns"`
+	Orders       *[]string         `json:"orders,omitempty"`
+	Dashboard    bool              `json:"dashboard"`
+	Role         *WorkflowRole     `json:"role,omitempty"`
+	DefaultQuery bool              `json:"default_query"`
+	Parameters   []query.Parameter `json:"parameters,omitempty"`
+	Syntax       query.Syntax      `json:"syntax"`
+	Alert        bool              `json:"alert"`
+	SharedBy     *string           `json:"shared_by,omitempty"`
+	Editable     bool              `json:"editable"`
+}
+
+// Validate checks if the stored query is usable. The parameters
+// are only type checked as there are no arguments at this point.
+func (sq *StoredQuery) Validate() error {
+	if sq.Name == "" {
+		return errors.New("missing 'name'")
+	}
+	if err := query.ValidateParameters(sq.Parameters); err != nil {
+		return fmt.Errorf("bad 'parameters' value: %w", err)
+	}
+	if sq.Alert {
+		if err := CheckAlert(sq.Kind, sq.Parameters); err != nil {
+			return err
+		}
+	}
+	parser := query.Parser{
+		Mode:       sq.Kind,
+		Syntax:     sq.Syntax,
+		Parameters: sq.Parameters,
+	}
+	expr, err := parser.Parse(sq.Query)
+	if err != nil {
+		return fmt.Errorf("bad 'query' value: %w", err)
+	}
+	// In advisory mode we only show the latest.
+	if sq.Kind == query.AdvisoryMode {
+		expr = expr.And(query.BoolField("latest"))
+	}
+	if len(sq.Columns) == 0 {
+		return errors.New("missing 'columns' value")
+	}
+	builder := query.SQLBuilder{Mode: sq.Kind}
+	builder.CreateWhere(expr)
+	if err := builder.CheckProjections(sq.Columns); err != nil {
+		return fmt.Errorf("bad 'columns' value: %w", err)
+	}
+	if sq.Orders != nil {
+		if _, err := builder.CreateOrder(*sq.Orders); err != nil {
+			return fmt.Errorf("bad 'orders' value: %w", err)
+		}
+	}
+	return nil
+}
+
+// ShareKind is the kind of receivers a stored query is shared with.
+type ShareKind string
+
+// The different kinds of receivers
+const (
+	UserShare  ShareKind = "user"  // UserShare shares with a single user.
+	GroupShare ShareKind = "group" // GroupShare shares with the members of a Keycloak group.
+	RoleShare  ShareKind = "role"  // RoleShare shares with the users having a role.
+)
+
+// ParseShareKind parses a share kind from a string.
+func ParseShareKind(s string) (ShareKind, error) {
+	switch k := ShareKind(strings.ToLower(s)); k {
+	case UserShare, GroupShare, RoleShare:
+		return k, nil
+	default:
+		return "", fmt.Errorf("unknown share kind %q", s)
+	}
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+func (sk *ShareKind) UnmarshalText(text []byte) error {
+	x, err := ParseShareKind(string(text))
+	if err != nil {
+		return err
+	}
+	*sk = x
+	return nil
+}
+
+// QueryShare shares a stored query with a user, a group or a role.
+type QueryShare struct {
+	Kind     ShareKind `json:"kind"`
+	Name     string    `json:"name"`
+	Editable bool      `json:"editable"`
+}
+
+// Validate checks if the share is well formed.
+// The names of roles are normalized.
+func (qs *QueryShare) Validate() error {
+	if _, err := ParseShareKind(string(qs.Kind)); err != nil {
+		return err
+	}
+	if qs.Name == "" {
+		return fmt.Errorf("missing name of %s", qs.Kind)
+	}
+	if qs.Kind == RoleShare {
+		role, err := ParseWorkflowRole(qs.Name)
+		if err != nil {
+			return err
+		}
+		qs.Name = string(role)
+	}
+	return nil
 }
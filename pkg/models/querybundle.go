@@ -0,0 +1,115 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ISDuBA/ISDuBA/pkg/database/query"
+)
+
+// QueryBundleVersion is the version of the format of query bundles.
+const QueryBundleVersion = 1
+
+// QueryBundle is a portable collection of stored queries.
+type QueryBundle struct {
+	Version  int             `json:"version"`
+	Exported time.Time       `json:"exported"`
+	Queries  []*BundledQuery `json:"queries"`
+}
+
+// BundledQuery is a stored query without the parts specific
+// to an installation like the ID and the definer.
+type BundledQuery struct {
+	Name         string            `json:"name"`
+	Description  *string           `json:"description,omitempty"`
+	Kind         query.ParserMode  `json:"kind"`
+	Query        string            `json:"query"`
+	Syntax       query.Syntax      `json:"syntax"`
+	Parameters   []query.Parameter `json:"parameters,omitempty"`
+	Columns      []string          `json:"columns"`
+	Orders       *[]string         `json:"orders,omitempty"`
+	Num          int64             `json:"num"`
+	Dashboard    bool              `json:"dashboard"`
+	Role         *WorkflowRole     `json:"role,omitempty"`
+	Global       bool              `json:"global"`
+	DefaultQuery bool              `json:"default_query"`
+	Alert        bool              `json:"alert"`
+}
+
+// Bundle returns the portable part of the stored query.
+func (sq *StoredQuery) Bundle() *BundledQuery {
+	return &BundledQuery{
+		Name:         sq.Name,
+		Description:  sq.Description,
+		Kind:         sq.Kind,
+		Query:        sq.Query,
+		Syntax:       sq.Syntax,
+		Parameters:   sq.Parameters,
+		Columns:      sq.Columns,
+		Orders:       sq.Orders,
+		Num:          sq.Num,
+		Dashboard:    sq.Dashboard,
+		Role:         sq.Role,
+		Global:       sq.Global,
+		DefaultQuery: sq.DefaultQuery,
+		Alert:        sq.Alert,
+	}
+}
+
+// CheckVersion checks if the bundle can be read by this version.
+func (qb *QueryBundle) CheckVersion() error {
+	if qb.Version < 1 || qb.Version > QueryBundleVersion {
+		return fmt.Errorf("unsupported bundle version %d", qb.Version)
+	}
+	return nil
+}
+
+// StoredQuery returns the bundled query as a stored query of the definer.
+func (bq *BundledQuery) StoredQuery(definer string) *StoredQuery {
+	return &StoredQuery{
+		Kind:         bq.Kind,
+		Definer:      definer,
+		Global:       bq.Global,
+		Name:         bq.Name,
+		Description:  bq.Description,
+		Query:        bq.Query,
+		Num:          bq.Num,
+		Columns:      bq.Columns,
+		Orders:       bq.Orders,
+		Dashboard:    bq.Dashboard,
+		Role:         bq.Role,
+		DefaultQuery: bq.DefaultQuery,
+		Parameters:   bq.Parameters,
+		Syntax:       bq.Syntax,
+		Alert:        bq.Alert,
+	}
+}
+
+// Validate checks if the query is usable in this installation
+// the same way a newly created stored query is checked.
+func (bq *BundledQuery) Validate() error {
+	return bq.StoredQuery("").Validate()
+}
+
+// RenameFree returns the name with the smallest counter
+// appended which is not taken.
+func RenameFree(name string, taken func(string) (bool, error)) (string, error) {
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)", strings.TrimSpace(name), i)
+		switch used, err := taken(candidate); {
+		case err != nil:
+			return "", err
+		case !used:
+			return candidate, nil
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestQueryBundle(t *testing.T) {
+	const input = `{
+  "version": 1,
+  "queries": [
+    {"name": "Critical", "kind": "advisories", "query": "$critical 7 float >",
+     "columns": ["id", "title"], "orders": ["-critical"]},
+    {"name": "Broken", "kind": "documents", "query": "$unknown 7 float >", "columns": ["id"]},
+    {"name": "Infix", "kind": "documents", "syntax": "infix",
+     "query": "critical > ?min", "columns": ["id"],
+     "parameters": [{"name": "min", "type": "float", "default": "7"}]},
+    {"name": "Events", "kind": "events", "query": "true", "columns": ["id"], "alert": true}
+  ]
+}`
+	var bundle QueryBundle
+	if err := json.Unmarshal([]byte(input), &bundle); err != nil {
+		t.Fatal(err)
+	}
+	if err := bundle.CheckVersion(); err != nil {
+		t.Fatal(err)
+	}
+	for i, valid := range []bool{true, false, true, false} {
+		if err := bundle.Queries[i].Validate(); (err == nil) != valid {
+			t.Errorf("validation of %q: expected valid %t got %v",
+				bundle.Queries[i].Name, valid, err)
+		}
+	}
+
+	bundle.Version = QueryBundleVersion + 1
+	if bundle.CheckVersion() == nil {
+		t.Error("future bundle version accepted")
+	}
+
+	taken := map[string]bool{"Critical (2)": true}
+	name, err := RenameFree("Critical", func(s string) (bool, error) { return taken[s], nil })
+	if err != nil || name != "Critical (3)" {
+		t.Errorf("expected %q got %q (%v)", "Critical (3)", name, err)
+	}
+}
@@ -0,0 +1,241 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/gocsaf/csaf/v3/csaf"
+	"github.com/gocsaf/csaf/v3/util"
+)
+
+// The kinds of the tests of the remote validator.
+const (
+	MandatoryTest   = "mandatory"
+	OptionalTest    = "optional"
+	InformativeTest = "informative"
+	OtherTest       = "other"
+)
+
+// ValidationMessage is a message of a validation
+// with the JSON pointer into the document it refers to.
+type ValidationMessage struct {
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+// RemoteTestReport is the result of a test of the remote validator.
+type RemoteTestReport struct {
+	Name     string              `json:"name"`
+	Kind     string              `json:"kind"`
+	Valid    bool                `json:"valid"`
+	Errors   []ValidationMessage `json:"errors,omitempty"`
+	Warnings []ValidationMessage `json:"warnings,omitempty"`
+	Infos    []ValidationMessage `json:"infos,omitempty"`
+}
+
+// RemoteReport is the result of the remote validator.
+type RemoteReport struct {
+	Valid bool               `json:"valid"`
+	Error string             `json:"error,omitempty"`
+	Tests []RemoteTestReport `json:"tests,omitempty"`
+}
+
+// FilenameReport is the result of the checks of the file name.
+type FilenameReport struct {
+	Filename   string `json:"filename"`
+	TrackingID string `json:"tracking_id,omitempty"`
+	Conforming bool   `json:"conforming"`
+	Matches    bool   `json:"matches"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ValidationReport is the detailed result of the validation of
+// an advisory. Parts which were not checked are missing.
+type ValidationReport struct {
+	Valid       bool                `json:"valid"`
+	Schema      []ValidationMessage `json:"schema,omitempty"`
+	SchemaError string              `json:"schema_error,omitempty"`
+	Remote      *RemoteReport       `json:"remote,omitempty"`
+	Filename    *FilenameReport     `json:"filename,omitempty"`
+}
+
+// ValidationError is the error sent if an advisory is not valid.
+type ValidationError struct {
+	Error  string            `json:"error"`
+	Code   int               `json:"code"`
+	Report *ValidationReport `json:"report"`
+}
+
+// NewValidationReport returns a report without any checks done.
+func NewValidationReport() *ValidationReport {
+	return &ValidationReport{Valid: true}
+}
+
+// messages converts the results of a remote test.
+func messages(results []csaf.RemoteTestResult) []ValidationMessage {
+	if len(results) == 0 {
+		return nil
+	}
+	msgs := make([]ValidationMessage, len(results))
+	for i, r := range results {
+		msgs[i] = ValidationMessage{Pointer: r.InstancePath, Message: r.Message}
+	}
+	return msgs
+}
+
+// testSection matches the section of the CSAF standard
+// defining a test, e.g. "6_1_27" in "mandatoryTest_6_1_27".
+var testSection = regexp.MustCompile(`(?:^|[^0-9])6[._]([123])[._]\d+`)
+
+// testKind derives the kind of a remote test. The remote validator
+// reports no kind, so it is taken from the section of the standard
+// defining the test: 6.1 are the mandatory, 6.2 the optional and
+// 6.3 the informative tests. Names without a section are matched
+// by their prefix, e.g. the presets "mandatory" and "optional".
+func testKind(name string) string {
+	kinds := []string{MandatoryTest, OptionalTest, InformativeTest}
+	if m := testSection.FindStringSubmatch(name); m != nil {
+		return kinds[m[1][0]-'1']
+	}
+	lower := strings.ToLower(name)
+	for _, kind := range kinds {
+		if strings.HasPrefix(lower, kind) {
+			return kind
+		}
+	}
+	return OtherTest
+}
+
+// CheckSchema validates the document against the schema.
+// It returns an error if the validation could not be done.
+func (vr *ValidationReport) CheckSchema(document any) error {
+	msgs, err := csaf.ValidateCSAF(document)
+	if err != nil {
+		vr.Valid = false
+		vr.SchemaError = err.Error()
+		return err
+	}
+	for _, msg := range msgs {
+		// The messages are of the form "pointer: message".
+		pointer, message, ok := strings.Cut(msg, ": ")
+		if !ok {
+			pointer, message = "", msg
+		}
+		vr.Schema = append(vr.Schema, ValidationMessage{Pointer: pointer, Message: message})
+	}
+	if len(msgs) > 0 {
+		vr.Valid = false
+	}
+	return nil
+}
+
+// AddRemote adds the result of the remote validator.
+// err is the error if the remote validation could not be done.
+func (vr *ValidationReport) AddRemote(rvr *csaf.RemoteValidationResult, err error) {
+	remote := &RemoteReport{}
+	switch {
+	case err != nil:
+		remote.Error = err.Error()
+	case rvr == nil:
+		remote.Error = "no result"
+	default:
+		remote.Valid = rvr.Valid
+		for _, t := range rvr.Tests {
+			remote.Tests = append(remote.Tests, RemoteTestReport{
+				Name:     t.Name,
+				Kind:     testKind(t.Name),
+				Valid:    t.Valid,
+				Errors:   messages(t.Error),
+				Warnings: messages(t.Warning),
+				Infos:    messages(t.Info),
+			})
+		}
+	}
+	if !remote.Valid {
+		vr.Valid = false
+	}
+	vr.Remote = remote
+}
+
+// CheckFilename checks if the file name is conforming
+// and if it matches the tracking ID of the document.
+func (vr *ValidationReport) CheckFilename(document any, filename string) {
+	fr := &FilenameReport{
+		Filename:   filename,
+		Conforming: util.ConformingFileName(filename),
+	}
+	pe := util.NewPathEval()
+	var id string
+	if err := pe.Extract(`$.document.tracking.id`, util.StringMatcher(&id), false, document); err == nil {
+		fr.TrackingID = id
+	}
+	if err := util.IDMatchesFilename(pe, document, filename); err != nil {
+		fr.Error = err.Error()
+	} else {
+		fr.Matches = true
+	}
+	if !fr.Conforming || !fr.Matches {
+		vr.Valid = false
+	}
+	vr.Filename = fr
+}
+
+// SchemaFailed checks if the schema validation failed.
+func (vr *ValidationReport) SchemaFailed() bool {
+	return vr.SchemaError != "" || len(vr.Schema) > 0
+}
+
+// RemoteFailed checks if the remote validation was done and failed.
+func (vr *ValidationReport) RemoteFailed() bool {
+	return vr.Remote != nil && !vr.Remote.Valid
+}
+
+// FilenameFailed checks if the check of the file name was done and failed.
+func (vr *ValidationReport) FilenameFailed() bool {
+	return vr.Filename != nil && (!vr.Filename.Conforming || !vr.Filename.Matches)
+}
+
+// SchemaErr returns the schema validation failure as an error.
+func (vr *ValidationReport) SchemaErr() error {
+	if vr.SchemaError != "" {
+		return errors.New("schema validation failed: " + vr.SchemaError)
+	}
+	if len(vr.Schema) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(vr.Schema))
+	for i, m := range vr.Schema {
+		msgs[i] = m.Pointer + ": " + m.Message
+	}
+	return errors.New("schema validation failed: " + strings.Join(msgs, ", "))
+}
+
+// RemoteErr returns the remote validation failure as an error.
+// It names the failed tests.
+func (vr *ValidationReport) RemoteErr() error {
+	if !vr.RemoteFailed() {
+		return nil
+	}
+	if vr.Remote.Error != "" {
+		return errors.New("remote validation failed: " + vr.Remote.Error)
+	}
+	var failed []string
+	for _, t := range vr.Remote.Tests {
+		if !t.Valid {
+			failed = append(failed, t.Name)
+		}
+	}
+	if len(failed) == 0 {
+		return errors.New("remote validation failed")
+	}
+	return errors.New("remote validation failed: " + strings.Join(failed, ", "))
+}
@@ -0,0 +1,135 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gocsaf/csaf/v3/csaf"
+)
+
+func TestCheckSchema(t *testing.T) {
+	document := map[string]any{
+		"document": map[string]any{
+			"category": 5,
+		},
+	}
+	report := NewValidationReport()
+	if err := report.CheckSchema(document); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Valid || !report.SchemaFailed() {
+		t.Fatal("invalid document passed schema validation")
+	}
+	pointers := map[string]string{}
+	for _, msg := range report.Schema {
+		pointers[msg.Pointer] = msg.Message
+	}
+	for _, x := range []struct {
+		pointer string
+		message string
+	}{
+		{"/document/category", "got number, want string"},
+		{"/document", "missing properties 'csaf_version', 'publisher', 'title', 'tracking'"},
+	} {
+		message, ok := pointers[x.pointer]
+		if !ok {
+			t.Errorf("no message for %q: %+v", x.pointer, report.Schema)
+			continue
+		}
+		if message != x.message {
+			t.Errorf("%q: got %q, expected %q", x.pointer, message, x.message)
+		}
+	}
+	if report.SchemaErr() == nil {
+		t.Error("missing schema error")
+	}
+}
+
+func TestTestKind(t *testing.T) {
+	for _, x := range []struct {
+		name     string
+		expected string
+	}{
+		{"mandatoryTest_6_1_1", MandatoryTest},
+		{"optionalTest_6_2_10", OptionalTest},
+		{"informativeTest_6_3_2", InformativeTest},
+		{"6.1.27.1", MandatoryTest},
+		{"test_6_2_1", OptionalTest},
+		{"mandatory", MandatoryTest},
+		{"Optional", OptionalTest},
+		{"informative", InformativeTest},
+		{"csaf_2_0", OtherTest},
+		{"schema", OtherTest},
+		{"non-mandatory", OtherTest},
+		{"test_16_1_1", OtherTest},
+	} {
+		if kind := testKind(x.name); kind != x.expected {
+			t.Errorf("%q: got %q, expected %q", x.name, kind, x.expected)
+		}
+	}
+}
+
+func TestRemoteErr(t *testing.T) {
+	for _, x := range []struct {
+		name     string
+		rvr      *csaf.RemoteValidationResult
+		err      error
+		expected string
+	}{{
+		name: "valid",
+		rvr: &csaf.RemoteValidationResult{
+			Valid: true,
+			Tests: []csaf.RemoteTest{{Name: "mandatoryTest_6_1_1", Valid: true}},
+		},
+	}, {
+		name:     "not done",
+		err:      errors.New("connection refused"),
+		expected: "remote validation failed: connection refused",
+	}, {
+		name:     "no result",
+		expected: "remote validation failed: no result",
+	}, {
+		name: "failed tests",
+		rvr: &csaf.RemoteValidationResult{
+			Tests: []csaf.RemoteTest{
+				{Name: "mandatoryTest_6_1_1", Valid: false},
+				{Name: "mandatoryTest_6_1_2", Valid: true},
+				{Name: "optionalTest_6_2_1", Valid: false},
+			},
+		},
+		expected: "remote validation failed: mandatoryTest_6_1_1, optionalTest_6_2_1",
+	}, {
+		name:     "no failed tests",
+		rvr:      &csaf.RemoteValidationResult{},
+		expected: "remote validation failed",
+	}} {
+		t.Run(x.name, func(t *testing.T) {
+			report := NewValidationReport()
+			report.AddRemote(x.rvr, x.err)
+			err := report.RemoteErr()
+			if x.expected == "" {
+				if err != nil || !report.Valid {
+					t.Errorf("unexpected failure: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("missing error")
+			}
+			if err.Error() != x.expected {
+				t.Errorf("got %q, expected %q", err, x.expected)
+			}
+			if report.Valid {
+				t.Error("report is valid")
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"net/url"
 
 	"github.com/ISDuBA/ISDuBA/pkg/config"
+	"github.com/ProtonMail/gopenpgp/v2/crypto"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -22,12 +23,14 @@ import (
 // Boot loads the sources from database.
 func (m *Manager) Boot(ctx context.Context) error {
 	const (
-		sourcesSQL = `SELECT id, name, url, rate, slots, active, headers, ` +
+		sourcesSQL = `SELECT id, name, url, mirrors, rate, slots, active, headers, ` +
 			`strict_mode, secure, signature_check, age, ignore_patterns, ` +
 			`client_cert_public, client_cert_private, client_cert_passphrase, ` +
-			`checksum, checksum_ack, checksum_updated ` +
+			`checksum, checksum_ack, checksum_updated, subscription ` +
 			`FROM sources ORDER BY id`
 		feedsSQL = `SELECT id, label, sources_id, url, rolie, log_lvl::text FROM feeds`
+		keysSQL  = `SELECT sources_id, fingerprint, approved, first_seen, ` +
+			`origin::text, armored, reviewed IS NOT NULL FROM source_keys ORDER BY first_seen`
 	)
 	if err := m.db.Run(
 		ctx,
@@ -48,15 +51,19 @@ func (m *Manager) Boot(ctx context.Context) error {
 					s                                       source
 					patterns                                []string
 					clientCertPrivate, clientCertPassphrase []byte
+					subscription                            *string
 				)
 				if err := row.Scan(
-					&s.id, &s.name, &s.url, &s.rate, &s.slots, &s.active, &s.headers,
+					&s.id, &s.name, &s.url, &s.mirrors, &s.rate, &s.slots, &s.active, &s.headers,
 					&s.strictMode, &s.secure, &s.signatureCheck, &s.age, &patterns,
 					&s.clientCertPublic, &clientCertPrivate, &clientCertPassphrase,
-					&s.checksum, &s.checksumAck, &s.checksumUpdated,
+					&s.checksum, &s.checksumAck, &s.checksumUpdated, &subscription,
 				); err != nil {
 					return nil, err
 				}
+				if subscription != nil {
+					s.subscription = *subscription
+				}
 				regexps, err := AsRegexps(patterns)
 				if err != nil {
 					return nil, err
@@ -138,6 +145,38 @@ func (m *Manager) Boot(ctx context.Context) error {
 			if err := frows.Err(); err != nil {
 				return fmt.Errorf("collecting feeds failed: %w", err)
 			}
+
+			// Collect pinned keys.
+			krows, err := tx.Query(rctx, keysSQL)
+			if err != nil {
+				return fmt.Errorf("querying source keys failed: %w", err)
+			}
+			defer krows.Close()
+			for krows.Next() {
+				var (
+					k       pinnedKey
+					sid     int64
+					armored *string
+				)
+				if err := krows.Scan(
+					&sid, &k.fingerprint, &k.approved, &k.firstSeen,
+					&k.origin, &armored, &k.reviewed,
+				); err != nil {
+					return err
+				}
+				if armored != nil {
+					if k.key, err = crypto.NewKeyFromArmored(*armored); err != nil {
+						slog.Error("Loading OpenPGP key failed",
+							"source", sid, "fingerprint", k.fingerprint, "err", err)
+					}
+				}
+				if s := m.findSourceByID(sid); s != nil {
+					s.keys = append(s.keys, &k)
+				}
+			}
+			if err := krows.Err(); err != nil {
+				return fmt.Errorf("collecting source keys failed: %w", err)
+			}
 			return tx.Commit(rctx)
 		}, 0,
 	); err != nil {
@@ -0,0 +1,148 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package sources
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gocsaf/csaf/v3/csaf"
+)
+
+// Origins of PMD candidates found in discovery.
+const (
+	// WellKnownOrigin is the /.well-known/csaf/provider-metadata.json path.
+	WellKnownOrigin = "well-known"
+	// SecurityTXTOrigin is a CSAF field in a security.txt.
+	SecurityTXTOrigin = "security.txt"
+	// DNSOrigin is the csaf.data.security.domain.tld host.
+	DNSOrigin = "dns"
+)
+
+// PMDCandidate is a location where a PMD was looked up during discovery.
+// Selected marks the candidate chosen as the PMD of the domain.
+type PMDCandidate struct {
+	URL      string   `json:"url"`
+	Origin   string   `json:"origin"`
+	Valid    bool     `json:"valid"`
+	Selected bool     `json:"selected"`
+	Messages []string `json:"messages,omitempty"`
+}
+
+// PMDDiscovery is the result of looking up the PMDs of a domain.
+type PMDDiscovery struct {
+	Domain     string         `json:"domain"`
+	Candidates []PMDCandidate `json:"candidates"`
+	Selected   string         `json:"selected,omitempty"`
+	Messages   []string       `json:"messages,omitempty"`
+}
+
+// domainRe matches a dotted host name without a port.
+// The top level domain is not numeric to rule out IP addresses.
+var domainRe = regexp.MustCompile(
+	`^(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z](?:[a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// IsDomain checks if the given string is a bare domain and not an URL.
+// Only dotted host names without a port are domains so that
+// no local or internal names are looked up.
+func IsDomain(s string) bool {
+	return len(s) <= 253 && domainRe.MatchString(strings.ToLower(s))
+}
+
+// DiscoverPMD looks up the PMDs of a domain in the order defined by CSAF:
+// the well-known path, the CSAF fields of the security.txt and
+// the DNS path. All candidates are probed and reported.
+// The selected one is the first valid candidate in that order.
+func (m *Manager) DiscoverPMD(domain string) (*PMDDiscovery, error) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if !IsDomain(domain) {
+		return nil, InvalidArgumentError(fmt.Sprintf("%q is not a domain", domain))
+	}
+	pd := &PMDDiscovery{Domain: domain}
+
+	// First try the well-known path.
+	pd.add(m, "https://"+domain+"/.well-known/csaf/provider-metadata.json", WellKnownOrigin)
+
+	// Next look at the security.txt.
+	for _, u := range m.securityTXTURLs(pd, domain) {
+		pd.add(m, u, SecurityTXTOrigin)
+	}
+
+	// The DNS path is the last resort.
+	pd.add(m, "https://csaf.data.security."+domain, DNSOrigin)
+	return pd, nil
+}
+
+// add looks up a PMD candidate and records the result.
+func (pd *PMDDiscovery) add(m *Manager, url, origin string) {
+	if slices.ContainsFunc(pd.Candidates, func(c PMDCandidate) bool { return c.URL == url }) {
+		return
+	}
+	// Loading a PMD without scheme would start a discovery of its own.
+	if !strings.HasPrefix(url, "https://") {
+		pd.Candidates = append(pd.Candidates, PMDCandidate{
+			URL:      url,
+			Origin:   origin,
+			Messages: []string{"not an https URL"},
+		})
+		return
+	}
+	cpmd := m.PMD(url)
+	cand := PMDCandidate{
+		URL:    url,
+		Origin: origin,
+		Valid:  cpmd.Valid(),
+	}
+	if cpmd != nil && cpmd.Loaded != nil {
+		for i := range cpmd.Loaded.Messages {
+			cand.Messages = append(cand.Messages, cpmd.Loaded.Messages[i].Message)
+		}
+	}
+	if cand.Valid && pd.Selected == "" {
+		pd.Selected = url
+		cand.Selected = true
+	}
+	pd.Candidates = append(pd.Candidates, cand)
+}
+
+// securityTXTURLs extracts the PMD URLs from the CSAF fields of
+// the security.txt of a domain. The legacy location is only
+// tried if there is none at the well-known path.
+func (m *Manager) securityTXTURLs(pd *PMDDiscovery, domain string) []string {
+	client := newPMDClient(m.cfg)
+	for _, path := range []string{
+		"https://" + domain + "/.well-known/security.txt",
+		"https://" + domain + "/security.txt",
+	} {
+		resp, err := client.Get(path)
+		if err != nil {
+			pd.Messages = append(pd.Messages, fmt.Sprintf("fetching %q failed: %v", path, err))
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			pd.Messages = append(pd.Messages,
+				fmt.Sprintf("fetching %q failed: %s (%d)", path, resp.Status, resp.StatusCode))
+			continue
+		}
+		urls, err := func() ([]string, error) {
+			defer resp.Body.Close()
+			return csaf.ExtractProviderURL(resp.Body, true)
+		}()
+		if err != nil {
+			pd.Messages = append(pd.Messages, fmt.Sprintf("loading %q failed: %v", path, err))
+			continue
+		}
+		return urls
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package sources
+
+import "testing"
+
+func TestIsDomain(t *testing.T) {
+	for _, x := range []struct {
+		input    string
+		expected bool
+	}{
+		{"example.com", true},
+		{"csaf.Example.org", true},
+		{"my-provider.example", true},
+		{"", false},
+		{"localhost", false},
+		{"intranet", false},
+		{"intranet:8443", false},
+		{"example.com:443", false},
+		{"192.168.0.1", false},
+		{"https://example.com", false},
+		{"example.com/path", false},
+		{"-bad.example", false},
+		{"bad-.example", false},
+		{"example..com", false},
+	} {
+		if got := IsDomain(x.input); got != x.expected {
+			t.Errorf("%q: got %t, expected %t", x.input, got, x.expected)
+		}
+	}
+}
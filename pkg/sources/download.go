@@ -27,7 +27,6 @@ import (
 	"github.com/ISDuBA/ISDuBA/pkg/config"
 	"github.com/ISDuBA/ISDuBA/pkg/models"
 	"github.com/ProtonMail/gopenpgp/v2/crypto"
-	"github.com/gocsaf/csaf/v3/csaf"
 	"github.com/gocsaf/csaf/v3/util"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -90,6 +89,8 @@ func (l *location) download(m *Manager, f *feed) {
 		data           bytes.Buffer             // The raw data will be stored in the database.
 		signatureData  []byte                   // The signature will be stored in the database.
 		client         *http.Client
+		origins        *origins // Mirrors to fail over to.
+		report         = models.NewValidationReport()
 	)
 
 	// The manager owns the configuration so extract the parameters beforehand.
@@ -97,6 +98,7 @@ func (l *location) download(m *Manager, f *feed) {
 		strictMode = f.source.useStrictMode(m)
 		signatureCheck = f.source.checkSignature(m)
 		client = f.source.httpClient(m)
+		origins = f.source.origins()
 	})
 	defer client.CloseIdleConnections()
 
@@ -123,7 +125,7 @@ func (l *location) download(m *Manager, f *feed) {
 		}
 		if checksum != nil {
 			var check func(*dlStatus, *feed)
-			if remoteChecksum, err := f.source.loadHash(client, m, hashFile); err != nil {
+			if remoteChecksum, err := f.source.loadHash(client, m, origins, hashFile); err != nil {
 				check = func(ds *dlStatus, f *feed) {
 					ds.set(checksumFailed)
 					f.log(m, config.WarnFeedLogLevel, "Fetching hash %q failed: %v", hashFile, err)
@@ -150,7 +152,7 @@ func (l *location) download(m *Manager, f *feed) {
 			{".sha256", sha256.New},
 		} {
 			guess := l.doc.String() + h.ext
-			if rc, err := f.source.loadHash(client, m, guess); err == nil {
+			if rc, err := f.source.loadHash(client, m, origins, guess); err == nil {
 				remoteChecksum, checksum = rc, h.cstr()
 				break
 			}
@@ -177,7 +179,7 @@ func (l *location) download(m *Manager, f *feed) {
 	writers = append(writers, &data)
 
 	// Download the CSAF document.
-	resp, err := f.source.httpGet(client, m, l.doc.String())
+	resp, servedBy, err := f.source.httpGetFailover(client, m, origins, l.doc.String())
 	if err != nil {
 		f.log(m, config.ErrorFeedLogLevel, "downloading %q failed: %v", l.doc, err)
 		return
@@ -205,23 +207,23 @@ func (l *location) download(m *Manager, f *feed) {
 
 	// Check if the tracking id matches the filename.
 	checks = append(checks, func(ds *dlStatus, f *feed) {
-		expr := util.NewPathEval()
-		if err := util.IDMatchesFilename(expr, doc, filename); err != nil {
+		if report.CheckFilename(doc, filename); !report.Filename.Matches {
 			ds.set(filenameFailed)
-			f.log(m, config.ErrorFeedLogLevel, "Tracking ID in %q is not conforming: %v", l.doc, err)
+			f.log(m, config.ErrorFeedLogLevel,
+				"Tracking ID in %q is not conforming: %s", l.doc, report.Filename.Error)
 		}
 	})
 
 	// Check document against schema.
 	checks = append(checks, func(ds *dlStatus, f *feed) {
-		if errors, err := csaf.ValidateCSAF(doc); err != nil || len(errors) > 0 {
+		if err := report.CheckSchema(doc); err != nil || report.SchemaFailed() {
 			ds.set(schemaValidationFailed)
 			if err != nil {
 				f.log(m, config.ErrorFeedLogLevel,
 					"Schema validation of document %q failed: %v", l.doc, err)
 			} else {
 				f.log(m, config.ErrorFeedLogLevel,
-					"Schema validation of document %q has %d errors", l.doc, len(errors))
+					"Schema validation of document %q has %d errors", l.doc, len(report.Schema))
 			}
 			return
 		}
@@ -230,25 +232,44 @@ func (l *location) download(m *Manager, f *feed) {
 	// Check against remote validator if configured.
 	if m.val != nil {
 		checks = append(checks, func(ds *dlStatus, f *feed) {
-			switch rvr, err := m.val.Validate(doc); {
+			rvr, err := m.val.Validate(doc)
+			report.AddRemote(rvr, err)
+			switch {
 			case err != nil:
 				ds.set(remoteValidationFailed)
 				slog.Error("Remote validation failed", "err", err, "url", l.doc)
 				f.log(m, config.ErrorFeedLogLevel,
 					"Remote validation of document %q failed: %v", l.doc, err)
-			case !rvr.Valid:
-				// XXX: Maybe we should tell more details here?!
+			case report.RemoteFailed():
 				ds.set(remoteValidationFailed)
 				f.log(m, config.ErrorFeedLogLevel,
-					"Remote validator classifies document %q as invalid", l.doc)
+					"Remote validator classifies document %q as invalid: %v", l.doc, report.RemoteErr())
 			}
 		})
 	}
 
-	// Check signatures
+	// Check signatures. Documents served by mirrors are verified
+	// against the keys of the original provider. As a mirror may
+	// serve anything their signatures are always checked.
+	mirrored := servedBy != l.doc.String()
+	if mirrored {
+		signatureCheck = true
+	}
 	keys, err := m.openPGPKeys(f.source)
+	var keysPending bool
+	m.inManager(func(*Manager, context.Context) { keysPending = f.source.keysPending() })
 	if err != nil {
 		f.log(m, config.ErrorFeedLogLevel, "Loading OpenPGP keys failed: %v", err)
+	}
+	if keysPending && keys.CountEntities() == 0 {
+		// No signature is accepted until the new keys are approved.
+		checks = append(checks, func(ds *dlStatus, f *feed) {
+			if signatureCheck {
+				ds.set(signatureFailed)
+				f.log(m, config.ErrorFeedLogLevel,
+					"Verifying OpenPGP signature of %q failed: key change pending approval", l.doc)
+			}
+		})
 	} else if keys.CountEntities() > 0 {
 		// Only check signature if we have something in the key ring.
 		checks = append(checks, func(ds *dlStatus, f *feed) {
@@ -265,7 +286,7 @@ func (l *location) download(m *Manager, f *feed) {
 			}
 			var err error
 			var signature *crypto.PGPSignature
-			if signature, signatureData, err = f.source.loadSignature(client, m, sign); err != nil {
+			if signature, signatureData, err = f.source.loadSignature(client, m, origins, sign); err != nil {
 				if signatureCheck {
 					ds.set(signatureFailed)
 					f.log(m, config.ErrorFeedLogLevel,
@@ -282,6 +303,20 @@ func (l *location) download(m *Manager, f *feed) {
 				}
 			}
 		})
+	} else if mirrored {
+		// Without the keys of the provider a mirror cannot be trusted.
+		checks = append(checks, func(ds *dlStatus, f *feed) {
+			ds.set(signatureFailed)
+			f.log(m, config.ErrorFeedLogLevel,
+				"Verifying OpenPGP signature of %q failed: no OpenPGP keys of the provider", l.doc)
+		})
+	}
+
+	// Record the mirror if the document was not served by the source.
+	addMirror := func(i *inserter) {
+		if mirrored {
+			i.add("mirror", servedBy)
+		}
 	}
 
 	// Run the checks.
@@ -291,18 +326,43 @@ func (l *location) download(m *Manager, f *feed) {
 	}
 
 	if strictMode && status != allSucceeded {
-		// Don't import, only write the stats.
+		// Don't import, only write the stats and quarantine the document.
 		if err := m.db.Run(context.Background(), func(ctx context.Context, conn *pgxpool.Conn) error {
+			tx, err := conn.Begin(ctx)
+			if err != nil {
+				return err
+			}
+			defer tx.Rollback(ctx)
 			var i inserter
 			status.toInserter(&i)
-			if !f.invalid.Load() {
+			i.add("validation_report", report)
+			addMirror(&i)
+			// Documents of removed feeds are not quarantined.
+			invalid := f.invalid.Load()
+			if !invalid {
 				i.add("feeds_id", f.id)
 			}
 			sql := i.sql("downloads")
-			_, err := conn.Exec(ctx, sql, i.values...)
-			return err
+			if _, err := tx.Exec(ctx, sql, i.values...); err != nil {
+				return err
+			}
+			if !invalid {
+				if err := l.quarantine(ctx, tx, f, &quarantined{
+					doc:       doc,
+					data:      data.Bytes(),
+					signature: signatureData,
+					filename:  filename,
+					status:    status,
+					report:    report,
+				}); err != nil {
+					return err
+				}
+			}
+			return tx.Commit(ctx)
 		}, 0); err != nil {
 			f.log(m, config.ErrorFeedLogLevel, "storing stats of %q failed: %v", l.doc, err)
+		} else {
+			f.log(m, config.WarnFeedLogLevel, "document %q quarantined", l.doc)
 		}
 		return
 	}
@@ -319,6 +379,8 @@ func (l *location) download(m *Manager, f *feed) {
 			i.add("feeds_id", f.id)
 		}
 		status.toInserter(&i)
+		i.add("validation_report", report)
+		addMirror(&i)
 		sql := i.sql("downloads")
 		_, err := tx.Exec(ctx, sql, i.values...)
 		return err
@@ -347,7 +409,7 @@ func (l *location) download(m *Manager, f *feed) {
 			doc, data.Bytes(),
 			importer,
 			m.cfg.Sources.PublishersTLPs,
-			models.ChainInTx(storeStats, storeSignature, f.storeLastChanges(l)),
+			models.ChainInTx(storeStats, storeSignature, f.storeLastChanges(l), f.clearQuarantine(l)),
 			false)
 		return err
 	}, 0); {
@@ -358,5 +420,9 @@ func (l *location) download(m *Manager, f *feed) {
 		return
 	}
 
-	f.log(m, config.InfoFeedLogLevel, "downloading %q done", l.doc)
+	if mirrored {
+		f.log(m, config.InfoFeedLogLevel, "downloading %q done (served by mirror %q)", l.doc, servedBy)
+	} else {
+		f.log(m, config.InfoFeedLogLevel, "downloading %q done", l.doc)
+	}
 }
@@ -9,26 +9,115 @@
 package sources
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/gocsaf/csaf/v3/csaf"
 )
 
+// indexRecheckInterval is the interval in which the already stored
+// documents listed in an index.txt are checked for changes.
+const indexRecheckInterval = 24 * time.Hour
+
 type feedIndex struct {
 	base           *url.URL
 	age            *time.Duration
 	ignorePatterns ignorePatterns
 	sameOrNewer    func(*location) bool
+	// fetch is used to load the feeds listed in a ROLIE service document.
+	fetch func(*url.URL) (io.ReadCloser, error)
+	// probe is used to determine the last modification time of
+	// documents listed in an index.txt.
+	probe func(*url.URL) (time.Time, error)
+	// stored are the URLs of the documents of an index.txt
+	// which are already stored.
+	stored map[string]bool
+	// recheck tells if the stored documents of an
+	// index.txt should be checked for changes.
+	recheck bool
+}
+
+// isServiceDocument checks if the given data is a ROLIE service document.
+func isServiceDocument(data []byte) bool {
+	var probe struct {
+		Service json.RawMessage `json:"service"`
+	}
+	return json.Unmarshal(data, &probe) == nil && len(probe.Service) > 0
 }
 
 // rolieLocations assumes that the feed index is ROLIE.
+// If the index turns out to be a ROLIE service document
+// the locations of all the listed feeds are returned.
 func (fi *feedIndex) rolieLocations(r io.Reader) ([]location, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading rolie feed failed: %w", err)
+	}
+	if isServiceDocument(data) {
+		return fi.serviceLocations(data)
+	}
+	return fi.feedLocations(bytes.NewReader(data))
+}
+
+// serviceLocations expands a ROLIE service document into
+// the locations of its feeds.
+func (fi *feedIndex) serviceLocations(data []byte) ([]location, error) {
+	if fi.fetch == nil {
+		return nil, fmt.Errorf("nested ROLIE service document %q", fi.base)
+	}
+	rsd, err := csaf.LoadROLIEServiceDocument(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("loading rolie service document failed: %w", err)
+	}
+	var dls []location
+	for _, feed := range serviceFeeds(fi.base, rsd) {
+		u, err := url.Parse(feed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid feed url: %v", feed)
+		}
+		sub := feedIndex{
+			base:           u,
+			age:            fi.age,
+			ignorePatterns: fi.ignorePatterns,
+			sameOrNewer:    fi.sameOrNewer,
+		}
+		locs, err := func() ([]location, error) {
+			rc, err := fi.fetch(u)
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return sub.feedLocations(rc)
+		}()
+		if err != nil {
+			// One broken feed should not spoil the others.
+			slog.Warn("loading feed of ROLIE service document failed",
+				"service", fi.base, "feed", u, "err", err)
+			continue
+		}
+		for _, loc := range locs {
+			// The same document may be listed in more than one feed.
+			if !slices.ContainsFunc(dls, func(l location) bool {
+				return l.doc.String() == loc.doc.String()
+			}) {
+				dls = append(dls, loc)
+			}
+		}
+	}
+	return dls, nil
+}
+
+// feedLocations extracts the locations from a ROLIE feed.
+func (fi *feedIndex) feedLocations(r io.Reader) ([]location, error) {
 	rolie, err := csaf.LoadROLIEFeed(r)
 	if err != nil {
 		return nil, fmt.Errorf("loading rolie feed from data failed: %w", err)
@@ -163,3 +252,71 @@ func (fi *feedIndex) directoryLocations(r io.Reader) ([]location, error) {
 
 	return dls, nil
 }
+
+// indexLocations assumes that the feed index is index.txt.
+// As index.txt does not carry any time information the
+// modification times are probed if possible. Documents which
+// are already stored are only probed if rechecking and are
+// downloaded again if their modification time is unknown.
+// New locations with unknown modification times have a zero updated time.
+func (fi *feedIndex) indexLocations(r io.Reader) ([]location, error) {
+	// If we have a max age set calculate the cut time.
+	now := time.Now()
+	var cut time.Time
+	if fi.age != nil {
+		cut = now.Add(-*fi.age)
+	}
+
+	var dls []location
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		doc, err := url.Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d is not a valid URL: %w", lineNo, err)
+		}
+		if !doc.IsAbs() {
+			doc = joinURL(fi.base, doc)
+		}
+		// Apply ignore patterns before probing to save requests.
+		if fi.ignorePatterns.ignore(doc) {
+			continue
+		}
+		dl := location{doc: doc}
+		// Without a modification time any queued location is the same
+		// or newer. These are downloaded anyway so save probing them.
+		if fi.sameOrNewer != nil && fi.sameOrNewer(&dl) {
+			continue
+		}
+		stored := fi.stored[doc.String()]
+		if stored && !fi.recheck {
+			continue
+		}
+		if fi.probe != nil {
+			updated, err := fi.probe(doc)
+			switch {
+			case err != nil && stored:
+				// Try again with the next recheck.
+				continue
+			case updated.IsZero() && stored:
+				// Unknown if it changed so check it by downloading it.
+				updated = now
+			}
+			dl.updated = updated
+		}
+		// Apply age filter only if we know the modification time.
+		if fi.age != nil && !dl.updated.IsZero() && dl.updated.Before(cut) {
+			continue
+		}
+		dls = append(dls, dl)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading index.txt failed: %w", err)
+	}
+
+	return dls, nil
+}
@@ -0,0 +1,99 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package sources
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIndexLocations(t *testing.T) {
+	base, _ := url.Parse("https://provider.example/white/")
+	modified := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	const index = "2026/new.json\n2026/new-unknown.json\n" +
+		"2026/stored.json\n2026/stored-unknown.json\n2026/stored-down.json\n"
+	probe := func(probed *[]string) func(*url.URL) (time.Time, error) {
+		return func(u *url.URL) (time.Time, error) {
+			name := u.String()[len(base.String()):]
+			*probed = append(*probed, name)
+			switch {
+			case strings.HasSuffix(name, "-down.json"):
+				return time.Time{}, errors.New("down")
+			case strings.HasSuffix(name, "-unknown.json"):
+				return time.Time{}, nil
+			}
+			return modified, nil
+		}
+	}
+	stored := map[string]bool{}
+	for _, name := range []string{"stored.json", "stored-unknown.json", "stored-down.json"} {
+		stored[base.String()+"2026/"+name] = true
+	}
+	for _, x := range []struct {
+		name     string
+		recheck  bool
+		probed   []string
+		expected map[string]bool // name -> zero updated time
+	}{{
+		name:   "new only",
+		probed: []string{"2026/new.json", "2026/new-unknown.json"},
+		expected: map[string]bool{
+			"2026/new.json":         false,
+			"2026/new-unknown.json": true,
+		},
+	}, {
+		name:    "recheck",
+		recheck: true,
+		probed: []string{
+			"2026/new.json", "2026/new-unknown.json",
+			"2026/stored.json", "2026/stored-unknown.json", "2026/stored-down.json",
+		},
+		expected: map[string]bool{
+			"2026/new.json":            false,
+			"2026/new-unknown.json":    true,
+			"2026/stored.json":         false,
+			"2026/stored-unknown.json": false,
+		},
+	}} {
+		t.Run(x.name, func(t *testing.T) {
+			var probed []string
+			fi := feedIndex{
+				base:    base,
+				probe:   probe(&probed),
+				stored:  stored,
+				recheck: x.recheck,
+			}
+			locs, err := fi.indexLocations(strings.NewReader(index))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(probed, x.probed) {
+				t.Errorf("probed %v, expected %v", probed, x.probed)
+			}
+			if len(locs) != len(x.expected) {
+				t.Fatalf("got %d locations, expected %d", len(locs), len(x.expected))
+			}
+			for _, l := range locs {
+				name := l.doc.String()[len(base.String()):]
+				zero, ok := x.expected[name]
+				if !ok {
+					t.Errorf("unexpected location %q", name)
+					continue
+				}
+				if l.updated.IsZero() != zero {
+					t.Errorf("%q: unexpected updated time %v", name, l.updated)
+				}
+			}
+		})
+	}
+}
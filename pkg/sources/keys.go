@@ -9,18 +9,89 @@
 package sources
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/ISDuBA/ISDuBA/pkg/cache"
 	"github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const keyChangePendingApproval = `OpenPGP key change pending approval.`
+
+// Origins of the OpenPGP keys of a source.
+const (
+	// PMDKeyOrigin marks keys found in the PMD of a source.
+	PMDKeyOrigin = "pmd"
+	// ManualKeyOrigin marks keys uploaded by a source manager.
+	ManualKeyOrigin = "manual"
+)
+
+// pinnedKey is the fingerprint of an OpenPGP key which was seen
+// in the PMD of a source or which was added manually.
+type pinnedKey struct {
+	fingerprint string
+	approved    bool
+	firstSeen   time.Time
+	origin      string
+	reviewed    bool        // false while a key change is pending approval.
+	key         *crypto.Key // nil if the PMD key was not loaded, yet.
+}
+
+// KeyInfo are the infos about a pinned OpenPGP key of a source.
+type KeyInfo struct {
+	Fingerprint string     `json:"fingerprint"`
+	Approved    bool       `json:"approved"`
+	FirstSeen   time.Time  `json:"first_seen"`
+	Origin      string     `json:"origin"`
+	Reviewed    bool       `json:"reviewed"`
+	Expires     *time.Time `json:"expires,omitempty"`
+}
+
+// keyExpiry returns the expiration time of a key.
+// nil is returned if the key does not expire.
+func keyExpiry(key *crypto.Key) *time.Time {
+	entity := key.GetEntity()
+	sig, _ := entity.PrimarySelfSignature()
+	if sig == nil || sig.KeyLifetimeSecs == nil || *sig.KeyLifetimeSecs == 0 {
+		return nil
+	}
+	expires := entity.PrimaryKey.CreationTime.
+		Add(time.Duration(*sig.KeyLifetimeSecs) * time.Second).UTC()
+	return &expires
+}
+
+func (k *pinnedKey) info() KeyInfo {
+	ki := KeyInfo{
+		Fingerprint: k.fingerprint,
+		Approved:    k.approved,
+		FirstSeen:   k.firstSeen,
+		Origin:      k.origin,
+		Reviewed:    k.reviewed,
+	}
+	if k.key != nil {
+		ki.Expires = keyExpiry(k.key)
+	}
+	return ki
+}
+
+// keyInfos returns the infos about the pinned keys of the source.
+func (s *source) keyInfos() []KeyInfo {
+	infos := make([]KeyInfo, 0, len(s.keys))
+	for _, k := range s.keys {
+		infos = append(infos, k.info())
+	}
+	return infos
+}
+
 type keysCache struct {
 	*cache.ExpirationCache[int64, *crypto.KeyRing]
 }
@@ -31,38 +102,81 @@ func newKeysCache(expiration time.Duration) *keysCache {
 	}
 }
 
-// openPGPKeys extracts the OpenPGP key from them PMD of a source if not already
-// in cache.
+// openPGPKeys builds the key ring of a source from the approved keys of
+// its PMD and the manually added keys if not already in cache.
+// The returned key ring is never nil.
 func (m *Manager) openPGPKeys(source *source) (*crypto.KeyRing, error) {
 	if keys, ok := m.keysCache.Get(source.id); ok {
 		return keys, nil
 	}
+	ckeys, err := m.pmdOpenPGPKeys(source)
+	// Only keys approved by pinning are used for verification.
+	var trusted []*crypto.Key
+	m.inManager(func(m *Manager, ctx context.Context) {
+		trusted = m.pinKeys(ctx, source, ckeys)
+		if err != nil {
+			// Keep the keys of the provider loaded before
+			// while its PMD or its keys are not available.
+			trusted = append(trusted, source.loadedKeys()...)
+		}
+	})
 	keys, _ := crypto.NewKeyRing(nil)
-	cpmd := m.pmdCache.pmd(source.url, m.cfg)
-	if !cpmd.Valid() {
+	for _, ckey := range trusted {
+		if err := keys.AddKey(ckey); err != nil {
+			slog.Warn(
+				"Could not add public OpenPGP key to key ring",
+				"fingerprint", ckey.GetFingerprint())
+		}
+	}
+	if err != nil {
 		// Try again soon.
 		m.keysCache.SetWithExpiration(source.id, keys, holdingPMDsDuration)
+		return keys, err
+	}
+	m.keysCache.Set(source.id, keys)
+	return keys, nil
+}
+
+// loadedKeys returns the approved PMD keys of the source
+// which were loaded before.
+func (s *source) loadedKeys() []*crypto.Key {
+	var keys []*crypto.Key
+	for _, k := range s.keys {
+		if k.origin == PMDKeyOrigin && k.approved && k.key != nil {
+			keys = append(keys, k.key)
+		}
+	}
+	return keys
+}
+
+// pmdOpenPGPKeys loads the OpenPGP keys listed in the PMD of a source.
+// It fails if the PMD lists keys but none of them could be loaded.
+func (m *Manager) pmdOpenPGPKeys(source *source) ([]*crypto.Key, error) {
+	cpmd := m.pmdCache.pmd(source.url, m.cfg)
+	if !cpmd.Valid() {
 		return nil, fmt.Errorf("PMD of %q is invalid", source.url)
 	}
 	pmd, err := cpmd.Model()
 	if err != nil {
-		// Try again soon.
-		m.keysCache.SetWithExpiration(source.id, keys, holdingPMDsDuration)
 		return nil, fmt.Errorf("re-marshaling failed: %w", err)
 	}
 	base, err := url.Parse(source.url)
 	if err != nil {
 		// XXX: This should not happen.
-		m.keysCache.SetWithExpiration(source.id, keys, holdingPMDsDuration)
 		return nil, fmt.Errorf("invalid PMD url: %q", source.url)
 	}
 	client := source.httpClient(m)
 	defer client.CloseIdleConnections()
+	var (
+		ckeys  []*crypto.Key
+		listed int
+	)
 	for i := range pmd.PGPKeys {
 		key := &pmd.PGPKeys[i]
 		if key.URL == nil {
 			continue
 		}
+		listed++
 		u, err := url.Parse(*key.URL)
 		if err != nil {
 			slog.Warn("Invalid OpenPGP url", "url", *key.URL, "err", err)
@@ -106,19 +220,286 @@ func (m *Manager) openPGPKeys(source *source) (*crypto.KeyRing, error) {
 				"url", u)
 			continue
 		}
-		if err := keys.AddKey(ckey); err != nil {
-			slog.Warn(
-				"Could not add public OpenPGP key to key ring",
-				"url", u)
+		ckeys = append(ckeys, ckey)
+	}
+	if listed > 0 && len(ckeys) == 0 {
+		return nil, fmt.Errorf("loading the OpenPGP keys of %q failed", source.url)
+	}
+	return ckeys, nil
+}
+
+// keysPending returns true if there are pinned keys
+// which are not approved.
+func (s *source) keysPending() bool {
+	return slices.ContainsFunc(s.keys, func(k *pinnedKey) bool { return !k.approved })
+}
+
+// keysUnreviewed returns true if there are pinned keys
+// nobody decided about, yet.
+func (s *source) keysUnreviewed() bool {
+	return slices.ContainsFunc(s.keys, func(k *pinnedKey) bool { return !k.reviewed })
+}
+
+// pinKeys compares the fingerprints of the given PMD keys with the pinned
+// ones of the source. If there are no pinned PMD keys, yet, all keys are
+// trusted on first use. Keys not seen before are pinned as not approved
+// and not reviewed which flags the source to need attention.
+// Returns the approved PMD keys and the approved manually added keys.
+func (m *Manager) pinKeys(ctx context.Context, s *source, ckeys []*crypto.Key) []*crypto.Key {
+	// Trust on first use.
+	tofu := !slices.ContainsFunc(s.keys, func(k *pinnedKey) bool { return k.origin == PMDKeyOrigin })
+
+	var (
+		trusted []*crypto.Key
+		news    []*pinnedKey
+		now     = time.Now().UTC()
+	)
+	for _, ckey := range ckeys {
+		fp := strings.ToLower(ckey.GetFingerprint())
+		if idx := slices.IndexFunc(s.keys, func(k *pinnedKey) bool { return k.fingerprint == fp }); idx != -1 {
+			k := s.keys[idx]
+			if k.origin == PMDKeyOrigin {
+				k.key = ckey
+				if k.approved {
+					trusted = append(trusted, ckey)
+				}
+			}
+			continue
+		}
+		if slices.ContainsFunc(news, func(k *pinnedKey) bool { return k.fingerprint == fp }) {
+			continue
 		}
+		news = append(news, &pinnedKey{
+			fingerprint: fp,
+			approved:    tofu,
+			firstSeen:   now,
+			origin:      PMDKeyOrigin,
+			reviewed:    tofu,
+			key:         ckey,
+		})
 	}
-	m.keysCache.Set(source.id, keys)
-	return keys, nil
+	// Add the manually added keys.
+	for _, k := range s.keys {
+		if k.origin == ManualKeyOrigin && k.approved && k.key != nil {
+			trusted = append(trusted, k.key)
+		}
+	}
+	if len(news) == 0 {
+		return trusted
+	}
+
+	const insertSQL = `INSERT INTO source_keys ` +
+		`(sources_id, fingerprint, approved, first_seen, origin, reviewed) ` +
+		`VALUES ($1, $2, $3, $4, 'pmd', CASE WHEN $5::bool THEN $4 END) ON CONFLICT DO NOTHING`
+	batch := &pgx.Batch{}
+	for _, k := range news {
+		batch.Queue(insertSQL, s.id, k.fingerprint, k.approved, k.firstSeen, k.reviewed)
+	}
+	if err := m.db.Run(
+		ctx,
+		func(rctx context.Context, con *pgxpool.Conn) error {
+			tx, err := con.Begin(rctx)
+			if err != nil {
+				return err
+			}
+			defer tx.Rollback(rctx)
+			if err := tx.SendBatch(rctx, batch).Close(); err != nil {
+				return err
+			}
+			return tx.Commit(rctx)
+		}, 0,
+	); err != nil {
+		slog.Error("Pinning OpenPGP keys failed", "source", s.id, "err", err)
+		return trusted
+	}
+	s.keys = append(s.keys, news...)
+	if tofu {
+		for _, k := range news {
+			trusted = append(trusted, k.key)
+		}
+		return trusted
+	}
+	slog.Warn("OpenPGP keys of source changed", "source", s.id, "name", s.name)
+	return trusted
+}
+
+// Keys returns the pinned OpenPGP keys of a source.
+func (m *Manager) Keys(sourceID int64) ([]KeyInfo, error) {
+	var (
+		keys []KeyInfo
+		err  error
+	)
+	m.inManager(func(m *Manager, _ context.Context) {
+		s := m.findSourceByID(sourceID)
+		if s == nil {
+			err = NoSuchEntryError("no such source")
+			return
+		}
+		keys = s.keyInfos()
+	})
+	return keys, err
+}
+
+// PublicKeys returns the approved public OpenPGP keys of the sources
+// with the given names. Without names the keys of all sources are returned.
+// Keys used by more than one source are returned once.
+func (m *Manager) PublicKeys(names []string) []*crypto.Key {
+	var sources []*source
+	m.inManager(func(m *Manager, _ context.Context) {
+		for _, s := range m.sources {
+			if len(names) == 0 || slices.Contains(names, s.name) {
+				sources = append(sources, s)
+			}
+		}
+	})
+	var (
+		keys []*crypto.Key
+		seen = map[string]bool{}
+	)
+	for _, s := range sources {
+		ring, err := m.openPGPKeys(s)
+		if err != nil {
+			slog.Warn("loading OpenPGP keys failed", "source", s.name, "err", err)
+		}
+		for _, key := range ring.GetKeys() {
+			if fp := key.GetFingerprint(); !seen[fp] {
+				seen[fp] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// UploadKey adds an armored public OpenPGP key to a source.
+func (m *Manager) UploadKey(sourceID int64, armored string) (*KeyInfo, error) {
+	ckey, err := crypto.NewKeyFromArmored(armored)
+	if err != nil {
+		return nil, InvalidArgumentError(fmt.Sprintf("invalid OpenPGP key: %v", err))
+	}
+	if ckey.IsPrivate() {
+		return nil, InvalidArgumentError("not a public OpenPGP key")
+	}
+	if armored, err = ckey.GetArmoredPublicKey(); err != nil {
+		return nil, InvalidArgumentError(fmt.Sprintf("invalid OpenPGP key: %v", err))
+	}
+	var ki *KeyInfo
+	if err := m.asManager(func(m *Manager, ctx context.Context, sourceID int64) error {
+		s := m.findSourceByID(sourceID)
+		if s == nil {
+			return NoSuchEntryError("no such source")
+		}
+		k := &pinnedKey{
+			fingerprint: strings.ToLower(ckey.GetFingerprint()),
+			approved:    true,
+			firstSeen:   time.Now().UTC(),
+			origin:      ManualKeyOrigin,
+			reviewed:    true,
+			key:         ckey,
+		}
+		if slices.ContainsFunc(s.keys, func(p *pinnedKey) bool { return p.fingerprint == k.fingerprint }) {
+			return InvalidArgumentError("key already exists")
+		}
+		const sql = `INSERT INTO source_keys ` +
+			`(sources_id, fingerprint, approved, first_seen, origin, armored, reviewed) ` +
+			`VALUES ($1, $2, $3, $4, 'manual', $5, $4)`
+		if err := m.db.Run(
+			ctx,
+			func(rctx context.Context, con *pgxpool.Conn) error {
+				_, err := con.Exec(rctx, sql,
+					sourceID, k.fingerprint, k.approved, k.firstSeen, armored)
+				return err
+			}, 0,
+		); err != nil {
+			return fmt.Errorf("storing key failed: %w", err)
+		}
+		s.keys = append(s.keys, k)
+		info := k.info()
+		ki = &info
+		// Force reloading the key ring.
+		m.keysCache.Delete(sourceID)
+		return nil
+	}, sourceID); err != nil {
+		return nil, err
+	}
+	return ki, nil
+}
+
+// RemoveKey removes a manually added OpenPGP key from a source.
+func (m *Manager) RemoveKey(sourceID int64, fingerprint string) error {
+	fingerprint = strings.ToLower(fingerprint)
+	return m.asManager(func(m *Manager, ctx context.Context, sourceID int64) error {
+		s := m.findSourceByID(sourceID)
+		if s == nil {
+			return NoSuchEntryError("no such source")
+		}
+		idx := slices.IndexFunc(s.keys, func(k *pinnedKey) bool { return k.fingerprint == fingerprint })
+		if idx == -1 {
+			return NoSuchEntryError("no such key")
+		}
+		if s.keys[idx].origin != ManualKeyOrigin {
+			return InvalidArgumentError("only manually added keys can be removed")
+		}
+		const sql = `DELETE FROM source_keys WHERE sources_id = $1 AND fingerprint = $2`
+		if err := m.db.Run(
+			ctx,
+			func(rctx context.Context, con *pgxpool.Conn) error {
+				_, err := con.Exec(rctx, sql, sourceID, fingerprint)
+				return err
+			}, 0,
+		); err != nil {
+			return fmt.Errorf("removing key failed: %w", err)
+		}
+		s.keys = slices.Delete(s.keys, idx, idx+1)
+		// Force reloading the key ring.
+		m.keysCache.Delete(sourceID)
+		return nil
+	}, sourceID)
+}
+
+// ApproveKey sets the approval of a pinned OpenPGP key of a source.
+func (m *Manager) ApproveKey(sourceID int64, fingerprint string, approved bool) error {
+	fingerprint = strings.ToLower(fingerprint)
+	return m.asManager(func(m *Manager, ctx context.Context, sourceID int64) error {
+		s := m.findSourceByID(sourceID)
+		if s == nil {
+			return NoSuchEntryError("no such source")
+		}
+		idx := slices.IndexFunc(s.keys, func(k *pinnedKey) bool { return k.fingerprint == fingerprint })
+		if idx == -1 {
+			return NoSuchEntryError("no such key")
+		}
+		// Rejecting a pending key is a decision, too.
+		if k := s.keys[idx]; k.approved == approved && k.reviewed {
+			return nil
+		}
+		const sql = `UPDATE source_keys SET (approved, reviewed) = ($1, CURRENT_TIMESTAMP) ` +
+			`WHERE sources_id = $2 AND fingerprint = $3`
+		if err := m.db.Run(
+			ctx,
+			func(rctx context.Context, con *pgxpool.Conn) error {
+				_, err := con.Exec(rctx, sql, approved, sourceID, fingerprint)
+				return err
+			}, 0,
+		); err != nil {
+			return fmt.Errorf("updating key approval failed: %w", err)
+		}
+		s.keys[idx].approved = approved
+		s.keys[idx].reviewed = true
+		// Force reloading the key ring.
+		m.keysCache.Delete(sourceID)
+		return nil
+	}, sourceID)
 }
 
 // loadSignature loads an ascii armored OpenPGP signature file from a given url.
-func (s *source) loadSignature(client *http.Client, m *Manager, u *url.URL) (*crypto.PGPSignature, []byte, error) {
-	resp, err := s.httpGet(client, m, u.String())
+func (s *source) loadSignature(
+	client *http.Client,
+	m *Manager,
+	o *origins,
+	u *url.URL,
+) (*crypto.PGPSignature, []byte, error) {
+	resp, _, err := s.httpGetFailover(client, m, o, u.String())
 	if err != nil {
 		return nil, nil, err
 	}
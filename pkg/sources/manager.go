@@ -56,6 +56,9 @@ func (InvalidArgumentError) Is(target error) bool {
 	return ok
 }
 
+// sourceExists is returned by [Manager.AddSource] if the name is already taken.
+const sourceExists InvalidArgumentError = "source already exists"
+
 const (
 	// refreshDuration is the fallback duration for feeds to be checked for refresh.
 	refreshDuration = time.Minute
@@ -117,6 +120,7 @@ type SourceInfo struct {
 	ID                      int64
 	Name                    string
 	URL                     string
+	Mirrors                 []string
 	Active                  bool
 	Attention               bool
 	Status                  []string
@@ -131,6 +135,7 @@ type SourceInfo struct {
 	HasClientCertPublic     bool
 	HasClientCertPrivate    bool
 	HasClientCertPassphrase bool
+	Keys                    []KeyInfo
 	Stats                   *Stats
 }
 
@@ -560,9 +565,10 @@ func (m *Manager) Source(id int64, stats bool) *SourceInfo {
 			ID:                      s.id,
 			Name:                    s.name,
 			URL:                     s.url,
+			Mirrors:                 s.mirrors,
 			Active:                  s.active,
-			Attention:               s.checksumAck.Before(s.checksumUpdated),
-			Status:                  s.status,
+			Attention:               s.attention(),
+			Status:                  s.statusMessages(),
 			Rate:                    s.rate,
 			Slots:                   s.slots,
 			Headers:                 s.headers,
@@ -574,6 +580,7 @@ func (m *Manager) Source(id int64, stats bool) *SourceInfo {
 			HasClientCertPublic:     s.clientCertPublic != nil,
 			HasClientCertPrivate:    s.clientCertPrivate != nil,
 			HasClientCertPassphrase: s.clientCertPassphrase != nil,
+			Keys:                    s.keyInfos(),
 			Stats:                   st,
 		}
 	}
@@ -648,7 +655,7 @@ func (m *Manager) Subscriptions(urls []string) []SourceSubscriptions {
 			}
 			subs = append(subs, SourceSubscriptions{
 				URL:           url,
-				Available:     availableFeeds(pmd),
+				Available:     availableFeeds(pmd, rps.services(url)...),
 				Subscriptions: subscriptions,
 			})
 		}
@@ -671,8 +678,9 @@ func (m *Manager) Sources(fn func(*SourceInfo), stats bool) {
 				ID:                      s.id,
 				Name:                    s.name,
 				URL:                     s.url,
+				Mirrors:                 s.mirrors,
 				Active:                  s.active,
-				Attention:               s.checksumAck.Before(s.checksumUpdated),
+				Attention:               s.attention(),
 				Rate:                    s.rate,
 				Slots:                   s.slots,
 				Headers:                 s.headers,
@@ -980,6 +988,7 @@ func (m *Manager) asManager(fn func(*Manager, context.Context, int64) error, id
 func (m *Manager) AddSource(
 	name string,
 	url string,
+	mirrors []string,
 	rate *float64,
 	slots *int,
 	headers []string,
@@ -1005,6 +1014,7 @@ func (m *Manager) AddSource(
 	s := &source{
 		name:                 name,
 		url:                  url,
+		mirrors:              mirrors,
 		rate:                 rate,
 		slots:                slots,
 		headers:              headers,
@@ -1034,19 +1044,19 @@ func (m *Manager) AddSource(
 	}
 	m.fns <- func(m *Manager, ctx context.Context) {
 		if m.findSourceByName(name) != nil {
-			errCh <- InvalidArgumentError("source already exists")
+			errCh <- sourceExists
 			return
 		}
 		const sql = `INSERT INTO sources (` +
 			`name, url, rate, slots, headers, ` +
 			`strict_mode, secure, signature_check, age, ignore_patterns, ` +
 			`client_cert_public, client_cert_private, client_cert_passphrase, ` +
-			`checksum, checksum_ack, checksum_updated) ` +
+			`checksum, checksum_ack, checksum_updated, mirrors) ` +
 			`VALUES (` +
 			`$1, $2, $3, $4, $5, ` +
 			`$6, $7, $8, $9, $10, ` +
 			`$11, $12, $13, ` +
-			`$14, $15, $16) ` +
+			`$14, $15, $16, $17) ` +
 			`RETURNING id`
 		if err := m.db.Run(
 			ctx,
@@ -1055,7 +1065,7 @@ func (m *Manager) AddSource(
 					name, url, rate, slots, headers,
 					strictMode, secure, signatureCheck, age, ignorePatterns,
 					clientCertPublic, clientCertPrivate, clientCertPassphrase,
-					s.checksum, s.checksumAck, s.checksumUpdated,
+					s.checksum, s.checksumAck, s.checksumUpdated, mirrors,
 				).Scan(&s.id)
 			}, 0,
 		); err != nil {
@@ -1091,12 +1101,14 @@ func (m *Manager) AddFeed(
 			errCh <- InvalidArgumentError("label already exists")
 			return
 		}
-		pmd, err := m.PMD(s.url).Model()
+		cpmd := m.PMD(s.url)
+		pmd, err := cpmd.Model()
 		if err != nil {
 			errCh <- err
 			return
 		}
-		rolie := isROLIEFeed(pmd, url.String())
+		rolie := isROLIEFeed(pmd, url.String()) ||
+			slices.Contains(cpmd.ServiceFeeds(m.cfg), url.String())
 		if !rolie && !isDirectoryFeed(pmd, url.String()) {
 			errCh <- InvalidArgumentError("feed is neither ROLIE nor directory based")
 			return
@@ -1329,6 +1341,21 @@ func (su *SourceUpdater) UpdateHeaders(headers []string) error {
 	return nil
 }
 
+// UpdateMirrors requests an update of the mirrors.
+func (su *SourceUpdater) UpdateMirrors(mirrors []string) error {
+	if slices.Equal(mirrors, su.updatable.mirrors) {
+		return nil
+	}
+	for _, mirror := range mirrors {
+		if err := ValidateMirror(mirror); err != nil {
+			return err
+		}
+	}
+	mirrors = clone(mirrors)
+	su.addChange(func(s *source) { s.mirrors = mirrors }, "mirrors", mirrors)
+	return nil
+}
+
 // UpdateStrictMode requests an update on strictMode.
 func (su *SourceUpdater) UpdateStrictMode(strictMode *bool) error {
 	if su.updatable.strictMode == nil && strictMode == nil {
@@ -1575,7 +1602,7 @@ func (m *Manager) UpdateFeed(
 func (m *Manager) AttentionSources(all bool, fn func(id int64, name string)) {
 	m.inManager(func(m *Manager, _ context.Context) {
 		for _, s := range m.sources {
-			if (all || s.active) && s.checksumAck.Before(s.checksumUpdated) {
+			if (all || s.active) && s.attention() {
 				fn(s.id, s.name)
 			}
 		}
@@ -0,0 +1,153 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package sources
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// origins are the base URLs a source can be fetched from.
+// Mirrors are expected to keep the directory layout of the provider
+// below the directory of their PMD like CSAF aggregators do.
+type origins struct {
+	primary     string
+	mirrorBases []string
+}
+
+// ValidateMirror checks if a mirror URL is usable.
+func ValidateMirror(mirror string) error {
+	u, err := url.Parse(mirror)
+	if err != nil {
+		return InvalidArgumentError(fmt.Sprintf("invalid mirror %q: %v", mirror, err))
+	}
+	if u.Scheme != "https" || u.Host == "" || !strings.HasSuffix(u.Path, ".json") {
+		return InvalidArgumentError(
+			fmt.Sprintf("mirror %q is not an https URL of a PMD", mirror))
+	}
+	return nil
+}
+
+// pmdBase returns the directory of a PMD URL including the trailing slash.
+// For bare domains the well-known directory is assumed.
+func pmdBase(pmd string) string {
+	if !strings.Contains(pmd, "://") {
+		return "https://" + strings.TrimSuffix(pmd, "/") + "/.well-known/csaf/"
+	}
+	if idx := strings.LastIndexByte(pmd, '/'); idx >= 0 {
+		return pmd[:idx+1]
+	}
+	return pmd
+}
+
+// origins returns the base URLs of the source and its mirrors.
+// Must be called in the manager.
+func (s *source) origins() *origins {
+	if len(s.mirrors) == 0 {
+		return nil
+	}
+	o := &origins{
+		primary:     pmdBase(s.url),
+		mirrorBases: make([]string, len(s.mirrors)),
+	}
+	for i, mirror := range s.mirrors {
+		o.mirrorBases[i] = pmdBase(mirror)
+	}
+	return o
+}
+
+// mirrors returns the base URLs of the mirrors.
+func (o *origins) mirrors() []string {
+	if o == nil {
+		return nil
+	}
+	return o.mirrorBases
+}
+
+// rebase replaces the prefix from of u with to.
+func rebase(u, from, to string) (string, bool) {
+	if rest, ok := strings.CutPrefix(u, from); ok {
+		return to + rest, true
+	}
+	return u, false
+}
+
+// alternatives returns the URLs of the mirrors for a URL of the source.
+func (o *origins) alternatives(u string) []string {
+	if o == nil {
+		return nil
+	}
+	var alts []string
+	for _, mirror := range o.mirrorBases {
+		if alt, ok := rebase(u, o.primary, mirror); ok {
+			alts = append(alts, alt)
+		}
+	}
+	return alts
+}
+
+// original maps a URL served by the mirror with the given base
+// back to the URL of the source.
+func (o *origins) original(u *url.URL, mirror string) *url.URL {
+	if u == nil {
+		return nil
+	}
+	orig, ok := rebase(u.String(), mirror, o.primary)
+	if !ok {
+		return u
+	}
+	if ou, err := url.Parse(orig); err == nil {
+		return ou
+	}
+	return u
+}
+
+// unavailable checks if the result of a request tells that
+// the server is not available, in contrast to answering that
+// the requested file does not exist or is not accessible.
+func unavailable(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// httpGetFailover fetches a URL from the source. If the source
+// is not available the mirrors are tried in order. Other answers
+// of the source, e.g. that the file does not exist, are final.
+// It returns the response and the URL which served it.
+// If all mirrors fail too the result of the source is returned.
+func (s *source) httpGetFailover(
+	client *http.Client,
+	m *Manager,
+	o *origins,
+	u string,
+) (*http.Response, string, error) {
+	resp, err := s.httpGet(client, m, u)
+	if !unavailable(resp, err) {
+		return resp, u, nil
+	}
+	for _, alt := range o.alternatives(u) {
+		aresp, aerr := s.httpGet(client, m, alt)
+		if aerr != nil {
+			slog.Debug("fetching from mirror failed", "url", alt, "err", aerr)
+			continue
+		}
+		if aresp.StatusCode != http.StatusOK {
+			aresp.Body.Close()
+			slog.Debug("fetching from mirror failed", "url", alt, "status", aresp.StatusCode)
+			continue
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return aresp, alt, nil
+	}
+	return resp, u, err
+}
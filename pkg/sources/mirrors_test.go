@@ -0,0 +1,136 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package sources
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestOrigins(t *testing.T) {
+	s := source{
+		url: "https://provider.example/.well-known/csaf/provider-metadata.json",
+		mirrors: []string{
+			"https://aggregator.example/mirror/provider/provider-metadata.json",
+			"https://other.example/provider-metadata.json",
+		},
+	}
+	o := s.origins()
+	const doc = "https://provider.example/.well-known/csaf/white/2026/doc.json"
+	alts := o.alternatives(doc)
+	expected := []string{
+		"https://aggregator.example/mirror/provider/white/2026/doc.json",
+		"https://other.example/white/2026/doc.json",
+	}
+	if len(alts) != len(expected) {
+		t.Fatalf("got %d alternatives, expected %d", len(alts), len(expected))
+	}
+	for i, alt := range alts {
+		if alt != expected[i] {
+			t.Errorf("alternative %d: got %q, expected %q", i, alt, expected[i])
+		}
+		u, err := url.Parse(alt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if orig := o.original(u, o.mirrors()[i]).String(); orig != doc {
+			t.Errorf("original of %q: got %q, expected %q", alt, orig, doc)
+		}
+	}
+	if alts := o.alternatives("https://elsewhere.example/doc.json"); len(alts) != 0 {
+		t.Errorf("unexpected alternatives %v for foreign URL", alts)
+	}
+	if (&source{url: s.url}).origins() != nil {
+		t.Error("origins of source without mirrors")
+	}
+}
+
+func TestHTTPGetFailover(t *testing.T) {
+	const content = "mirrored"
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/doc.json" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, content)
+	}))
+	defer mirror.Close()
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+
+	status := func(code int) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(code)
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	m := &Manager{fns: make(chan func(*Manager, context.Context))}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for {
+			select {
+			case fn := <-m.fns:
+				fn(m, ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for _, x := range []struct {
+		name     string
+		primary  string
+		mirrors  []string
+		status   int
+		mirrored bool
+	}{
+		{"server error", status(http.StatusBadGateway).URL, []string{mirror.URL}, http.StatusOK, true},
+		{"not reachable", down.URL, []string{mirror.URL}, http.StatusOK, true},
+		{"not found", status(http.StatusNotFound).URL, []string{mirror.URL}, http.StatusNotFound, false},
+		{"forbidden", status(http.StatusForbidden).URL, []string{mirror.URL}, http.StatusForbidden, false},
+		{"next mirror", status(http.StatusServiceUnavailable).URL, []string{missing.URL, mirror.URL}, http.StatusOK, true},
+		{"all failing", status(http.StatusInternalServerError).URL, []string{missing.URL}, http.StatusInternalServerError, false},
+	} {
+		t.Run(x.name, func(t *testing.T) {
+			o := &origins{primary: x.primary + "/"}
+			for _, mirror := range x.mirrors {
+				o.mirrorBases = append(o.mirrorBases, mirror+"/")
+			}
+			u := x.primary + "/doc.json"
+			resp, servedBy, err := (&source{}).httpGetFailover(http.DefaultClient, m, o, u)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != x.status {
+				t.Errorf("got status %d, expected %d", resp.StatusCode, x.status)
+			}
+			if mirrored := servedBy != u; mirrored != x.mirrored {
+				t.Errorf("served by %q", servedBy)
+			}
+			if x.mirrored {
+				if servedBy != mirror.URL+"/doc.json" {
+					t.Errorf("served by %q, expected the mirror", servedBy)
+				}
+				if data, _ := io.ReadAll(resp.Body); string(data) != content {
+					t.Errorf("got %q, expected %q", data, content)
+				}
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"slices"
 	"sync"
 	"time"
@@ -33,6 +34,10 @@ type CachedProviderMetadata struct {
 	Loaded  *csaf.LoadedProviderMetadata
 	modelMu sync.Mutex
 	model   *csaf.ProviderMetadata
+
+	servicesMu     sync.Mutex
+	servicesLoaded bool
+	services       []string
 }
 
 type pmdCache struct {
@@ -40,8 +45,9 @@ type pmdCache struct {
 }
 
 type resolvedPMD struct {
-	url string
-	pmd *csaf.ProviderMetadata
+	url      string
+	pmd      *csaf.ProviderMetadata
+	services []string
 }
 
 type resolvedPMDs []resolvedPMD
@@ -52,12 +58,9 @@ func newPMDCache() *pmdCache {
 	}
 }
 
-func (pc *pmdCache) pmd(url string, cfg *config.Config) *CachedProviderMetadata {
-
-	if cpmd, ok := pc.Get(url); ok {
-		return cpmd
-	}
-
+// newPMDClient creates the HTTP client used to fetch PMDs and
+// the documents referenced by them.
+func newPMDClient(cfg *config.Config) util.Client {
 	header := http.Header{}
 	header.Add("User-Agent", UserAgent)
 
@@ -81,7 +84,16 @@ func (pc *pmdCache) pmd(url string, cfg *config.Config) *CachedProviderMetadata
 			},
 		}
 	}
-	pmdLoader := csaf.NewProviderMetadataLoader(client)
+	return client
+}
+
+func (pc *pmdCache) pmd(url string, cfg *config.Config) *CachedProviderMetadata {
+
+	if cpmd, ok := pc.Get(url); ok {
+		return cpmd
+	}
+
+	pmdLoader := csaf.NewProviderMetadataLoader(newPMDClient(cfg))
 	lpmd := pmdLoader.Load(url)
 	cpmd := &CachedProviderMetadata{Loaded: lpmd}
 	pc.Set(url, cpmd)
@@ -113,8 +125,135 @@ func (cpmd *CachedProviderMetadata) Model() (*csaf.ProviderMetadata, error) {
 	return model, nil
 }
 
+// ServiceFeeds returns the URLs of the ROLIE feeds listed in the
+// ROLIE service documents referenced by the PMD.
+// The service documents are only fetched once per cached PMD.
+func (cpmd *CachedProviderMetadata) ServiceFeeds(cfg *config.Config) []string {
+	pmd, err := cpmd.Model()
+	if err != nil {
+		return nil
+	}
+	cpmd.servicesMu.Lock()
+	defer cpmd.servicesMu.Unlock()
+	if cpmd.servicesLoaded {
+		return cpmd.services
+	}
+	cpmd.servicesLoaded = true
+	var client util.Client
+	for i := range pmd.Distributions {
+		d := pmd.Distributions[i]
+		if d.Rolie == nil {
+			continue
+		}
+		for _, service := range d.Rolie.Services {
+			if client == nil {
+				client = newPMDClient(cfg)
+			}
+			feeds, err := loadServiceFeeds(client, string(service))
+			if err != nil {
+				slog.Warn("loading ROLIE service document failed",
+					"url", service, "err", err)
+				continue
+			}
+			for _, feed := range feeds {
+				if !slices.Contains(cpmd.services, feed) {
+					cpmd.services = append(cpmd.services, feed)
+				}
+			}
+		}
+	}
+	return cpmd.services
+}
+
+// Expanded returns the PMD document with the feeds from the ROLIE
+// service documents added to the ROLIE distribution referencing them.
+// The original document is returned if there is nothing to add.
+func (cpmd *CachedProviderMetadata) Expanded(cfg *config.Config) (any, error) {
+	pmd, err := cpmd.Model()
+	if err != nil {
+		return nil, err
+	}
+	known := availableFeeds(pmd)
+	var extra []string
+	for _, feed := range cpmd.ServiceFeeds(cfg) {
+		if !slices.Contains(known, feed) {
+			extra = append(extra, feed)
+		}
+	}
+	if len(extra) == 0 {
+		return cpmd.Loaded.Document, nil
+	}
+	// Don't modify the cached model.
+	expanded := new(csaf.ProviderMetadata)
+	if err := util.ReMarshalJSON(expanded, pmd); err != nil {
+		return nil, fmt.Errorf("copying PMD failed: %w", err)
+	}
+	idx := slices.IndexFunc(expanded.Distributions, func(d csaf.Distribution) bool {
+		return d.Rolie != nil && len(d.Rolie.Services) > 0
+	})
+	if idx == -1 {
+		return cpmd.Loaded.Document, nil
+	}
+	rolie := expanded.Distributions[idx].Rolie
+	// The service documents don't tell the TLP of the feeds.
+	unlabeled := csaf.TLPLabel(csaf.TLPLabelUnlabeled)
+	for _, feed := range extra {
+		u := csaf.JSONURL(feed)
+		rolie.Feeds = append(rolie.Feeds, csaf.Feed{
+			Summary:  "Feed from ROLIE service document",
+			TLPLabel: &unlabeled,
+			URL:      &u,
+		})
+	}
+	return expanded, nil
+}
+
+// loadServiceFeeds fetches a ROLIE service document and returns
+// the URLs of the feeds listed in its collections.
+func loadServiceFeeds(client util.Client, serviceURL string) ([]string, error) {
+	base, err := url.Parse(serviceURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service URL: %w", err)
+	}
+	resp, err := client.Get(serviceURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	rsd, err := csaf.LoadROLIEServiceDocument(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return serviceFeeds(base, rsd), nil
+}
+
+// serviceFeeds extracts the absolute URLs of the feeds listed in a ROLIE service document.
+func serviceFeeds(base *url.URL, rsd *csaf.ROLIEServiceDocument) []string {
+	var feeds []string
+	for _, ws := range rsd.Service.Workspace {
+		for _, coll := range ws.Collection {
+			if coll.HRef == "" {
+				continue
+			}
+			u, err := url.Parse(coll.HRef)
+			if err != nil {
+				slog.Warn("invalid collection href", "href", coll.HRef)
+				continue
+			}
+			if feed := base.ResolveReference(u).String(); !slices.Contains(feeds, feed) {
+				feeds = append(feeds, feed)
+			}
+		}
+	}
+	return feeds
+}
+
 // availableFeeds returns a list of the feeds available for the given provider.
-func availableFeeds(pmd *csaf.ProviderMetadata) []string {
+// The feeds expanded from ROLIE service documents are passed as extra feeds.
+func availableFeeds(pmd *csaf.ProviderMetadata, extra ...string) []string {
 	var feeds []string
 	add := func(feed string) {
 		if !slices.Contains(feeds, feed) {
@@ -140,6 +279,9 @@ func availableFeeds(pmd *csaf.ProviderMetadata) []string {
 			add(d.DirectoryURL)
 		}
 	}
+	for _, feed := range extra {
+		add(feed)
+	}
 	return feeds
 }
 
@@ -154,13 +296,17 @@ func checksumPMD(pmd *csaf.ProviderMetadata) []byte {
 	return hash.Sum(nil)
 }
 
-// isROLIEFeed checks if the given url leads to a ROLIE feed.
+// isROLIEFeed checks if the given url leads to a ROLIE feed
+// or a ROLIE service document listing feeds.
 func isROLIEFeed(pmd *csaf.ProviderMetadata, url string) bool {
 	for i := range pmd.Distributions {
 		d := pmd.Distributions[i]
 		if d.Rolie == nil {
 			continue
 		}
+		if slices.Contains(d.Rolie.Services, csaf.JSONURL(url)) {
+			return true
+		}
 		feeds := d.Rolie.Feeds
 		for j := range feeds {
 			if f := &feeds[j]; f.URL != nil && string(*f.URL) == url {
@@ -214,6 +360,7 @@ func (rps resolvedPMDs) resolve(cache *pmdCache, cfg *config.Config) {
 				continue
 			}
 			tr.pmd = pmd
+			tr.services = cpmd.ServiceFeeds(cfg)
 		}
 	}
 	for range max(1, min(len(rps), numURLResolvers)) {
@@ -233,3 +380,11 @@ func (rps resolvedPMDs) pmd(url string) *csaf.ProviderMetadata {
 	}
 	return nil
 }
+
+// services returns the feeds expanded from the ROLIE service documents of a PMD.
+func (rps resolvedPMDs) services(url string) []string {
+	if idx := slices.IndexFunc(rps, func(rp resolvedPMD) bool { return rp.url == url }); idx >= 0 {
+		return rps[idx].services
+	}
+	return nil
+}
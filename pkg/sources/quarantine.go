@@ -0,0 +1,200 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ISDuBA/ISDuBA/pkg/models"
+)
+
+// quarantined holds a document rejected by the strict mode.
+type quarantined struct {
+	doc       any
+	data      []byte
+	signature []byte
+	filename  string
+	status    dlStatus
+	report    *models.ValidationReport
+}
+
+// quarantineUpdates are the columns replaced if a document
+// is downloaded again from the same location.
+var quarantineUpdates = []string{
+	"updated",
+	"download_failed",
+	"filename_failed",
+	"schema_failed",
+	"remote_failed",
+	"checksum_failed",
+	"signature_failed",
+	"duplicate_failed",
+	"validation_report",
+	"document",
+	"original",
+	"signature",
+	"filename",
+}
+
+// quarantine stores a document rejected by the strict mode so that
+// it can be inspected and released later. A document downloaded
+// again from the same location replaces the old one.
+func (l *location) quarantine(ctx context.Context, tx pgx.Tx, f *feed, q *quarantined) error {
+	i := q.inserter(f.id, l.doc.String(), l.updated)
+	_, err := tx.Exec(ctx, quarantineUpsertSQL(i), i.values...)
+	return err
+}
+
+// inserter returns the columns and values of a quarantined document.
+func (q *quarantined) inserter(feedID int64, url string, updated time.Time) *inserter {
+	var i inserter
+	i.add("feeds_id", feedID)
+	i.add("url", url)
+	i.add("updated", updated)
+	q.status.toInserter(&i)
+	i.add("validation_report", q.report)
+	i.add("document", q.doc)
+	i.add("original", q.data)
+	i.add("signature", q.signature)
+	i.add("filename", q.filename)
+	return &i
+}
+
+// quarantineUpsertSQL returns the statement to insert a quarantined
+// document or to replace the one from the same location.
+func quarantineUpsertSQL(i *inserter) string {
+	var b strings.Builder
+	b.WriteString(i.sql("quarantine"))
+	b.WriteString(` ON CONFLICT (url, feeds_id) DO UPDATE SET time = CURRENT_TIMESTAMP`)
+	for _, column := range quarantineUpdates {
+		fmt.Fprintf(&b, `, %[1]s = EXCLUDED.%[1]s`, column)
+	}
+	return b.String()
+}
+
+// clearQuarantine removes a quarantined document of a location
+// once the location was downloaded successfully.
+func (f *feed) clearQuarantine(l *location) func(context.Context, pgx.Tx, int64, bool) error {
+	return func(ctx context.Context, tx pgx.Tx, _ int64, _ bool) error {
+		if f.invalid.Load() {
+			return nil
+		}
+		const deleteSQL = `DELETE FROM quarantine WHERE url = $1 AND feeds_id = $2`
+		_, err := tx.Exec(ctx, deleteSQL, l.doc.String(), f.id)
+		return err
+	}
+}
+
+// storeChange records the last change of a location of a feed.
+func storeChange(ctx context.Context, tx pgx.Tx, url string, feedID int64, updated time.Time) error {
+	const updatedSQL = `INSERT INTO changes (url, feeds_id, time) ` +
+		`VALUES ($1, $2, $3) ` +
+		`ON CONFLICT (url, feeds_id) DO ` +
+		`UPDATE SET time = $3`
+	_, err := tx.Exec(ctx, updatedSQL, url, feedID, updated)
+	return err
+}
+
+// Release imports a quarantined document into the database
+// and removes it from the quarantine. The document is removed
+// from the quarantine, too, if it is already in the database.
+// The publishers and TLPs are the ones the releasing user is allowed to import.
+func (m *Manager) Release(
+	ctx context.Context,
+	id int64,
+	actor *string,
+	pstlps models.PublishersTLPs,
+) (int64, error) {
+	const selectSQL = `SELECT feeds_id, url, updated, original, signature, filename, validation_report ` +
+		`FROM quarantine WHERE id = $1`
+	var (
+		feedID    int64
+		url       string
+		updated   time.Time
+		original  []byte
+		signature []byte
+		filename  string
+		report    *models.ValidationReport
+	)
+	if err := m.db.Run(ctx, func(rctx context.Context, conn *pgxpool.Conn) error {
+		return conn.QueryRow(rctx, selectSQL, id).Scan(
+			&feedID, &url, &updated, &original, &signature, &filename, &report)
+	}, 0); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, NoSuchEntryError("no such quarantined document")
+		}
+		return 0, fmt.Errorf("loading quarantined document failed: %w", err)
+	}
+
+	var doc any
+	if err := json.Unmarshal(original, &doc); err != nil {
+		return 0, fmt.Errorf("decoding quarantined document failed: %w", err)
+	}
+
+	// The failures are already counted when the document was quarantined.
+	storeStats := func(ctx context.Context, tx pgx.Tx, docID int64, duplicate bool) error {
+		if duplicate {
+			return nil
+		}
+		const insertSQL = `INSERT INTO downloads ` +
+			`(documents_id, feeds_id, validation_report) VALUES ($1, $2, $3)`
+		_, err := tx.Exec(ctx, insertSQL, docID, feedID, report)
+		return err
+	}
+
+	storeSignature := func(ctx context.Context, tx pgx.Tx, docID int64, duplicate bool) error {
+		if duplicate {
+			return nil
+		}
+		const updateSQL = `UPDATE documents ` +
+			`SET (signature, filename) = ($1, $2) ` +
+			`WHERE id = $3`
+		_, err := tx.Exec(ctx, updateSQL, signature, filename, docID)
+		return err
+	}
+
+	// Don't download the released document again.
+	storeLastChange := func(ctx context.Context, tx pgx.Tx, _ int64, _ bool) error {
+		return storeChange(ctx, tx, url, feedID, updated)
+	}
+
+	remove := func(ctx context.Context, tx pgx.Tx, _ int64, _ bool) error {
+		const deleteSQL = `DELETE FROM quarantine WHERE id = $1`
+		tag, err := tx.Exec(ctx, deleteSQL, id)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() != 1 {
+			return NoSuchEntryError("no such quarantined document")
+		}
+		return nil
+	}
+
+	var docID int64
+	err := m.db.Run(ctx, func(rctx context.Context, conn *pgxpool.Conn) error {
+		var err error
+		docID, err = models.ImportDocumentData(
+			rctx, conn,
+			doc, original,
+			actor,
+			pstlps,
+			models.ChainInTx(storeStats, storeSignature, storeLastChange, remove),
+			false)
+		return err
+	}, 0)
+	return docID, err
+}
@@ -0,0 +1,54 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package sources
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQuarantineUpsert(t *testing.T) {
+	q := quarantined{status: schemaValidationFailed | signatureFailed}
+	i := q.inserter(1, "https://example.com/doc.json", time.Now())
+	if len(i.keys) != len(i.values) {
+		t.Fatalf("%d columns but %d values", len(i.keys), len(i.values))
+	}
+	// All inserted columns but the location are replaced.
+	for _, key := range i.keys {
+		if key == "feeds_id" || key == "url" {
+			if slices.Contains(quarantineUpdates, key) {
+				t.Errorf("location column %q replaced", key)
+			}
+			continue
+		}
+		if !slices.Contains(quarantineUpdates, key) {
+			t.Errorf("column %q not replaced", key)
+		}
+	}
+	for _, column := range quarantineUpdates {
+		if !slices.Contains(i.keys, column) {
+			t.Errorf("replaced column %q not inserted", column)
+		}
+	}
+	sql := quarantineUpsertSQL(i)
+	for _, part := range []string{
+		"ON CONFLICT (url, feeds_id) DO UPDATE SET time = CURRENT_TIMESTAMP",
+		"schema_failed = EXCLUDED.schema_failed",
+		"original = EXCLUDED.original",
+	} {
+		if !strings.Contains(sql, part) {
+			t.Errorf("%q missing in %s", part, sql)
+		}
+	}
+	if strings.Contains(sql, "url = EXCLUDED") || strings.Contains(sql, "feeds_id = EXCLUDED") {
+		t.Errorf("location replaced in %s", sql)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
@@ -69,6 +70,13 @@ type feed struct {
 	refreshBlocked bool
 	lastETag       string
 	lastModified   time.Time
+	// indexTXT is set if a directory based feed had no changes.csv
+	// the last time and index.txt was used instead.
+	// The tags belong to the index.txt then.
+	indexTXT bool
+	// indexRecheck is the time the already stored entries
+	// of an index.txt were checked for changes the last time.
+	indexRecheck time.Time
 }
 
 type ignorePatterns []*regexp.Regexp
@@ -77,10 +85,13 @@ type source struct {
 	id        int64
 	name      string
 	url       string
+	mirrors   []string
 	active    bool
 	feeds     []*feed
 	usedSlots int
 	status    []string
+	// subscription tells how the source was created automatically.
+	subscription string
 
 	rate           *float64
 	limiter        *rate.Limiter
@@ -100,6 +111,8 @@ type source struct {
 	checksum        []byte
 	checksumAck     time.Time
 	checksumUpdated time.Time
+
+	keys []*pinnedKey
 }
 
 // ignore returns true if the given url should be ignored.
@@ -192,32 +205,86 @@ func (f *feed) removeOutdatedWaiting(candidates []location) {
 func (f *feed) resetIndexTags() {
 	f.lastETag = ""
 	f.lastModified = time.Time{}
+	f.indexTXT = false
+	f.indexRecheck = time.Time{}
 }
 
 // fetchIndex fetches the content of the feed index.
+// Directory based feeds fall back to index.txt if there is no changes.csv.
 func (f *feed) fetchIndex(m *Manager, fn func([]location, error)) {
 	// Prevent stacked calling
 	f.refreshBlocked = true
 
-	indexURL := f.url.String()
-	if !f.rolie {
-		var err error
-		if indexURL, err = url.JoinPath(indexURL, "changes.csv"); err != nil {
-			fn(nil, err)
-			return
+	newRequest := func(index string, conditional bool) (*http.Request, error) {
+		indexURL := f.url.String()
+		if !f.rolie {
+			var err error
+			if indexURL, err = url.JoinPath(indexURL, index); err != nil {
+				return nil, err
+			}
+		}
+		slog.Debug("fetching index", "url", indexURL, "rolie", f.rolie)
+		req, err := http.NewRequest(http.MethodGet, indexURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !conditional {
+			return req, nil
 		}
+		if f.lastETag != "" {
+			req.Header.Add("If-None-Match", f.lastETag)
+		}
+		if !f.lastModified.IsZero() {
+			req.Header.Add("If-Modified-Since", f.lastModified.Format(http.TimeFormat))
+		}
+		return req, nil
 	}
-	slog.Debug("fetching index", "url", indexURL, "rolie", f.rolie)
-	req, err := http.NewRequest(http.MethodGet, indexURL, nil)
+	// Directory based feeds look for changes.csv on every refresh
+	// as a provider may add it later. The tags only apply to the
+	// index fetched the last time.
+	req, err := newRequest("changes.csv", !f.indexTXT)
 	if err != nil {
 		fn(nil, err)
 		return
 	}
-	if f.lastETag != "" {
-		req.Header.Add("If-None-Match", f.lastETag)
+	// The stored entries of an index.txt are only checked for
+	// changes from time to time as this needs a request per entry.
+	recheck := !f.rolie && time.Since(f.indexRecheck) >= indexRecheckInterval
+	// Prepare the fallback beforehand as the feed is owned by the manager.
+	var fallback *http.Request
+	if !f.rolie {
+		// A recheck needs the index.txt even if it did not change.
+		if fallback, err = newRequest("index.txt", f.indexTXT && !recheck); err != nil {
+			fn(nil, err)
+			return
+		}
 	}
-	if !f.lastModified.IsZero() {
-		req.Header.Add("If-Modified-Since", f.lastModified.Format(http.TimeFormat))
+	// Prepare the requests to the mirrors in case the source fails.
+	type mirrorRequest struct {
+		base     string
+		req      *http.Request
+		fallback *http.Request
+	}
+	var mirrorRequests []mirrorRequest
+	origins := f.source.origins()
+	for _, mirror := range origins.mirrors() {
+		indexURL, ok := rebase(req.URL.String(), origins.primary, mirror)
+		if !ok {
+			continue
+		}
+		mr := mirrorRequest{base: mirror}
+		if mr.req, err = http.NewRequest(http.MethodGet, indexURL, nil); err != nil {
+			fn(nil, err)
+			return
+		}
+		if fallback != nil {
+			fallbackURL, _ := rebase(fallback.URL.String(), origins.primary, mirror)
+			if mr.fallback, err = http.NewRequest(http.MethodGet, fallbackURL, nil); err != nil {
+				fn(nil, err)
+				return
+			}
+		}
+		mirrorRequests = append(mirrorRequests, mr)
 	}
 	client := f.source.httpClient(m)
 	// Copy relevant data to avoid races.
@@ -226,7 +293,22 @@ func (f *feed) fetchIndex(m *Manager, fn func([]location, error)) {
 		age:            f.source.age,
 		ignorePatterns: f.source.ignorePatterns,
 		sameOrNewer:    f.sameOrNewer(),
+		fetch: func(u *url.URL) (io.ReadCloser, error) {
+			resp, err := f.source.httpGet(client, m, u.String())
+			if err != nil {
+				return nil, err
+			}
+			if resp.StatusCode != http.StatusOK {
+				resp.Body.Close()
+				return nil, fmt.Errorf("status code %d", resp.StatusCode)
+			}
+			return resp.Body, nil
+		},
+		probe: func(u *url.URL) (time.Time, error) {
+			return f.source.lastModified(client, m, u)
+		},
 	}
+	feedID := f.id
 	// Do the actual fetching async.
 	go func() {
 		defer func() {
@@ -234,7 +316,42 @@ func (f *feed) fetchIndex(m *Manager, fn func([]location, error)) {
 			// Re-enable refreshing
 			m.fns <- func(*Manager, context.Context) { f.refreshBlocked = false }
 		}()
-		resp, err := f.source.doRequest(client, m, req)
+		fetch := func(req, fallback *http.Request) (*http.Response, bool, error) {
+			resp, err := f.source.doRequest(client, m, req)
+			if err != nil {
+				return nil, false, err
+			}
+			// Some directory based providers only offer an index.txt.
+			if fallback != nil && resp.StatusCode == http.StatusNotFound {
+				resp.Body.Close()
+				slog.Debug("no changes.csv, falling back to index.txt", "feed", f.id)
+				resp, err = f.source.doRequest(client, m, fallback)
+				return resp, true, err
+			}
+			return resp, false, nil
+		}
+		resp, usedIndexTXT, err := fetch(req, fallback)
+		// Fail over to the mirrors if the source is not reachable.
+		var servedBy string
+		if unavailable(resp, err) {
+			for _, mr := range mirrorRequests {
+				mresp, mIndexTXT, merr := fetch(mr.req, mr.fallback)
+				if merr != nil {
+					slog.Debug("fetching index from mirror failed", "url", mr.req.URL, "err", merr)
+					continue
+				}
+				if mresp.StatusCode != http.StatusOK {
+					mresp.Body.Close()
+					continue
+				}
+				if resp != nil {
+					resp.Body.Close()
+				}
+				resp, usedIndexTXT, err, servedBy = mresp, mIndexTXT, nil, mr.base
+				f.log(m, config.WarnFeedLogLevel, "feed index served by mirror %q", mr.req.URL)
+				break
+			}
+		}
 		if err != nil {
 			fn(nil, err)
 			return
@@ -250,17 +367,43 @@ func (f *feed) fetchIndex(m *Manager, fn func([]location, error)) {
 			return
 		}
 		var locations []location
-		if f.rolie {
+		switch {
+		case f.rolie:
 			locations, err = fi.rolieLocations(resp.Body)
-		} else {
+		case usedIndexTXT:
+			// The probes go to the source so there is no recheck
+			// if the index.txt is served by a mirror.
+			fi.recheck = recheck && servedBy == ""
+			if fi.stored, err = storedURLs(m.db, feedID); err != nil {
+				fn(nil, err)
+				return
+			}
+			locations, err = fi.indexLocations(resp.Body)
+		default:
 			locations, err = fi.directoryLocations(resp.Body)
 		}
 		if err != nil {
 			fn(nil, err)
 			return
 		}
+		if servedBy != "" {
+			// Keep the URLs of the source to identify the documents.
+			for i := range locations {
+				l := &locations[i]
+				l.doc = origins.original(l.doc, servedBy)
+				l.hash = origins.original(l.hash, servedBy)
+				l.signature = origins.original(l.signature, servedBy)
+			}
+			fn(locations, nil)
+			// The tags of the mirror are meaningless for the source.
+			return
+		}
 		fn(locations, nil)
 		m.fns <- func(*Manager, context.Context) {
+			if fi.recheck {
+				f.indexRecheck = time.Now()
+			}
+			f.indexTXT = usedIndexTXT
 			f.lastETag = resp.Header.Get("Etag")
 			if m := resp.Header.Get("Last-Modified"); m != "" {
 				f.lastModified, _ = time.Parse(http.TimeFormat, m)
@@ -370,6 +513,29 @@ func (f *feed) addStats(st *Stats) {
 	}
 }
 
+// statusMessages returns the status of the source
+// including the messages about its subscription and pending key changes.
+func (s *source) statusMessages() []string {
+	var extra []string
+	if s.subscription != "" {
+		extra = append(extra, s.subscription)
+	}
+	if s.keysUnreviewed() {
+		extra = append(extra, keyChangePendingApproval)
+	}
+	if len(extra) == 0 {
+		return s.status
+	}
+	// Don't modify in place as the status may be shared.
+	return slices.Concat(extra, s.status)
+}
+
+// attention returns true if the source needs attention because
+// its checksum changed or a key change is pending a decision.
+func (s *source) attention() bool {
+	return s.checksumAck.Before(s.checksumUpdated) || s.keysUnreviewed()
+}
+
 func (s *source) addStats(st *Stats) {
 	for _, f := range s.feeds {
 		if !f.invalid.Load() {
@@ -513,9 +679,54 @@ func (s *source) httpGet(client *http.Client, m *Manager, url string) (*http.Res
 	return s.doRequest(client, m, req)
 }
 
+// lastModified probes the last modification time of a remote document.
+// A zero time is returned if the document has no modification time.
+func (s *source) lastModified(client *http.Client, m *Manager, u *url.URL) (time.Time, error) {
+	req, err := http.NewRequest(http.MethodHead, u.String(), nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	resp, err := s.doRequest(client, m, req)
+	if err != nil {
+		slog.Debug("probing last modification failed", "url", u, "err", err)
+		return time.Time{}, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	modified, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+	if err != nil {
+		return time.Time{}, nil
+	}
+	return modified.UTC(), nil
+}
+
+// storedURLs returns the URLs of the documents of a feed
+// which are already stored.
+func storedURLs(db *database.DB, feedID int64) (map[string]bool, error) {
+	const sql = `SELECT url FROM changes WHERE feeds_id = $1`
+	stored := map[string]bool{}
+	if err := db.Run(
+		context.Background(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			rows, _ := conn.Query(rctx, sql, feedID)
+			var url string
+			_, err := pgx.ForEachRow(rows, []any{&url}, func() error {
+				stored[url] = true
+				return nil
+			})
+			return err
+		}, 0,
+	); err != nil {
+		return nil, fmt.Errorf("loading stored documents failed: %w", err)
+	}
+	return stored, nil
+}
+
 // loadHash fetches text form of a hash from remote location.
-func (s *source) loadHash(client *http.Client, m *Manager, url string) ([]byte, error) {
-	resp, err := s.httpGet(client, m, url)
+func (s *source) loadHash(client *http.Client, m *Manager, o *origins, url string) ([]byte, error) {
+	resp, _, err := s.httpGetFailover(client, m, o, url)
 	if err != nil {
 		return nil, err
 	}
@@ -551,11 +762,6 @@ func (f *feed) storeLastChanges(l *location) func(context.Context, pgx.Tx, int64
 		if f.invalid.Load() {
 			return nil
 		}
-		const updatedSQL = `INSERT INTO changes (url, feeds_id, time) ` +
-			`VALUES ($1, $2, $3) ` +
-			`ON CONFLICT (url, feeds_id) DO ` +
-			`UPDATE SET time = $3`
-		_, err := tx.Exec(ctx, updatedSQL, l.doc.String(), f.id, l.updated)
-		return err
+		return storeChange(ctx, tx, l.doc.String(), f.id, l.updated)
 	}
 }
@@ -0,0 +1,235 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package sources
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/gocsaf/csaf/v3/csaf"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AutoSubscription describes a source which is created automatically
+// for a provider, e.g. when it shows up in an aggregator.
+type AutoSubscription struct {
+	// Name is the wished name of the source. If it is already
+	// taken a counter is appended.
+	Name string
+	// URL is the URL of the PMD of the provider.
+	URL string
+	// Mirrors are the URLs of the PMDs of mirrors of the provider.
+	Mirrors []string
+	// Active tells if the source should be activated after creation.
+	Active bool
+	// TLPs restricts the feeds to the ones with these TLP labels.
+	// If empty all feeds including the directory based ones are subscribed.
+	TLPs []csaf.TLPLabel
+	// Rate is the download rate of the source.
+	Rate *float64
+	// Slots are the download slots of the source.
+	Slots *int
+	// Status is a message stored with the new source
+	// telling how it was created.
+	Status string
+}
+
+// SubscribedSource is a source created by [Manager.Subscribe].
+type SubscribedSource struct {
+	// ID is the id of the new source.
+	ID int64
+	// Name is the name of the new source.
+	Name string
+	// Active tells if the new source was activated.
+	Active bool
+}
+
+// Subscribed checks if there are already sources subscribing the PMD
+// behind the given URL.
+func (m *Manager) Subscribed(url string) (bool, error) {
+	subs := m.Subscriptions([]string{url})
+	if len(subs) == 0 {
+		return false, InvalidArgumentError("loading PMD failed")
+	}
+	return len(subs[0].Subscriptions) > 0, nil
+}
+
+// Subscribe creates a new source for the PMD of a provider and
+// subscribes its feeds. The source is only activated if feeds
+// were subscribed. If the activation fails the created source
+// is returned together with the error.
+func (m *Manager) Subscribe(as *AutoSubscription) (*SubscribedSource, error) {
+	cpmd := m.PMD(as.URL)
+	if !cpmd.Valid() {
+		return nil, InvalidArgumentError("PMD is invalid")
+	}
+	model, err := cpmd.Model()
+	if err != nil {
+		return nil, InvalidArgumentError("PMD model is invalid")
+	}
+
+	// Name the source after the host of the PMD if there is no name.
+	base := strings.TrimSpace(as.Name)
+	if base == "" {
+		if u, err := url.Parse(as.URL); err == nil && u.Host != "" {
+			base = u.Host
+		} else {
+			base = as.URL
+		}
+	}
+
+	var age = &m.cfg.Sources.DefaultAge
+	if *age == 0 {
+		age = nil
+	}
+	mirrors := slices.DeleteFunc(slices.Clone(as.Mirrors), func(mirror string) bool {
+		return ValidateMirror(mirror) != nil
+	})
+	// Append a counter to the name as long as it is taken.
+	var (
+		name     = base
+		sourceID int64
+	)
+	for i := 2; ; i++ {
+		sourceID, err = m.AddSource(
+			name, as.URL, mirrors, as.Rate, as.Slots,
+			nil, nil, nil, nil, age, nil, nil, nil, nil)
+		if err != sourceExists {
+			break
+		}
+		name = fmt.Sprintf("%s (%d)", base, i)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ss := &SubscribedSource{ID: sourceID, Name: name}
+
+	added := 0
+	for label, feedURL := range subscribableFeeds(model, cpmd.ServiceFeeds(m.cfg), as.TLPs) {
+		u, err := url.Parse(feedURL)
+		if err != nil {
+			slog.Warn("invalid feed URL", "url", feedURL, "err", err)
+			continue
+		}
+		if _, err := m.AddFeed(sourceID, label, u, m.cfg.Sources.FeedLogLevel); err != nil {
+			slog.Warn("adding feed failed", "source", name, "url", feedURL, "err", err)
+			continue
+		}
+		added++
+	}
+
+	status := as.Status
+	if added == 0 {
+		status = strings.TrimSpace(status + " No matching feeds found.")
+	}
+	if status != "" {
+		if err := m.storeSubscription(sourceID, status); err != nil {
+			return ss, err
+		}
+	}
+
+	if as.Active && added > 0 {
+		if _, err := m.UpdateSource(sourceID, func(su *SourceUpdater) error {
+			return su.UpdateActive(true)
+		}); err != nil {
+			return ss, err
+		}
+		ss.Active = true
+	}
+	return ss, nil
+}
+
+// storeSubscription stores how a source was created automatically.
+func (m *Manager) storeSubscription(sourceID int64, subscription string) error {
+	errCh := make(chan error)
+	m.fns <- func(m *Manager, ctx context.Context) {
+		s := m.findSourceByID(sourceID)
+		if s == nil {
+			errCh <- NoSuchEntryError("no such source")
+			return
+		}
+		const sql = `UPDATE sources SET subscription = $1 WHERE id = $2`
+		if err := m.db.Run(
+			ctx,
+			func(rctx context.Context, conn *pgxpool.Conn) error {
+				_, err := conn.Exec(rctx, sql, subscription, sourceID)
+				return err
+			}, 0,
+		); err != nil {
+			errCh <- fmt.Errorf("storing subscription failed: %w", err)
+			return
+		}
+		s.subscription = subscription
+		errCh <- nil
+	}
+	return <-errCh
+}
+
+// subscribableFeeds returns the feeds of a PMD matching the given TLP labels
+// keyed by unique labels. Directory based feeds and the feeds of ROLIE
+// service documents have no TLP label and are only used if no labels are given.
+func subscribableFeeds(
+	pmd *csaf.ProviderMetadata,
+	services []string,
+	tlps []csaf.TLPLabel,
+) map[string]string {
+	feeds := map[string]string{}
+	add := func(label, feed string) {
+		for _, f := range feeds {
+			if f == feed {
+				return
+			}
+		}
+		if label == "" {
+			label = "feed"
+		}
+		unique := label
+		for i := 2; feeds[unique] != ""; i++ {
+			unique = fmt.Sprintf("%s-%d", label, i)
+		}
+		feeds[unique] = feed
+	}
+	for i := range pmd.Distributions {
+		d := pmd.Distributions[i]
+		if d.Rolie == nil {
+			continue
+		}
+		for j := range d.Rolie.Feeds {
+			f := &d.Rolie.Feeds[j]
+			if f.URL == nil {
+				continue
+			}
+			var tlp csaf.TLPLabel
+			if f.TLPLabel != nil {
+				tlp = *f.TLPLabel
+			}
+			if len(tlps) > 0 && !slices.Contains(tlps, tlp) {
+				continue
+			}
+			add(strings.ToLower(string(tlp)), string(*f.URL))
+		}
+	}
+	if len(tlps) > 0 {
+		return feeds
+	}
+	for _, service := range services {
+		add(strings.TrimSuffix(path.Base(service), ".json"), service)
+	}
+	for i := range pmd.Distributions {
+		if d := pmd.Distributions[i]; d.Rolie == nil && d.DirectoryURL != "" {
+			add("directory", d.DirectoryURL)
+		}
+	}
+	return feeds
+}
@@ -39,7 +39,10 @@ func (c *Controller) changeStatusAll(ctx *gin.Context, inputs advisoryStates) {
 	actor := c.currentUser(ctx)
 	tlps := c.tlps(ctx)
 
-	var forbidden, noTransition, bad bool
+	var (
+		forbidden, noTransition, bad bool
+		changed                      []int64
+	)
 
 	if err := c.db.Run(
 		ctx.Request.Context(),
@@ -104,6 +107,7 @@ func (c *Controller) changeStatusAll(ctx *gin.Context, inputs advisoryStates) {
 				if _, err := tx.Exec(rctx, insertLog, string(input.State), actor, documentID); err != nil {
 					return err
 				}
+				changed = append(changed, documentID)
 			}
 
 			return tx.Commit(rctx)
@@ -125,6 +129,7 @@ func (c *Controller) changeStatusAll(ctx *gin.Context, inputs advisoryStates) {
 	case noTransition:
 		models.SendErrorMessage(ctx, http.StatusBadRequest, "state transition not possible")
 	default:
+		c.evaluateAlerts(ctx, models.StateChangeEvent, changed...)
 		models.SendSuccess(ctx, http.StatusOK, "transition done")
 	}
 }
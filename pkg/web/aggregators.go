@@ -15,24 +15,130 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ISDuBA/ISDuBA/pkg/aggregators"
 	"github.com/ISDuBA/ISDuBA/pkg/models"
 	"github.com/ISDuBA/ISDuBA/pkg/sources"
 	"github.com/gin-gonic/gin"
+	"github.com/gocsaf/csaf/v3/csaf"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// aggregatorAttentionSQL checks if an aggregator needs attention.
+// This is the case if it was flagged or if it has unacknowledged changes.
+const aggregatorAttentionSQL = `(checksum_ack < checksum_updated OR EXISTS(` +
+	`SELECT 1 FROM aggregator_changes ` +
+	`WHERE aggregators_id = aggregators.id AND acknowledged IS NULL))`
+
 type custom struct {
 	ID            int64                         `json:"id,omitempty"`
 	Name          string                        `json:"name,omitempty"`
 	Attention     *bool                         `json:"attention,omitempty"`
+	Policy        *aggregatorPolicy             `json:"policy,omitempty"`
 	Subscriptions []sources.SourceSubscriptions `json:"subscriptions,omitempty"`
 }
 
+// aggregatorPolicy is the auto-subscription policy of an aggregator.
+type aggregatorPolicy struct {
+	Policy aggregators.Policy `json:"policy"`
+	Roles  []string           `json:"roles"`
+	TLPs   []string           `json:"tlps,omitempty"`
+	Rate   *float64           `json:"rate,omitempty"`
+	Slots  *int               `json:"slots,omitempty"`
+}
+
+// policyFromForm extracts the fields of an auto-subscription policy
+// from the form and passes the given ones to add.
+func (c *Controller) policyFromForm(ctx *gin.Context, add func(field string, value any)) bool {
+	if policyParam, ok := ctx.GetPostForm("policy"); ok {
+		policy, ok := parse(ctx, aggregators.ParsePolicy, policyParam)
+		if !ok {
+			return false
+		}
+		add("policy", string(policy))
+	}
+	if roles, ok := ctx.GetPostFormArray("policy_roles"); ok {
+		roles = slices.DeleteFunc(roles, func(r string) bool { return r == "" })
+		for _, role := range roles {
+			switch csaf.MetadataRole(role) {
+			case csaf.MetadataRolePublisher,
+				csaf.MetadataRoleProvider,
+				csaf.MetadataRoleTrustedProvider:
+			default:
+				models.SendErrorMessage(ctx, http.StatusBadRequest,
+					fmt.Sprintf("invalid role %q", role))
+				return false
+			}
+		}
+		add("policy_roles", roles)
+	}
+	if tlps, ok := ctx.GetPostFormArray("policy_tlps"); ok {
+		tlps = slices.DeleteFunc(tlps, func(t string) bool { return t == "" })
+		for i, tlp := range tlps {
+			tlps[i] = strings.ToUpper(tlp)
+			switch tlps[i] {
+			case csaf.TLPLabelUnlabeled,
+				csaf.TLPLabelWhite,
+				csaf.TLPLabelGreen,
+				csaf.TLPLabelAmber,
+				csaf.TLPLabelRed:
+			default:
+				models.SendErrorMessage(ctx, http.StatusBadRequest,
+					fmt.Sprintf("invalid TLP label %q", tlp))
+				return false
+			}
+		}
+		// No labels means all feeds.
+		if len(tlps) == 0 {
+			tlps = nil
+		}
+		add("policy_tlps", tlps)
+	}
+	if rateParam, ok := ctx.GetPostForm("policy_rate"); ok {
+		var rate *float64
+		if rateParam != "" {
+			r, ok := parse(ctx, func(s string) (float64, error) {
+				return strconv.ParseFloat(s, 64)
+			}, rateParam)
+			if !ok {
+				return false
+			}
+			if r < 0 || (c.cfg.Sources.MaxRatePerSource != 0 && r > c.cfg.Sources.MaxRatePerSource) {
+				models.SendErrorMessage(ctx, http.StatusBadRequest, "'policy_rate' out of range")
+				return false
+			}
+			if r != 0 {
+				rate = &r
+			}
+		}
+		add("policy_rate", rate)
+	}
+	if slotsParam, ok := ctx.GetPostForm("policy_slots"); ok {
+		var slots *int
+		if slotsParam != "" {
+			s, ok := parse(ctx, strconv.Atoi, slotsParam)
+			if !ok {
+				return false
+			}
+			if s < 0 || s > c.cfg.Sources.MaxSlotsPerSource {
+				models.SendErrorMessage(ctx, http.StatusBadRequest, "'policy_slots' out of range")
+				return false
+			}
+			if s != 0 {
+				slots = &s
+			}
+		}
+		add("policy_slots", slots)
+	}
+	return true
+}
+
 type argumentedAggregator struct {
 	Aggregator json.RawMessage `json:"aggregator"`
 	Custom     custom          `json:"custom"`
@@ -58,7 +164,7 @@ func (c *Controller) aggregatorProxy(ctx *gin.Context) {
 	}
 	// search in database
 	const sql = `SELECT ` +
-		`id, name, (checksum_ack < checksum_updated) AS attention ` +
+		`id, name, ` + aggregatorAttentionSQL + ` AS attention ` +
 		`FROM aggregators WHERE url = $1`
 	var (
 		id        int64
@@ -101,15 +207,17 @@ func (c *Controller) aggregatorProxy(ctx *gin.Context) {
 //	@Router			/aggregators [get]
 func (c *Controller) viewAggregators(ctx *gin.Context) {
 	type aggregator struct {
-		ID        int64  `json:"id"`
-		Name      string `json:"name"`
-		URL       string `json:"url"`
-		Active    bool   `json:"active"`
-		Attention bool   `json:"attention"`
+		ID        int64            `json:"id"`
+		Name      string           `json:"name"`
+		URL       string           `json:"url"`
+		Active    bool             `json:"active"`
+		Attention bool             `json:"attention"`
+		Policy    aggregatorPolicy `json:"policy"`
 	}
 	var list []aggregator
 	const sql = `SELECT ` +
-		`id, name, url, active, (checksum_ack < checksum_updated) AS attention ` +
+		`id, name, url, active, ` + aggregatorAttentionSQL + ` AS attention, ` +
+		`policy::text, policy_roles, policy_tlps, policy_rate, policy_slots ` +
 		`FROM aggregators ORDER by name`
 	if err := c.db.Run(
 		ctx.Request.Context(),
@@ -118,7 +226,9 @@ func (c *Controller) viewAggregators(ctx *gin.Context) {
 			var err error
 			list, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (aggregator, error) {
 				var a aggregator
-				err := row.Scan(&a.ID, &a.Name, &a.URL, &a.Active, &a.Attention)
+				err := row.Scan(&a.ID, &a.Name, &a.URL, &a.Active, &a.Attention,
+					&a.Policy.Policy, &a.Policy.Roles, &a.Policy.TLPs,
+					&a.Policy.Rate, &a.Policy.Slots)
 				return a, err
 			})
 			return err
@@ -154,14 +264,19 @@ func (c *Controller) viewAggregator(ctx *gin.Context) {
 		url       string
 		active    bool
 		attention bool
+		policy    aggregatorPolicy
 	)
 	const sql = `SELECT ` +
-		`name, url, active, (checksum_ack < checksum_updated) AS attention ` +
+		`name, url, active, ` + aggregatorAttentionSQL + ` AS attention, ` +
+		`policy::text, policy_roles, policy_tlps, policy_rate, policy_slots ` +
 		`FROM aggregators WHERE id = $1`
 	switch err := c.db.Run(
 		ctx.Request.Context(),
 		func(rctx context.Context, conn *pgxpool.Conn) error {
-			return conn.QueryRow(rctx, sql, id).Scan(&name, &url, &active, &attention)
+			return conn.QueryRow(rctx, sql, id).Scan(
+				&name, &url, &active, &attention,
+				&policy.Policy, &policy.Roles, &policy.TLPs,
+				&policy.Rate, &policy.Slots)
 		}, 0,
 	); {
 	case errors.Is(err, pgx.ErrNoRows):
@@ -183,6 +298,7 @@ func (c *Controller) viewAggregator(ctx *gin.Context) {
 			ID:            id,
 			Name:          name,
 			Attention:     &attention,
+			Policy:        &policy,
 			Subscriptions: c.sm.Subscriptions(ca.SourceURLs()),
 		},
 	}
@@ -193,8 +309,14 @@ func (c *Controller) viewAggregator(ctx *gin.Context) {
 //
 //	@Summary		Creates an aggregator.
 //	@Description	Creates an aggregator with specified configuration.
-//	@Param			name	formData	string	true	"Aggregator name"
-//	@Param			url		formData	string	true	"Aggregator URL"
+//	@Param			name			formData	string		true	"Aggregator name"
+//	@Param			url				formData	string		true	"Aggregator URL"
+//	@Param			active			formData	bool		false	"Aggregator active flag"
+//	@Param			policy			formData	string		false	"Auto-subscription policy"	Enums(none, create_inactive, create_active)
+//	@Param			policy_roles	formData	[]string	false	"Roles of providers the policy applies to"
+//	@Param			policy_tlps		formData	[]string	false	"TLP labels of the feeds to subscribe"
+//	@Param			policy_rate		formData	number		false	"Download rate of created sources"
+//	@Param			policy_slots	formData	int			false	"Download slots of created sources"
 //	@Accept			multipart/form-data
 //	@Produce		json
 //	@Success		201	{object}	models.ID
@@ -227,11 +349,26 @@ func (c *Controller) createAggregator(ctx *gin.Context) {
 		}
 	}
 
-	const sql = `INSERT INTO aggregators (name, url, active) VALUES ($1, $2, $3) RETURNING id`
+	var (
+		columns = []string{"name", "url", "active"}
+		values  = []any{name, url, active}
+	)
+	if !c.policyFromForm(ctx, func(field string, value any) {
+		columns = append(columns, field)
+		values = append(values, value)
+	}) {
+		return
+	}
+	places := make([]string, len(values))
+	for i := range places {
+		places[i] = "$" + strconv.Itoa(i+1)
+	}
+	sql := `INSERT INTO aggregators (` + strings.Join(columns, ", ") + `) ` +
+		`VALUES (` + strings.Join(places, ", ") + `) RETURNING id`
 	if err := c.db.Run(
 		ctx.Request.Context(),
 		func(rctx context.Context, conn *pgxpool.Conn) error {
-			return conn.QueryRow(rctx, sql, name, url, active).Scan(&id)
+			return conn.QueryRow(rctx, sql, values...).Scan(&id)
 		}, 0,
 	); err != nil {
 		var pgErr *pgconn.PgError
@@ -287,7 +424,7 @@ func (c *Controller) deleteAggregator(ctx *gin.Context) {
 
 func (c *Controller) attentionAggregators(ctx *gin.Context) {
 	const sql = `SELECT id, name FROM aggregators ` +
-		`WHERE checksum_ack < checksum_updated ` +
+		`WHERE ` + aggregatorAttentionSQL + ` ` +
 		`ORDER BY name`
 	type attention struct {
 		ID   int64  `json:"id"`
@@ -318,11 +455,16 @@ func (c *Controller) attentionAggregators(ctx *gin.Context) {
 //
 //	@Summary		Updates aggregator configuration.
 //	@Description	Updates the aggregator configuration.
-//	@Param			id			path		int		true	"Aggregator ID"
-//	@Param			name		formData	string	false	"Aggregator name"
-//	@Param			url			formData	string	false	"Aggregator URL"
-//	@Param			active		formData	bool	false	"Aggregator active flag"
-//	@Param			attention	formData	bool	false	"Aggregator attention flag"
+//	@Param			id				path		int			true	"Aggregator ID"
+//	@Param			name			formData	string		false	"Aggregator name"
+//	@Param			url				formData	string		false	"Aggregator URL"
+//	@Param			active			formData	bool		false	"Aggregator active flag"
+//	@Param			attention		formData	bool		false	"Aggregator attention flag"
+//	@Param			policy			formData	string		false	"Auto-subscription policy"	Enums(none, create_inactive, create_active)
+//	@Param			policy_roles	formData	[]string	false	"Roles of providers the policy applies to"
+//	@Param			policy_tlps		formData	[]string	false	"TLP labels of the feeds to subscribe"
+//	@Param			policy_rate		formData	number		false	"Download rate of created sources"
+//	@Param			policy_slots	formData	int			false	"Download slots of created sources"
 //	@Accept			multipart/form-data
 //	@Produce		json
 //	@Success		200	{object}	models.Success
@@ -384,6 +526,9 @@ func (c *Controller) updateAggregator(ctx *gin.Context) {
 			fields = append(fields, sqlAttFalse)
 		}
 	}
+	if !c.policyFromForm(ctx, add) {
+		return
+	}
 
 	if len(fields) == 0 {
 		models.SendSuccess(ctx, http.StatusOK, "unchanged")
@@ -395,15 +540,31 @@ func (c *Controller) updateAggregator(ctx *gin.Context) {
 	updateSQL := prefix + strings.Join(fields, ",") + suffix
 	slog.Debug("update aggregators", "sql", updateSQL, "values", values)
 
+	// Removing the attention acknowledges all open changes.
+	const ackSQL = `UPDATE aggregator_changes ` +
+		`SET (acknowledged, acknowledged_by) = (current_timestamp, $2) ` +
+		`WHERE aggregators_id = $1 AND acknowledged IS NULL`
+	ackAll := slices.Contains(fields, sqlAttFalse)
+
 	if err := c.db.Run(
 		ctx.Request.Context(),
 		func(rctx context.Context, conn *pgxpool.Conn) error {
-			tags, err := conn.Exec(rctx, updateSQL, values...)
+			tx, err := conn.Begin(rctx)
+			if err != nil {
+				return err
+			}
+			defer tx.Rollback(rctx)
+			tags, err := tx.Exec(rctx, updateSQL, values...)
 			if err != nil {
 				return err
 			}
 			changed = tags.RowsAffected() > 0
-			return nil
+			if changed && ackAll {
+				if _, err := tx.Exec(rctx, ackSQL, id, c.currentUser(ctx)); err != nil {
+					return err
+				}
+			}
+			return tx.Commit(rctx)
 		}, 0,
 	); err != nil {
 		var pgErr *pgconn.PgError
@@ -422,3 +583,151 @@ func (c *Controller) updateAggregator(ctx *gin.Context) {
 		models.SendSuccess(ctx, http.StatusOK, "unchanged")
 	}
 }
+
+// aggregatorChange is a recorded change of a provider listed in an aggregator.
+type aggregatorChange struct {
+	ID             int64                  `json:"id"`
+	Time           time.Time              `json:"time"`
+	Kind           aggregators.ChangeKind `json:"kind"`
+	URL            string                 `json:"url"`
+	Previous       *aggregators.Provider  `json:"previous,omitempty"`
+	Current        *aggregators.Provider  `json:"current,omitempty"`
+	Acknowledged   *time.Time             `json:"acknowledged,omitempty"`
+	AcknowledgedBy *string                `json:"acknowledged_by,omitempty"`
+	Action         *string                `json:"action,omitempty"`
+	SourceID       *int64                 `json:"source_id,omitempty"`
+}
+
+// viewAggregatorChanges is an endpoint that returns the change history of an aggregator.
+//
+//	@Summary		Returns the changes of an aggregator.
+//	@Description	Returns the providers added, removed, changed or updated in the aggregator, newest first.
+//	@Description	Updated providers only have a new last update time. These changes are acknowledged from the start.
+//	@Param			id				path	int		true	"Aggregator ID"
+//	@Param			unacknowledged	query	bool	false	"Only return unacknowledged changes"
+//	@Produce		json
+//	@Success		200	{array}		aggregatorChange
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		404	{object}	models.Error	"not found"
+//	@Failure		500	{object}	models.Error
+//	@Router			/aggregators/{id}/changes [get]
+func (c *Controller) viewAggregatorChanges(ctx *gin.Context) {
+	id, ok := parse(ctx, toInt64, ctx.Param("id"))
+	if !ok {
+		return
+	}
+	var unacknowledged bool
+	if unack := ctx.Query("unacknowledged"); unack != "" {
+		if unacknowledged, ok = parse(ctx, strconv.ParseBool, unack); !ok {
+			return
+		}
+	}
+	const (
+		existsSQL = `SELECT EXISTS(SELECT 1 FROM aggregators WHERE id = $1)`
+		sql       = `SELECT id, time, kind::text, url, previous, current, ` +
+			`acknowledged, acknowledged_by, action, sources_id ` +
+			`FROM aggregator_changes ` +
+			`WHERE aggregators_id = $1 AND (NOT $2 OR acknowledged IS NULL) ` +
+			`ORDER BY time DESC, id DESC`
+	)
+	var (
+		exists bool
+		list   []aggregatorChange
+	)
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			if err := conn.QueryRow(rctx, existsSQL, id).Scan(&exists); err != nil || !exists {
+				return err
+			}
+			rows, _ := conn.Query(rctx, sql, id, unacknowledged)
+			var err error
+			list, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (aggregatorChange, error) {
+				var ac aggregatorChange
+				err := row.Scan(
+					&ac.ID, &ac.Time, &ac.Kind, &ac.URL, &ac.Previous, &ac.Current,
+					&ac.Acknowledged, &ac.AcknowledgedBy, &ac.Action, &ac.SourceID)
+				return ac, err
+			})
+			return err
+		}, 0,
+	); err != nil {
+		slog.Error("fetching aggregator changes failed", "error", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if !exists {
+		models.SendErrorMessage(ctx, http.StatusNotFound, "not found")
+		return
+	}
+	if list == nil {
+		list = []aggregatorChange{}
+	}
+	ctx.JSON(http.StatusOK, list)
+}
+
+// updateAggregatorChange is an endpoint that acknowledges a change of an aggregator.
+//
+//	@Summary		Acknowledges an aggregator change.
+//	@Description	Acknowledges a single change of an aggregator or revokes the acknowledgment.
+//	@Description	If all changes are acknowledged the aggregator does not need attention any more.
+//	@Param			id				path		int		true	"Aggregator ID"
+//	@Param			change			path		int		true	"Change ID"
+//	@Param			acknowledged	formData	bool	true	"Acknowledgment"
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Success		200	{object}	models.Success
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		404	{object}	models.Error	"not found"
+//	@Failure		500	{object}	models.Error
+//	@Router			/aggregators/{id}/changes/{change} [put]
+func (c *Controller) updateAggregatorChange(ctx *gin.Context) {
+	id, ok := parse(ctx, toInt64, ctx.Param("id"))
+	if !ok {
+		return
+	}
+	changeID, ok := parse(ctx, toInt64, ctx.Param("change"))
+	if !ok {
+		return
+	}
+	acknowledged, ok := parse(ctx, strconv.ParseBool, ctx.PostForm("acknowledged"))
+	if !ok {
+		return
+	}
+	const (
+		ackSQL = `UPDATE aggregator_changes ` +
+			`SET (acknowledged, acknowledged_by) = (current_timestamp, $3) ` +
+			`WHERE id = $1 AND aggregators_id = $2`
+		unackSQL = `UPDATE aggregator_changes ` +
+			`SET (acknowledged, acknowledged_by) = (NULL, NULL) ` +
+			`WHERE id = $1 AND aggregators_id = $2`
+	)
+	var found bool
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			var (
+				tags pgconn.CommandTag
+				err  error
+			)
+			if acknowledged {
+				tags, err = conn.Exec(rctx, ackSQL, changeID, id, c.currentUser(ctx))
+			} else {
+				tags, err = conn.Exec(rctx, unackSQL, changeID, id)
+			}
+			found = tags.RowsAffected() > 0
+			return err
+		}, 0,
+	); err != nil {
+		slog.Error("updating aggregator change failed", "error", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if !found {
+		models.SendErrorMessage(ctx, http.StatusNotFound, "not found")
+		return
+	}
+	models.SendSuccess(ctx, http.StatusOK, "changed")
+}
@@ -0,0 +1,293 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ISDuBA/ISDuBA/pkg/database/query"
+	"github.com/ISDuBA/ISDuBA/pkg/models"
+)
+
+// evaluateAlerts checks if the documents match any alert query
+// after the given event. Failures are only logged as the
+// triggering change is already done.
+func (c *Controller) evaluateAlerts(ctx *gin.Context, event models.Event, documentIDs ...int64) {
+	var actor *string
+	if user := c.currentUser(ctx); user.Valid {
+		actor = &user.String
+	}
+	if err := c.db.Run(
+		context.WithoutCancel(ctx.Request.Context()),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			var errs []error
+			for _, documentID := range documentIDs {
+				errs = append(errs, models.EvaluateAlerts(rctx, conn, documentID, event, actor))
+			}
+			return errors.Join(errs...)
+		}, 0,
+	); err != nil {
+		slog.Error("evaluating alerts failed", "err", err)
+	}
+}
+
+// listAlerts is an endpoint that returns the number of hits of the alert queries.
+//
+//	@Summary		Returns the alerts.
+//	@Description	Returns the alert queries visible to the user with the number of their new and all hits.
+//	@Produce		json
+//	@Success		200	{array}		web.listAlerts.alert
+//	@Failure		401
+//	@Failure		500	{object}	models.Error
+//	@Router			/alerts [get]
+func (c *Controller) listAlerts(ctx *gin.Context) {
+	type alert struct {
+		ID      int64                `json:"id"`
+		Name    string               `json:"name"`
+		Definer string               `json:"definer"`
+		Global  bool                 `json:"global"`
+		Role    *models.WorkflowRole `json:"role,omitempty"`
+		New     int64                `json:"new"`
+		Total   int64                `json:"total"`
+		Last    *time.Time           `json:"last,omitempty"`
+	}
+
+	// Only the hits on documents the user is allowed to see are counted.
+	builder := query.SQLBuilder{}
+	builder.CreateWhere(c.tlps(ctx).AsExpr())
+	user := len(builder.Replacements) + 1
+
+	selectSQL := fmt.Sprintf(`SELECT `+
+		`sq.id, sq.name, sq.definer, sq.global, sq.role, `+
+		`count(ah.documents_id) FILTER (WHERE ah.time > COALESCE(seen.time, '-infinity')), `+
+		`count(ah.documents_id), `+
+		`max(ah.time) `+
+		`FROM stored_queries sq `+
+		`LEFT JOIN (alert_hits ah `+
+		`JOIN documents ON ah.documents_id = documents.id `+
+		`JOIN advisories ON documents.advisories_id = advisories.id AND (%[1]s)) `+
+		`ON ah.stored_queries_id = sq.id `+
+		`LEFT JOIN alerts_seen seen ON seen.stored_queries_id = sq.id AND seen."user" = $%[2]d `+
+		`WHERE sq.alert AND (sq.definer = $%[2]d OR sq.global OR %[3]s) `+
+		`GROUP BY sq.id, seen.time `+
+		`ORDER BY sq.global DESC, sq.definer, sq.num`,
+		builder.WhereClause, user, sharedWith("sq", user, false))
+
+	args := append(builder.Replacements, c.shareArgs(ctx)...)
+
+	var alerts []*alert
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			rows, _ := conn.Query(rctx, selectSQL, args...)
+			var err error
+			alerts, err = pgx.CollectRows(rows,
+				func(row pgx.CollectableRow) (*alert, error) {
+					var a alert
+					err := row.Scan(
+						&a.ID, &a.Name, &a.Definer, &a.Global, &a.Role,
+						&a.New, &a.Total, &a.Last)
+					return &a, err
+				})
+			return err
+		}, 0,
+	); err != nil {
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Remove alerts that should only be viewable by other roles.
+	alerts = slices.DeleteFunc(alerts, func(a *alert) bool {
+		return a.Global && a.Role != nil && !c.hasAnyRole(ctx, *a.Role, models.Admin)
+	})
+
+	ctx.JSON(http.StatusOK, alerts)
+}
+
+// viewAlertHits is an endpoint that returns the hits of an alert query.
+//
+//	@Summary		Returns the hits of an alert.
+//	@Description	Returns the documents which matched the alert query, the newest first.
+//	@Param			query	path	int	true	"Query ID"
+//	@Param			limit	query	int	false	"Maximum number of hits"
+//	@Param			offset	query	int	false	"Offset of the hits"
+//	@Produce		json
+//	@Success		200	{array}		web.viewAlertHits.hit
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		404	{object}	models.Error
+//	@Failure		500	{object}	models.Error
+//	@Router			/alerts/{query} [get]
+func (c *Controller) viewAlertHits(ctx *gin.Context) {
+	type hit struct {
+		DocumentID int64        `json:"document_id"`
+		Publisher  string       `json:"publisher"`
+		TrackingID string       `json:"tracking_id"`
+		Version    string       `json:"version"`
+		Title      *string      `json:"title,omitempty"`
+		Event      models.Event `json:"event"`
+		Actor      *string      `json:"actor,omitempty"`
+		Time       time.Time    `json:"time"`
+		New        bool         `json:"new"`
+	}
+
+	queryID, ok := parse(ctx, toInt64, ctx.Param("query"))
+	if !ok {
+		return
+	}
+
+	var limit, offset int64 = -1, -1
+	if lim := ctx.Query("limit"); lim != "" {
+		if limit, ok = parse(ctx, toInt64, lim); !ok {
+			return
+		}
+	}
+	if ofs := ctx.Query("offset"); ofs != "" {
+		if offset, ok = parse(ctx, toInt64, ofs); !ok {
+			return
+		}
+	}
+
+	alertSQL := `SELECT global, role FROM stored_queries ` +
+		`WHERE id = $1 AND alert AND (global OR definer = $2 OR ` +
+		sharedWith("stored_queries", 2, false) + `)`
+
+	// Only the hits on documents the user is allowed to see are listed.
+	builder := query.SQLBuilder{}
+	builder.CreateWhere(c.tlps(ctx).AsExpr())
+	n := len(builder.Replacements)
+
+	selectSQL := fmt.Sprintf(`SELECT `+
+		`ah.documents_id, advisories.publisher, advisories.tracking_id, `+
+		`documents.version, documents.title, `+
+		`ah.event::text, ah.actor, ah.time, `+
+		`ah.time > COALESCE(seen.time, '-infinity') `+
+		`FROM alert_hits ah `+
+		`JOIN documents ON ah.documents_id = documents.id `+
+		`JOIN advisories ON documents.advisories_id = advisories.id `+
+		`LEFT JOIN alerts_seen seen ON seen.stored_queries_id = ah.stored_queries_id `+
+		`AND seen."user" = $%d `+
+		`WHERE ah.stored_queries_id = $%d AND (%s) `+
+		`ORDER BY ah.time DESC, ah.documents_id DESC`,
+		n+1, n+2, builder.WhereClause)
+	if limit > -1 {
+		selectSQL += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	if offset > -1 {
+		selectSQL += fmt.Sprintf(" OFFSET %d", offset)
+	}
+
+	user := ctx.GetString("uid")
+	args := append(builder.Replacements, user, queryID)
+
+	var (
+		notFound bool
+		hits     []*hit
+	)
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			var (
+				global bool
+				role   *models.WorkflowRole
+			)
+			alertArgs := append([]any{queryID}, c.shareArgs(ctx)...)
+			if err := conn.QueryRow(rctx, alertSQL, alertArgs...).Scan(&global, &role); err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					notFound = true
+					return nil
+				}
+				return err
+			}
+			// Global queries may be restricted to a role.
+			if global && role != nil && !c.hasAnyRole(ctx, *role, models.Admin) {
+				notFound = true
+				return nil
+			}
+			rows, _ := conn.Query(rctx, selectSQL, args...)
+			var err error
+			hits, err = pgx.CollectRows(rows,
+				func(row pgx.CollectableRow) (*hit, error) {
+					var h hit
+					err := row.Scan(
+						&h.DocumentID, &h.Publisher, &h.TrackingID,
+						&h.Version, &h.Title,
+						&h.Event, &h.Actor, &h.Time,
+						&h.New)
+					return &h, err
+				})
+			return err
+		}, 0,
+	); err != nil {
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if notFound {
+		models.SendErrorMessage(ctx, http.StatusNotFound, "alert not found")
+		return
+	}
+	ctx.JSON(http.StatusOK, hits)
+}
+
+// markAlertSeen is an endpoint that marks the hits of an alert query as seen.
+//
+//	@Summary		Marks the hits of an alert as seen.
+//	@Description	Marks all current hits of the alert query as seen by the user.
+//	@Param			query	path	int	true	"Query ID"
+//	@Produce		json
+//	@Success		200	{object}	models.Success
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		404	{object}	models.Error
+//	@Failure		500	{object}	models.Error
+//	@Router			/alerts/{query}/seen [put]
+func (c *Controller) markAlertSeen(ctx *gin.Context) {
+	queryID, ok := parse(ctx, toInt64, ctx.Param("query"))
+	if !ok {
+		return
+	}
+
+	upsertSQL := `INSERT INTO alerts_seen ("user", stored_queries_id, time) ` +
+		`SELECT $1, id, current_timestamp FROM stored_queries ` +
+		`WHERE id = $4 AND alert AND (global OR definer = $1 OR ` +
+		sharedWith("stored_queries", 1, false) + `) ` +
+		`ON CONFLICT ("user", stored_queries_id) DO UPDATE SET time = excluded.time`
+
+	var marked bool
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			args := append(c.shareArgs(ctx), queryID)
+			tag, err := conn.Exec(rctx, upsertSQL, args...)
+			marked = err == nil && tag.RowsAffected() > 0
+			return err
+		}, 0,
+	); err != nil {
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if !marked {
+		models.SendErrorMessage(ctx, http.StatusNotFound, "alert not found")
+		return
+	}
+	models.SendSuccess(ctx, http.StatusOK, "seen")
+}
@@ -79,6 +79,7 @@ func (c *Controller) createComment(ctx *gin.Context) {
 		message, _        = ctx.GetPostForm("message")
 		now               = time.Now().UTC()
 		commentID         *int64
+		stateChanged      bool
 	)
 
 	if err := c.db.Run(
@@ -145,6 +146,7 @@ func (c *Controller) createComment(ctx *gin.Context) {
 				if err := logEvent(models.StateChangeEvent, models.AssessingWorkflow); err != nil {
 					return err
 				}
+				stateChanged = true
 			}
 
 			// Now insert the comment itself
@@ -180,6 +182,9 @@ func (c *Controller) createComment(ctx *gin.Context) {
 	case forbidden:
 		models.SendErrorMessage(ctx, http.StatusForbidden, "user not allowed to change state")
 	default:
+		if stateChanged {
+			c.evaluateAlerts(ctx, models.StateChangeEvent, docID)
+		}
 		ctx.JSON(http.StatusCreated, commentResult{
 			ID:          commentID,
 			Time:        now,
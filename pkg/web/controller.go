@@ -23,6 +23,7 @@ import (
 	"github.com/ISDuBA/ISDuBA/pkg/database"
 	"github.com/ISDuBA/ISDuBA/pkg/forwarder"
 	"github.com/ISDuBA/ISDuBA/pkg/ginkeycloak"
+	"github.com/ISDuBA/ISDuBA/pkg/imports"
 	"github.com/ISDuBA/ISDuBA/pkg/models"
 	"github.com/ISDuBA/ISDuBA/pkg/sources"
 	"github.com/ISDuBA/ISDuBA/pkg/tempstore"
@@ -40,6 +41,7 @@ type Controller struct {
 	ts  *tempstore.Store
 	sm  *sources.Manager
 	am  *aggregators.Manager
+	im  *imports.Manager
 	val csaf.RemoteValidator
 }
 
@@ -51,6 +53,7 @@ func NewController(
 	ts *tempstore.Store,
 	dl *sources.Manager,
 	am *aggregators.Manager,
+	im *imports.Manager,
 	val csaf.RemoteValidator,
 ) *Controller {
 	return &Controller{
@@ -60,6 +63,7 @@ func NewController(
 		ts:  ts,
 		sm:  dl,
 		am:  am,
+		im:  im,
 		val: val,
 	}
 }
@@ -98,6 +102,7 @@ func (c *Controller) Bind() http.Handler {
 		authAdEdImReSM = authRoles(models.Admin, models.Editor, models.Importer, models.Reviewer,
 			models.SourceManager)
 		authAdEdRe = authRoles(models.Admin, models.Editor, models.Reviewer)
+		authAdIm   = authRoles(models.Admin, models.Importer)
 		authAuEdRe = authRoles(models.Auditor, models.Editor, models.Reviewer)
 		authAuEdSM = authRoles(models.Auditor, models.Editor, models.SourceManager)
 		authEd     = authRoles(models.Editor)
@@ -108,6 +113,11 @@ func (c *Controller) Bind() http.Handler {
 			models.Reviewer, models.SourceManager)
 	)
 
+	// Read-only CSAF provider tree of the mirrored sources.
+	if c.cfg.Mirror.Enabled {
+		r.GET(c.cfg.Mirror.Path+"/*file", c.mirror)
+	}
+
 	api := r.Group("/api")
 
 	// Documents
@@ -115,6 +125,7 @@ func (c *Controller) Bind() http.Handler {
 	api.POST("/documents", authIm, c.importDocument)
 	// Everyone can view (GET) overviewDocuments and viewDocuments?
 	api.GET("/documents", authAll, c.overviewDocuments)
+	api.GET("/documents/aggregate", authAll, c.aggregateDocuments)
 	api.GET("/documents/:id", authAll, c.viewDocument)
 	api.GET("/documents/forward", authAdEdImReSM, c.viewForwardTargets)
 	api.POST("/documents/forward/:id/:target", authAdEdImReSM, c.forwardDocument)
@@ -124,6 +135,15 @@ func (c *Controller) Bind() http.Handler {
 	// Related CVEs
 	api.GET("/documents/:id/cve_related", authAdAuEdRe, c.cveRelatedDocuments)
 
+	// Validation reports of downloads and imports
+	api.GET("/documents/:id/validation", authAll, c.viewValidation)
+
+	// Import jobs of archives
+	api.POST("/imports", authIm, c.createImportJob)
+	api.GET("/imports", authAdIm, c.listImportJobs)
+	api.GET("/imports/:id", authAdIm, c.viewImportJob)
+	api.DELETE("/imports/:id", authAdIm, c.cancelImportJob)
+
 	// Advisories
 	api.DELETE("/advisory/:publisher/:trackingid", authAd, c.deleteAdvisory)
 
@@ -137,12 +157,23 @@ func (c *Controller) Bind() http.Handler {
 	api.POST("/queries", authAll, c.createStoredQuery)
 	api.POST("/queries/orders", authAll, c.updateOrder)
 	api.GET("/queries", authAll, c.listStoredQueries)
+	api.GET("/queries/export", authAll, c.exportStoredQueries)
+	api.POST("/queries/import", authAll, c.importStoredQueries)
 	api.GET("/queries/:query", authAll, c.fetchStoredQuery)
 	api.PUT("/queries/:query", authAll, c.updateStoredQuery)
 	api.DELETE("/queries/:query", authAll, c.deleteStoredQuery)
+	api.GET("/queries/:query/shares", authAll, c.viewStoredQueryShares)
+	api.PUT("/queries/:query/shares", authAll, c.shareStoredQuery)
+	api.DELETE("/queries/:query/shares/:kind/:name", authAll, c.unshareStoredQuery)
 	api.GET("/queries/ignore", authAll, c.getDefaultQueryExclusion)
 	api.POST("/queries/ignore/:query", authAll, c.insertDefaultQueryExclusion)
 	api.DELETE("/queries/ignore/:query", authAll, c.deleteDefaultQueryExclusion)
+	api.GET("/query/validate", authAll, c.validateQuery)
+
+	// Alerts
+	api.GET("/alerts", authAll, c.listAlerts)
+	api.GET("/alerts/:query", authAll, c.viewAlertHits)
+	api.PUT("/alerts/:query/seen", authAll, c.markAlertSeen)
 
 	// Events
 	api.GET("/events", authAdAuEdRe, c.overviewEvents)
@@ -177,6 +208,7 @@ func (c *Controller) Bind() http.Handler {
 
 	// PMD proxy
 	api.GET("/pmd", authSM, c.pmd)
+	api.GET("/pmd/discover", authSM, c.discoverPMD)
 
 	// Source manager
 	api.GET("/sources", authAuEdSM, c.viewSources)
@@ -189,6 +221,10 @@ func (c *Controller) Bind() http.Handler {
 	api.PUT("/sources/:id", authSM, c.updateSource)
 
 	// Source feeds
+	api.GET("/sources/:id/keys", authSM, c.viewSourceKeys)
+	api.POST("/sources/:id/keys", authSM, c.uploadSourceKey)
+	api.PUT("/sources/:id/keys/:fingerprint", authSM, c.approveSourceKey)
+	api.DELETE("/sources/:id/keys/:fingerprint", authSM, c.deleteSourceKey)
 	api.GET("/sources/:id/feeds", authAuEdSM, c.viewFeeds)
 	api.POST("/sources/:id/feeds", authSM, c.createFeed)
 	api.GET("/sources/feeds/:id", authAuEdSM, c.viewFeed)
@@ -198,6 +234,14 @@ func (c *Controller) Bind() http.Handler {
 	api.GET("/sources/feeds/:id/log", authSM, c.feedLog)
 	api.GET("/sources/feeds/keep", authAll, c.keepFeedTime)
 
+	// Quarantine of documents rejected in strict mode
+	api.GET("/sources/quarantine", authSM, c.viewQuarantine)
+	api.GET("/sources/quarantine/:id", authSM, c.viewQuarantined)
+	api.GET("/sources/quarantine/:id/document", authSM, c.viewQuarantinedDocument)
+	api.GET("/sources/quarantine/:id/diff", authSM, c.viewQuarantineDiff)
+	api.POST("/sources/quarantine/:id/release", authSM, c.releaseQuarantined)
+	api.DELETE("/sources/quarantine/:id", authSM, c.discardQuarantined)
+
 	// Import stats
 	api.GET("/stats/imports/source/:id", authAll, c.importStatsSource)
 	api.GET("/stats/imports/feed/:id", authAll, c.importStatsFeed)
@@ -216,6 +260,8 @@ func (c *Controller) Bind() http.Handler {
 	api.GET("/aggregators/:id", authAuEdSM, c.viewAggregator)
 	api.PUT("/aggregators/:id", authSM, c.updateAggregator)
 	api.GET("/aggregators/attention", authSM, c.attentionAggregators)
+	api.GET("/aggregators/:id/changes", authAuEdSM, c.viewAggregatorChanges)
+	api.PUT("/aggregators/:id/changes/:change", authSM, c.updateAggregatorChange)
 	api.POST("/aggregators", authSM, c.createAggregator)
 	api.DELETE("/aggregators/:id", authSM, c.deleteAggregator)
 
@@ -0,0 +1,218 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ISDuBA/ISDuBA/pkg/models"
+)
+
+// queryCostError is returned if the plan of a query is too expensive.
+type queryCostError struct {
+	cost, limit float64
+}
+
+// Error implements [error].
+func (qce *queryCostError) Error() string {
+	return fmt.Sprintf(
+		"the estimated cost %.0f of the query exceeds the maximum of %.0f; "+
+			"narrow the filter e.g. by publisher or release dates, "+
+			"prefer comparisons over ilike and searches or lower the limit",
+		qce.cost, qce.limit)
+}
+
+// queryGuard checks the costs of user provided queries before running
+// them and logs them if they took long.
+type queryGuard struct {
+	ctrl   *Controller
+	actor  string
+	source string
+}
+
+// newQueryGuard creates a guard for the given query in the query language.
+func (c *Controller) newQueryGuard(ctx *gin.Context, source string) *queryGuard {
+	return &queryGuard{
+		ctrl:   c,
+		actor:  ctx.GetString("uid"),
+		source: source,
+	}
+}
+
+// explainCost returns the estimated total cost and the plan of an SQL statement.
+func explainCost(
+	rctx context.Context,
+	conn *pgxpool.Conn,
+	sql string,
+	args []any,
+) (float64, json.RawMessage, error) {
+	var plan json.RawMessage
+	if err := conn.QueryRow(
+		rctx, "EXPLAIN (FORMAT JSON) "+sql, args...,
+	).Scan(&plan); err != nil {
+		return 0, nil, fmt.Errorf("explaining query failed: %w", err)
+	}
+	var plans []struct {
+		Plan struct {
+			TotalCost float64 `json:"Total Cost"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &plans); err != nil || len(plans) == 0 {
+		return 0, nil, fmt.Errorf("unexpected query plan: %w", err)
+	}
+	return plans[0].Plan.TotalCost, plan, nil
+}
+
+// query checks the cost of an SQL statement and runs it passing the rows
+// to the given function. If it took long it is logged.
+func (qg *queryGuard) query(
+	rctx context.Context,
+	conn *pgxpool.Conn,
+	sql string,
+	args []any,
+	fn func(pgx.Rows) error,
+) error {
+	var cost *float64
+	if limit := qg.ctrl.cfg.Database.MaxQueryCost; limit > 0 {
+		c, _, err := explainCost(rctx, conn, sql, args)
+		if err != nil {
+			return err
+		}
+		if c > limit {
+			return &queryCostError{cost: c, limit: limit}
+		}
+		cost = &c
+	}
+	start := time.Now()
+	err := func() error {
+		rows, err := conn.Query(rctx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("cannot fetch results: %w", err)
+		}
+		defer rows.Close()
+		return fn(rows)
+	}()
+	if threshold := qg.ctrl.cfg.Database.SlowQueryDuration; threshold > 0 {
+		if duration := time.Since(start); duration >= threshold {
+			qg.logSlow(rctx, sql, duration, cost, err != nil)
+		}
+	}
+	return err
+}
+
+// count checks the cost of a counting SQL statement and returns the count.
+func (qg *queryGuard) count(
+	rctx context.Context,
+	conn *pgxpool.Conn,
+	sql string,
+	args []any,
+) (int64, error) {
+	var n int64
+	err := qg.query(rctx, conn, sql, args, func(rows pgx.Rows) error {
+		var err error
+		n, err = pgx.CollectExactlyOneRow(rows, pgx.RowTo[int64])
+		return err
+	})
+	return n, err
+}
+
+// logSlow stores a slow query in the database.
+func (qg *queryGuard) logSlow(
+	rctx context.Context,
+	sql string,
+	duration time.Duration,
+	cost *float64,
+	failed bool,
+) {
+	const insertSQL = `INSERT INTO slow_queries ` +
+		`(actor, source, sql, duration, cost, failed) ` +
+		`VALUES ($1, $2, $3, $4, $5, $6)`
+	slog.Warn("slow query", "actor", qg.actor, "query", qg.source, "duration", duration)
+	// The request may be already cancelled because it took too long.
+	if err := qg.ctrl.db.Run(
+		context.WithoutCancel(rctx),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			_, err := conn.Exec(rctx, insertSQL,
+				qg.actor, qg.source, sql, duration, cost, failed)
+			return err
+		}, 0,
+	); err != nil {
+		slog.Error("logging slow query failed", "err", err)
+	}
+}
+
+// sendQueryError sends the error of running a user provided query.
+func sendQueryError(ctx *gin.Context, err error) {
+	if qce := (*queryCostError)(nil); errors.As(err, &qce) {
+		models.SendError(ctx, http.StatusBadRequest, qce)
+		return
+	}
+	slog.Error("database error", "err", err)
+	models.SendError(ctx, http.StatusInternalServerError, err)
+}
+
+// requestedExplain checks if the plan of the query should be returned
+// instead of the results. Only admins are allowed to do so.
+func (c *Controller) requestedExplain(ctx *gin.Context) (bool, bool) {
+	explain, ok := parse(ctx, strconv.ParseBool, ctx.DefaultQuery("explain", "false"))
+	if !ok {
+		return false, false
+	}
+	if explain && !c.hasAnyRole(ctx, models.Admin) {
+		models.SendErrorMessage(ctx, http.StatusForbidden, "explain is only allowed for admins")
+		return false, false
+	}
+	return explain, true
+}
+
+// explain sends the plan of the SQL statement.
+func (c *Controller) explain(ctx *gin.Context, sql string, args []any) {
+	var (
+		cost float64
+		plan json.RawMessage
+	)
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			var err error
+			cost, plan, err = explainCost(rctx, conn, sql, args)
+			return err
+		},
+		c.cfg.Database.MaxQueryDuration,
+	); err != nil {
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	type explanation struct {
+		SQL          string          `json:"sql"`
+		Replacements []any           `json:"replacements"`
+		Cost         float64         `json:"cost"`
+		MaxCost      float64         `json:"max_cost,omitempty"`
+		Plan         json.RawMessage `json:"plan"`
+	}
+	ctx.JSON(http.StatusOK, explanation{
+		SQL:          sql,
+		Replacements: args,
+		Cost:         cost,
+		MaxCost:      c.cfg.Database.MaxQueryCost,
+		Plan:         plan,
+	})
+}
@@ -136,6 +136,12 @@ func (c *Controller) viewDiff(ctx *gin.Context) {
 		*f.doc = data
 	}
 
+	sendDiff(ctx, doc)
+}
+
+// sendDiff sends the JSON patch between two documents.
+// The query parameters select a specific operation item or word diffing.
+func sendDiff(ctx *gin.Context, doc [2][]byte) {
 	// Create the patch.
 	patch, err := jsonpatch.CreatePatch(doc[0], doc[1])
 	if err != nil {
@@ -22,7 +22,6 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gocsaf/csaf/v3/csaf"
 	"github.com/gocsaf/csaf/v3/util"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -109,12 +108,12 @@ func (c *Controller) deleteDocument(ctx *gin.Context) {
 // importDocument is an end point to import a document.
 //
 //	@Summary		Imports a CSAF document.
-//	@Description	Upload endpoint for CSAF documents.
+//	@Description	Upload endpoint for CSAF documents. The validation reports of documents rejected as not valid are recorded, too.
 //	@Param			file	formData	file	true	"Document file"
 //	@Accept			multipart/form-data
 //	@Produce		json
 //	@Success		201	{object}	models.ID
-//	@Failure		400	{object}	models.Error
+//	@Failure		400	{object}	models.ValidationError	"Not valid"
 //	@Failure		401
 //	@Failure		403	{object}	models.Error	"False TLP or publisher"
 //	@Failure		409	{object}	models.Error	"Already in database"
@@ -149,14 +148,15 @@ func (c *Controller) importDocument(ctx *gin.Context) {
 		return
 	}
 
-	msgs, err := csaf.ValidateCSAF(document)
-	if err != nil {
+	report := models.NewValidationReport()
+	if err := report.CheckSchema(document); err != nil {
+		c.storeRejectedImport(ctx.Request.Context(), report)
 		models.SendErrorMessage(ctx, http.StatusBadRequest, "schema validation failed: "+err.Error())
 		return
 	}
-	if len(msgs) > 0 {
-		models.SendErrorMessage(ctx, http.StatusBadRequest,
-			"schema validation failed: "+strings.Join(msgs, ", "))
+	if report.SchemaFailed() {
+		c.storeRejectedImport(ctx.Request.Context(), report)
+		sendValidationError(ctx, report.SchemaErr(), report)
 		return
 	}
 
@@ -165,16 +165,19 @@ func (c *Controller) importDocument(ctx *gin.Context) {
 		rvr, err := c.val.Validate(document)
 		if err != nil {
 			slog.Error("remote validation failed", "err", err)
+			report.AddRemote(nil, err)
+			c.storeRejectedImport(ctx.Request.Context(), report)
 			models.SendErrorMessage(ctx, http.StatusInternalServerError,
 				"remote validation failed: "+err.Error())
 			return
 		}
-		if !rvr.Valid {
-			// XXX: Maybe we should tell, what's exactly wrong?
-			models.SendErrorMessage(ctx, http.StatusBadRequest, "remote validation failed")
+		if report.AddRemote(rvr, nil); report.RemoteFailed() {
+			c.storeRejectedImport(ctx.Request.Context(), report)
+			sendValidationError(ctx, report.RemoteErr(), report)
 			return
 		}
 	}
+	report.CheckFilename(document, file.Filename)
 
 	// Store stats in database.
 	storeStats := func(ctx context.Context, tx pgx.Tx, docID int64, duplicate bool) error {
@@ -182,9 +185,9 @@ func (c *Controller) importDocument(ctx *gin.Context) {
 			return nil
 		}
 		const insertSQL = `INSERT INTO downloads ` +
-			`(documents_id, feeds_id) VALUES ($1, ` +
-			`(SELECT id FROM feeds WHERE sources_id = 0 AND label = 'single'))`
-		_, err := tx.Exec(ctx, insertSQL, docID)
+			`(documents_id, feeds_id, validation_report) VALUES ($1, ` +
+			`(SELECT id FROM feeds WHERE sources_id = 0 AND label = 'single'), $2)`
+		_, err := tx.Exec(ctx, insertSQL, docID, report)
 		return err
 	}
 	var id int64
@@ -344,15 +347,20 @@ func (c *Controller) forwardDocument(ctx *gin.Context) {
 //	@Description	Returns all documents that match the specified query.
 //	@Param			advisories	query	bool	false	"Return advisories"
 //	@Param			query		query	string	false	"Document query"
+//	@Param			syntax		query	string	false	"Query syntax (rpn or infix)"
 //	@Param			columns		query	string	false	"Columns"
 //	@Param			orders		query	string	false	"Ordering"
 //	@Param			count		query	bool	false	"Enable counting"
 //	@Param			limit		query	int		false	"Maximum documents"
 //	@Param			offset		query	int		false	"Offset"
+//	@Param			cursor		query	string	false	"Page after this cursor, empty for the first page"
 //	@Param			results		query	bool	false	"Return search results"
+//	@Param			stored		query	int		false	"Stored query to run"
+//	@Param			params		query	object	false	"Arguments of the stored query as params[name]=value"
+//	@Param			explain		query	bool	false	"Return the query plan instead of the results (admins only)"
 //	@Produce		json
 //	@Success		200	{object}	web.flatResults.documentResult
-//	@Failure		400	{object}	models.Error
+//	@Failure		400	{object}	web.parseError
 //	@Failure		401
 //	@Failure		500	{object}	models.Error
 //	@Router			/documents [get]
@@ -374,14 +382,47 @@ func (c *Controller) overviewDocuments(ctx *gin.Context) {
 		mode = query.AdvisoryMode
 	}
 
+	var (
+		queryDefault   = "true"
+		ordersDefault  = "publisher tracking_id -current_release_date -rev_history_length"
+		columnsDefault = "id title tracking_id version publisher"
+	)
+
+	// A stored query provides the defaults and the declared parameters.
+	sq, ok := c.requestedStoredQuery(ctx, query.DocumentMode, query.AdvisoryMode)
+	if !ok {
+		return
+	}
+
 	parser := query.Parser{
 		Mode:            mode,
 		MinSearchLength: MinSearchLength,
 		Me:              ctx.GetString("uid"),
 	}
 
+	if sq != nil {
+		mode, advisory = sq.Kind, sq.Kind == query.AdvisoryMode
+		parser.Mode = mode
+		parser.Syntax = sq.Syntax
+		parser.Parameters = sq.Parameters
+		parser.Arguments = ctx.QueryMap("params")
+		queryDefault = sq.Query
+		columnsDefault = strings.Join(sq.Columns, " ")
+		if sq.Orders != nil {
+			ordersDefault = strings.Join(*sq.Orders, " ")
+		}
+	}
+
+	// The notation of the query.
+	if syntax, ok := ctx.GetQuery("syntax"); ok {
+		if parser.Syntax, ok = parse(ctx, query.ParseSyntax, syntax); !ok {
+			return
+		}
+	}
+
 	// The query to filter the documents.
-	expr, ok := parse(ctx, parser.Parse, ctx.DefaultQuery("query", "true"))
+	source := ctx.DefaultQuery("query", queryDefault)
+	expr, ok := parse(ctx, parser.Parse, source)
 	if !ok {
 		return
 	}
@@ -394,10 +435,7 @@ func (c *Controller) overviewDocuments(ctx *gin.Context) {
 		expr = expr.And(query.BoolField("latest"))
 	}
 
-	orderFields := strings.Fields(
-		ctx.DefaultQuery(
-			"orders",
-			"publisher tracking_id -current_release_date -rev_history_length"))
+	orderFields := strings.Fields(ctx.DefaultQuery("orders", ordersDefault))
 	if aggregate {
 		if !slices.Contains(orderFields, "id") {
 			orderFields = append(orderFields, "id")
@@ -405,8 +443,19 @@ func (c *Controller) overviewDocuments(ctx *gin.Context) {
 		orderFields = slices.AppendSeq(orderFields, expr.Aliases())
 	}
 
-	fields := strings.Fields(
-		ctx.DefaultQuery("columns", "id title tracking_id version publisher"))
+	// Paging by cursor needs the id to order the rows uniquely.
+	var cursor *query.Cursor
+	if cur, ok := ctx.GetQuery("cursor"); ok {
+		if cursor, ok = parse(ctx, query.ParseCursor, cur); !ok {
+			return
+		}
+		// In aggregation mode the id is already there.
+		if !slices.Contains(orderFields, "id") && !slices.Contains(orderFields, "-id") {
+			orderFields = append(orderFields, "id")
+		}
+	}
+
+	fields := strings.Fields(ctx.DefaultQuery("columns", columnsDefault))
 
 	// If we are in aggregation mode we need the id.
 	if aggregate && !slices.Contains(fields, "id") {
@@ -418,7 +467,8 @@ func (c *Controller) overviewDocuments(ctx *gin.Context) {
 		query.AdvancedSQLBuilderOrderFields(orderFields),
 		query.AdvancedSQLBuilderFields(fields),
 		query.AdvancedSQLBuilderParser(&parser),
-		query.AdvancedSQLBuilderAggregate(aggregate))
+		query.AdvancedSQLBuilderAggregate(aggregate),
+		query.AdvancedSQLBuilderCursor(cursor))
 
 	if err != nil {
 		models.SendError(ctx, http.StatusBadRequest, err)
@@ -442,11 +492,20 @@ func (c *Controller) overviewDocuments(ctx *gin.Context) {
 		}
 	}
 
+	explain, ok := c.requestedExplain(ctx)
+	if !ok {
+		return
+	}
+	if explain {
+		c.explain(ctx, builder.CreateQuery(limit, offset), builder.Replacements)
+		return
+	}
+
 	deliver := (*Controller).flatResults
 	if aggregate {
 		deliver = (*Controller).aggregatedResults
 	}
-	deliver(c, ctx, calcCount, limit, offset, builder)
+	deliver(c, ctx, calcCount, limit, offset, builder, c.newQueryGuard(ctx, source))
 }
 
 func (c *Controller) flatResults(
@@ -454,10 +513,12 @@ func (c *Controller) flatResults(
 	calcCount bool,
 	limit, offset int64,
 	builder *query.AdvancedSQLBuilder,
+	guard *queryGuard,
 ) {
 	type documentResult struct {
-		Count     *int64           `json:"count,omitempty"`
-		Documents []map[string]any `json:"documents"`
+		Count      *int64           `json:"count,omitempty"`
+		Documents  []map[string]any `json:"documents"`
+		NextCursor string           `json:"next_cursor,omitempty"`
 	}
 	var (
 		results []map[string]any
@@ -471,11 +532,8 @@ func (c *Controller) flatResults(
 				if slog.Default().Enabled(rctx, slog.LevelDebug) {
 					slog.Debug("count", "SQL", query.InterpolateSQLqnd(countSQL, builder.Replacements))
 				}
-				if err := conn.QueryRow(
-					rctx,
-					countSQL,
-					builder.Replacements...,
-				).Scan(&count); err != nil {
+				var err error
+				if count, err = guard.count(rctx, conn, countSQL, builder.Replacements); err != nil {
 					return fmt.Errorf("cannot calculate count %w", err)
 				}
 			}
@@ -489,20 +547,18 @@ func (c *Controller) flatResults(
 			if slog.Default().Enabled(rctx, slog.LevelDebug) {
 				slog.Debug("documents", "SQL", query.InterpolateSQLqnd(sql, builder.Replacements))
 			}
-			rows, err := conn.Query(rctx, sql, builder.Replacements...)
-			if err != nil {
-				return fmt.Errorf("cannot fetch results: %w", err)
-			}
-			defer rows.Close()
-			if results, err = scanRows(rows, builder.Fields()); err != nil {
-				return fmt.Errorf("loading data failed: %w", err)
-			}
-			return nil
+			fields := slices.Concat(builder.Fields(), builder.CursorFields())
+			return guard.query(rctx, conn, sql, builder.Replacements, func(rows pgx.Rows) error {
+				var err error
+				if results, err = scanRows(rows, fields); err != nil {
+					return fmt.Errorf("loading data failed: %w", err)
+				}
+				return nil
+			})
 		},
 		c.cfg.Database.MaxQueryDuration, // In case the user provided a very expensive query.
 	); err != nil {
-		slog.Error("database error", "err", err)
-		models.SendError(ctx, http.StatusInternalServerError, err)
+		sendQueryError(ctx, err)
 		return
 	}
 
@@ -510,6 +566,14 @@ func (c *Controller) flatResults(
 	if calcCount {
 		h.Count = &count
 	}
+	var next *query.Cursor
+	for _, row := range results {
+		next = builder.NextCursor(row)
+	}
+	// Only full pages have a next one.
+	if next != nil && limit >= 0 && int64(len(results)) == limit {
+		h.NextCursor = next.String()
+	}
 	if len(results) > 0 {
 		h.Documents = results
 	}
@@ -30,9 +30,12 @@ import (
 //	@Summary		Returns a list of events.
 //	@Description	Returns all events that match the specified query.
 //	@Param			query	query	string	false	"Event query"
+//	@Param			syntax	query	string	false	"Query syntax (rpn or infix)"
+//	@Param			stored	query	int		false	"Stored query to run"
+//	@Param			params	query	object	false	"Arguments of the stored query as params[name]=value"
 //	@Produce		json
 //	@Success		200	{object}	web.overviewEvents.events
-//	@Failure		400	{object}	models.Error
+//	@Failure		400	{object}	web.parseError
 //	@Failure		401
 //	@Failure		404	{object}	models.Error
 //	@Failure		500	{object}	models.Error
@@ -44,8 +47,37 @@ func (c *Controller) overviewEvents(ctx *gin.Context) {
 		Me:              ctx.GetString("uid"),
 	}
 
+	var (
+		queryDefault   = "true"
+		ordersDefault  = "-time"
+		columnsDefault = "event event_state time actor comments_id message id"
+	)
+
+	// A stored query provides the defaults and the declared parameters.
+	sq, ok := c.requestedStoredQuery(ctx, query.EventMode)
+	if !ok {
+		return
+	}
+	if sq != nil {
+		parser.Syntax = sq.Syntax
+		parser.Parameters = sq.Parameters
+		parser.Arguments = ctx.QueryMap("params")
+		queryDefault = sq.Query
+		columnsDefault = strings.Join(sq.Columns, " ")
+		if sq.Orders != nil {
+			ordersDefault = strings.Join(*sq.Orders, " ")
+		}
+	}
+
+	// The notation of the query.
+	if syntax, ok := ctx.GetQuery("syntax"); ok {
+		if parser.Syntax, ok = parse(ctx, query.ParseSyntax, syntax); !ok {
+			return
+		}
+	}
+
 	// The query to filter the documents.
-	expr, ok := parse(ctx, parser.Parse, ctx.DefaultQuery("query", "true"))
+	expr, ok := parse(ctx, parser.Parse, ctx.DefaultQuery("query", queryDefault))
 	if !ok {
 		return
 	}
@@ -56,15 +88,14 @@ func (c *Controller) overviewEvents(ctx *gin.Context) {
 	builder := query.SQLBuilder{Mode: query.EventMode}
 	builder.CreateWhere(expr)
 
-	fields := strings.Fields(
-		ctx.DefaultQuery("columns", "event event_state time actor comments_id message id"))
+	fields := strings.Fields(ctx.DefaultQuery("columns", columnsDefault))
 
 	if err := builder.CheckProjections(fields); err != nil {
 		models.SendError(ctx, http.StatusBadRequest, err)
 		return
 	}
 
-	orderFields := strings.Fields(ctx.DefaultQuery("orders", "-time"))
+	orderFields := strings.Fields(ctx.DefaultQuery("orders", ordersDefault))
 	order, err := builder.CreateOrder(orderFields)
 	if err != nil {
 		models.SendError(ctx, http.StatusBadRequest, err)
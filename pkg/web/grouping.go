@@ -0,0 +1,197 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package web
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ISDuBA/ISDuBA/pkg/database/query"
+	"github.com/ISDuBA/ISDuBA/pkg/models"
+)
+
+// parseGrouping parses the groups, aggregates and orders of an aggregating query.
+func parseGrouping(groups, aggregates, orders string) (*query.Grouping, error) {
+	grouping := query.Grouping{Orders: strings.Fields(orders)}
+	for _, s := range strings.Fields(groups) {
+		group, err := query.ParseGroup(s)
+		if err != nil {
+			return nil, err
+		}
+		grouping.Groups = append(grouping.Groups, group)
+	}
+	for _, s := range strings.Fields(aggregates) {
+		aggregate, err := query.ParseAggregate(s)
+		if err != nil {
+			return nil, err
+		}
+		grouping.Aggregates = append(grouping.Aggregates, aggregate)
+	}
+	return &grouping, nil
+}
+
+// aggregateDocuments is an end point to return aggregated values
+// of the documents grouped by columns.
+//
+//	@Summary		Returns aggregated document values.
+//	@Description	Groups the documents matching the query and aggregates values per group.
+//	@Param			advisories	query	bool	false	"Group advisories"
+//	@Param			query		query	string	false	"Document query"
+//	@Param			syntax		query	string	false	"Query syntax (rpn or infix)"
+//	@Param			group		query	string	false	"Columns to group by, timestamps as column:bucket"
+//	@Param			aggregates	query	string	false	"Aggregates as count or function:column"
+//	@Param			orders		query	string	false	"Ordering by groups or aggregates"
+//	@Param			count		query	bool	false	"Enable counting of groups"
+//	@Param			limit		query	int		false	"Maximum groups"
+//	@Param			offset		query	int		false	"Offset"
+//	@Param			explain		query	bool	false	"Return the query plan instead of the groups (admins only)"
+//	@Produce		json
+//	@Success		200	{object}	web.aggregateDocuments.groupsResult
+//	@Failure		400	{object}	web.parseError
+//	@Failure		401
+//	@Failure		500	{object}	models.Error
+//	@Router			/documents/aggregate [get]
+func (c *Controller) aggregateDocuments(ctx *gin.Context) {
+	advisory, ok := parse(ctx, strconv.ParseBool, ctx.DefaultQuery("advisories", "false"))
+	if !ok {
+		return
+	}
+
+	mode := query.DocumentMode
+	if advisory {
+		mode = query.AdvisoryMode
+	}
+
+	parser := query.Parser{
+		Mode:            mode,
+		MinSearchLength: MinSearchLength,
+		Me:              ctx.GetString("uid"),
+	}
+
+	// The notation of the query.
+	if syntax, ok := ctx.GetQuery("syntax"); ok {
+		if parser.Syntax, ok = parse(ctx, query.ParseSyntax, syntax); !ok {
+			return
+		}
+	}
+
+	// The query to filter the documents.
+	source := ctx.DefaultQuery("query", "true")
+	expr, ok := parse(ctx, parser.Parse, source)
+	if !ok {
+		return
+	}
+
+	// Filter the allowed
+	expr = c.andTLPExpr(ctx, expr)
+
+	// In advisory mode we only aggregate the latest.
+	if advisory {
+		expr = expr.And(query.BoolField("latest"))
+	}
+
+	grouping, err := parseGrouping(
+		ctx.Query("group"),
+		ctx.DefaultQuery("aggregates", "count"),
+		ctx.Query("orders"))
+	if err != nil {
+		models.SendError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	builder, err := query.NewAdvancedSQLBuilder(
+		query.AdvancedSQLBuilderExpr(expr),
+		query.AdvancedSQLBuilderParser(&parser),
+		query.AdvancedSQLBuilderGrouping(grouping))
+	if err != nil {
+		models.SendError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	var (
+		calcCount           = ctx.Query("count") != ""
+		limit, offset int64 = -1, -1
+	)
+
+	if lim := ctx.Query("limit"); lim != "" {
+		if limit, ok = parse(ctx, toInt64, lim); !ok {
+			return
+		}
+	}
+
+	if ofs := ctx.Query("offset"); ofs != "" {
+		if offset, ok = parse(ctx, toInt64, ofs); !ok {
+			return
+		}
+	}
+
+	explain, ok := c.requestedExplain(ctx)
+	if !ok {
+		return
+	}
+	if explain {
+		c.explain(ctx, builder.CreateGroupQuery(limit, offset), builder.Replacements)
+		return
+	}
+
+	guard := c.newQueryGuard(ctx, source)
+
+	type groupsResult struct {
+		Count  *int64           `json:"count,omitempty"`
+		Groups []map[string]any `json:"groups"`
+	}
+	var (
+		results []map[string]any
+		count   int64
+	)
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			if calcCount {
+				countSQL := builder.CreateGroupCountSQL()
+				var err error
+				if count, err = guard.count(rctx, conn, countSQL, builder.Replacements); err != nil {
+					return fmt.Errorf("cannot calculate count %w", err)
+				}
+			}
+			sql := builder.CreateGroupQuery(limit, offset)
+			if slog.Default().Enabled(rctx, slog.LevelDebug) {
+				slog.Debug("aggregate", "SQL", query.InterpolateSQLqnd(sql, builder.Replacements))
+			}
+			return guard.query(rctx, conn, sql, builder.Replacements, func(rows pgx.Rows) error {
+				var err error
+				if results, err = scanRows(rows, builder.GroupFields()); err != nil {
+					return fmt.Errorf("loading data failed: %w", err)
+				}
+				return nil
+			})
+		},
+		c.cfg.Database.MaxQueryDuration, // In case the user provided a very expensive query.
+	); err != nil {
+		sendQueryError(ctx, err)
+		return
+	}
+
+	h := groupsResult{Groups: []map[string]any{}}
+	if calcCount {
+		h.Count = &count
+	}
+	if len(results) > 0 {
+		h.Groups = results
+	}
+	ctx.JSON(http.StatusOK, h)
+}
@@ -0,0 +1,195 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package web
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ISDuBA/ISDuBA/pkg/archive"
+	"github.com/ISDuBA/ISDuBA/pkg/imports"
+	"github.com/ISDuBA/ISDuBA/pkg/models"
+)
+
+// multipartOverhead is the room left for the framing
+// of the multipart form around an uploaded archive.
+const multipartOverhead = 1024 * 1024
+
+// importJobsUser returns the user whose import jobs are visible.
+// Admins see the jobs of all users.
+func (c *Controller) importJobsUser(ctx *gin.Context) string {
+	if c.hasAnyRole(ctx, models.Admin) {
+		return ""
+	}
+	return ctx.GetString("uid")
+}
+
+// sendImportJobError sends the errors of the import manager.
+func sendImportJobError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, imports.ErrJobNotFound):
+		models.SendErrorMessage(ctx, http.StatusNotFound, "import job not found")
+	case errors.Is(err, imports.ErrJobDone):
+		models.SendErrorMessage(ctx, http.StatusConflict, "import job already done")
+	case errors.Is(err, imports.ErrTooManyJobs):
+		models.SendErrorMessage(ctx, http.StatusTooManyRequests, "too many import jobs")
+	default:
+		slog.Error("import job failed", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+// createImportJob is an endpoint that starts the import of an archive.
+//
+//	@Summary		Imports an archive of CSAF documents.
+//	@Description	Uploads a zip or tar archive and imports its documents in a background job.
+//	@Param			file	formData	file	true	"Archive (.zip, .tar, .tar.gz, .tgz, .tar.zst or .tzst)"
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Success		202	{object}	models.ID
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		413	{object}	models.Error	"Archive too large"
+//	@Failure		429	{object}	models.Error	"Too many import jobs"
+//	@Failure		500	{object}	models.Error
+//	@Router			/imports [post]
+func (c *Controller) createImportJob(ctx *gin.Context) {
+	var actor *string
+	if user := c.currentUser(ctx); user.Valid {
+		actor = &user.String
+	}
+
+	// Limit the body before the form is parsed as parsing
+	// stores the whole upload. The multipart framing needs some extra bytes.
+	limit := int64(c.cfg.Imports.ArchiveUploadLimit)
+	ctx.Request.Body = http.MaxBytesReader(
+		ctx.Writer, ctx.Request.Body, limit+multipartOverhead)
+
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		if mbe := (*http.MaxBytesError)(nil); errors.As(err, &mbe) {
+			models.SendErrorMessage(ctx, http.StatusRequestEntityTooLarge, "archive too large")
+		} else {
+			models.SendError(ctx, http.StatusBadRequest, err)
+		}
+		return
+	}
+	if !archive.IsArchive(file.Filename) {
+		models.SendErrorMessage(ctx, http.StatusBadRequest, "unsupported archive format")
+		return
+	}
+	if file.Size > limit {
+		models.SendErrorMessage(ctx, http.StatusRequestEntityTooLarge, "archive too large")
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		models.SendError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	limited := http.MaxBytesReader(ctx.Writer, f, limit)
+	defer limited.Close()
+
+	// The job outlives the request so the archive has to be copied.
+	tmp, err := os.CreateTemp("", "isduba-import-*")
+	if err != nil {
+		slog.Error("creating temporary file failed", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if _, err := io.Copy(tmp, limited); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		models.SendError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		slog.Error("writing temporary file failed", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	id, err := c.im.Submit(
+		ctx.GetString("uid"), actor, c.tlps(ctx),
+		file.Filename, tmp.Name())
+	if err != nil {
+		os.Remove(tmp.Name())
+		sendImportJobError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusAccepted, models.ID{ID: id})
+}
+
+// listImportJobs is an endpoint that returns the import jobs.
+//
+//	@Summary		Returns the import jobs.
+//	@Description	Returns the import jobs of the user without the results per file. Admins see the jobs of all users.
+//	@Produce		json
+//	@Success		200	{array}	imports.Job
+//	@Failure		401
+//	@Router			/imports [get]
+func (c *Controller) listImportJobs(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.im.Jobs(c.importJobsUser(ctx)))
+}
+
+// viewImportJob is an endpoint that returns the status of an import job.
+//
+//	@Summary		Returns an import job.
+//	@Description	Returns the status of an import job with the results per file.
+//	@Param			id	path	int	true	"Job ID"
+//	@Produce		json
+//	@Success		200	{object}	imports.Job
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		404	{object}	models.Error
+//	@Failure		500	{object}	models.Error
+//	@Router			/imports/{id} [get]
+func (c *Controller) viewImportJob(ctx *gin.Context) {
+	id, ok := parse(ctx, toInt64, ctx.Param("id"))
+	if !ok {
+		return
+	}
+	job, err := c.im.Job(id, c.importJobsUser(ctx))
+	if err != nil {
+		sendImportJobError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, &job)
+}
+
+// cancelImportJob is an endpoint that cancels an import job.
+//
+//	@Summary		Cancels an import job.
+//	@Description	Cancels a queued or running import job. Documents already imported are kept.
+//	@Param			id	path	int	true	"Job ID"
+//	@Produce		json
+//	@Success		200	{object}	models.Success
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		404	{object}	models.Error
+//	@Failure		409	{object}	models.Error	"Already done"
+//	@Failure		500	{object}	models.Error
+//	@Router			/imports/{id} [delete]
+func (c *Controller) cancelImportJob(ctx *gin.Context) {
+	id, ok := parse(ctx, toInt64, ctx.Param("id"))
+	if !ok {
+		return
+	}
+	if err := c.im.Cancel(id, c.importJobsUser(ctx)); err != nil {
+		sendImportJobError(ctx, err)
+		return
+	}
+	models.SendSuccess(ctx, http.StatusOK, "cancelled")
+}
@@ -0,0 +1,481 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocsaf/csaf/v3/csaf"
+	"github.com/gocsaf/csaf/v3/util"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ISDuBA/ISDuBA/pkg/models"
+)
+
+// mirrorDocument are the meta data of a document published by the mirror.
+type mirrorDocument struct {
+	id         int64
+	publisher  string
+	trackingID string
+	title      string
+	tlp        models.TLP
+	initial    time.Time
+	current    time.Time
+	signed     bool
+}
+
+// year returns the folder of the document.
+func (md *mirrorDocument) year() string {
+	if !md.initial.IsZero() {
+		return strconv.Itoa(md.initial.Year())
+	}
+	return strconv.Itoa(md.current.Year())
+}
+
+// filename returns the file name of the document.
+func (md *mirrorDocument) filename() string {
+	return util.CleanFileName(md.trackingID)
+}
+
+// path returns the path of the document relative to its TLP folder.
+func (md *mirrorDocument) path() string {
+	return md.year() + "/" + md.filename()
+}
+
+// mirrorFeedName returns the file name of the ROLIE feed of a TLP.
+func mirrorFeedName(tlp models.TLP) string {
+	return "csaf-feed-tlp-" + strings.ToLower(string(tlp)) + ".json"
+}
+
+// mirrorTLPs returns the TLPs which are published by the mirror.
+func (c *Controller) mirrorTLPs() []models.TLP {
+	var tlps []models.TLP
+	for _, tlp := range []models.TLP{
+		models.TLPWhite,
+		models.TLPGreen,
+		models.TLPAmber,
+		models.TLPRed,
+	} {
+		for _, allowed := range c.cfg.Mirror.PublishersTLPs {
+			if slices.Contains(allowed, tlp) {
+				tlps = append(tlps, tlp)
+				break
+			}
+		}
+	}
+	return tlps
+}
+
+// mirrorBaseURL returns the absolute URL of the mirror.
+// It is never derived from the request as the mirror
+// is served without authentication.
+func (c *Controller) mirrorBaseURL() string {
+	return strings.TrimSuffix(c.cfg.Web.ExternalURL, "/") + c.cfg.Mirror.Path
+}
+
+// mirrorDocumentsSQL selects the latest documents of a TLP of the mirrored sources.
+// Documents without a current release date are left out as they
+// can not be placed in the directory layout.
+const mirrorDocumentsSQL = `SELECT d.id, a.publisher, a.tracking_id, coalesce(d.title, ''), d.tlp, ` +
+	`d.initial_release_date, d.current_release_date, d.signature IS NOT NULL ` +
+	`FROM documents d JOIN advisories a ON d.advisories_id = a.id ` +
+	`WHERE d.latest AND d.tlp = $1 AND d.current_release_date IS NOT NULL AND EXISTS (` +
+	`SELECT 1 FROM downloads dl ` +
+	`JOIN feeds f ON dl.feeds_id = f.id ` +
+	`JOIN sources s ON f.sources_id = s.id ` +
+	`WHERE dl.documents_id = d.id AND (cardinality($2::text[]) = 0 OR s.name = ANY($2)))`
+
+// mirrorDocumentsOrder orders the documents by their current release dates, newest first.
+const mirrorDocumentsOrder = ` ORDER BY d.current_release_date DESC, a.publisher, a.tracking_id`
+
+// queryMirrorDocuments returns the latest documents of the mirrored sources
+// which are allowed to be published. The extra condition and its
+// arguments following the TLP and the sources narrow the selection.
+func (c *Controller) queryMirrorDocuments(
+	ctx context.Context,
+	tlp models.TLP,
+	extra string,
+	args ...any,
+) ([]*mirrorDocument, error) {
+	sql := mirrorDocumentsSQL + extra + mirrorDocumentsOrder
+	args = append([]any{string(tlp), c.cfg.Mirror.Sources}, args...)
+
+	var docs []*mirrorDocument
+	if err := c.db.Run(
+		ctx,
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			rows, err := conn.Query(rctx, sql, args...)
+			if err != nil {
+				return err
+			}
+			docs, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (*mirrorDocument, error) {
+				var (
+					md               mirrorDocument
+					initial, current *time.Time
+				)
+				if err := row.Scan(
+					&md.id, &md.publisher, &md.trackingID, &md.title, &md.tlp,
+					&initial, &current, &md.signed,
+				); err != nil {
+					return nil, err
+				}
+				if current != nil {
+					md.current = current.UTC()
+				}
+				// Without an initial release date the current one is used.
+				if initial != nil {
+					md.initial = initial.UTC()
+				} else {
+					md.initial = md.current
+				}
+				return &md, nil
+			})
+			return err
+		}, 0,
+	); err != nil {
+		return nil, err
+	}
+	// Apply the access rules and drop documents with the same path.
+	// Documents of different publishers may have the same tracking ID.
+	seen := map[string]*mirrorDocument{}
+	return slices.DeleteFunc(docs, func(md *mirrorDocument) bool {
+		if !c.cfg.Mirror.PublishersTLPs.Allowed(md.publisher, md.tlp) {
+			return true
+		}
+		path := md.path()
+		if other := seen[path]; other != nil {
+			if other.publisher != md.publisher {
+				slog.Warn("mirror: tracking ID collision",
+					"tlp", md.tlp,
+					"path", path,
+					"published", other.publisher,
+					"hidden", md.publisher)
+			}
+			return true
+		}
+		seen[path] = md
+		return false
+	}), nil
+}
+
+// mirrorDocuments returns the latest documents of the mirrored sources
+// which are allowed to be published. The documents are ordered by
+// their current release dates, newest first.
+func (c *Controller) mirrorDocuments(ctx context.Context, tlp models.TLP) ([]*mirrorDocument, error) {
+	return c.queryMirrorDocuments(ctx, tlp, "")
+}
+
+// mirrorFileSQL narrows the documents to the ones with a given
+// file name in a given year folder. The file name is derived
+// from the tracking ID like [util.CleanFileName] does.
+const mirrorFileSQL = ` AND regexp_replace(` +
+	`regexp_replace(lower(a.tracking_id), '\.json$', ''), ` +
+	`'[^-+a-z0-9]+', '_', 'g') || '.json' = $3 ` +
+	`AND extract(year FROM coalesce(d.initial_release_date, d.current_release_date) ` +
+	`AT TIME ZONE 'UTC') = $4`
+
+// mirror serves the read-only CSAF provider tree of the mirror.
+func (c *Controller) mirror(ctx *gin.Context) {
+	parts := strings.Split(strings.TrimPrefix(ctx.Param("file"), "/"), "/")
+	if len(parts) == 1 {
+		if parts[0] == "provider-metadata.json" {
+			c.mirrorPMD(ctx)
+			return
+		}
+		models.SendErrorMessage(ctx, http.StatusNotFound, "not found")
+		return
+	}
+	if len(parts) == 2 && parts[0] == "openpgp" {
+		c.mirrorKey(ctx, parts[1])
+		return
+	}
+	var tlp models.TLP
+	if err := tlp.UnmarshalText([]byte(strings.ToUpper(parts[0]))); err != nil ||
+		parts[0] != strings.ToLower(parts[0]) ||
+		!slices.Contains(c.mirrorTLPs(), tlp) {
+		models.SendErrorMessage(ctx, http.StatusNotFound, "not found")
+		return
+	}
+	switch {
+	case len(parts) == 2 && parts[1] == "index.txt":
+		c.mirrorIndex(ctx, tlp)
+	case len(parts) == 2 && parts[1] == "changes.csv":
+		c.mirrorChanges(ctx, tlp)
+	case len(parts) == 2 && parts[1] == mirrorFeedName(tlp):
+		c.mirrorFeed(ctx, tlp)
+	case len(parts) == 3:
+		c.mirrorFile(ctx, tlp, parts[1], parts[2])
+	default:
+		models.SendErrorMessage(ctx, http.StatusNotFound, "not found")
+	}
+}
+
+// mirrorPMD serves the generated provider metadata of the mirror.
+func (c *Controller) mirrorPMD(ctx *gin.Context) {
+	base := c.mirrorBaseURL()
+	var (
+		lastUpdated time.Time
+		feeds       []csaf.Feed
+		dists       []csaf.Distribution
+	)
+	for _, tlp := range c.mirrorTLPs() {
+		docs, err := c.mirrorDocuments(ctx.Request.Context(), tlp)
+		if err != nil {
+			slog.Error("database error", "err", err)
+			models.SendError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		if len(docs) > 0 && docs[0].current.After(lastUpdated) {
+			lastUpdated = docs[0].current
+		}
+		label := csaf.TLPLabel(tlp)
+		feedURL := csaf.JSONURL(base + "/" + strings.ToLower(string(tlp)) + "/" + mirrorFeedName(tlp))
+		feeds = append(feeds, csaf.Feed{
+			Summary:  "TLP:" + string(tlp) + " advisories",
+			TLPLabel: &label,
+			URL:      &feedURL,
+		})
+		dists = append(dists, csaf.Distribution{
+			DirectoryURL: base + "/" + strings.ToLower(string(tlp)),
+		})
+	}
+	if lastUpdated.IsZero() {
+		lastUpdated = time.Now().UTC()
+	}
+	if len(feeds) > 0 {
+		dists = append(dists, csaf.Distribution{Rolie: &csaf.ROLIE{Feeds: feeds}})
+	}
+	// The documents keep the signatures of their sources.
+	var keys []csaf.PGPKey
+	for _, key := range c.sm.PublicKeys(c.cfg.Mirror.Sources) {
+		fingerprint := strings.ToUpper(key.GetFingerprint())
+		keyURL := base + "/openpgp/" + fingerprint + ".asc"
+		keys = append(keys, csaf.PGPKey{
+			Fingerprint: csaf.Fingerprint(fingerprint),
+			URL:         &keyURL,
+		})
+	}
+	var (
+		canonical = csaf.ProviderURL(base + "/provider-metadata.json")
+		updated   = csaf.TimeStamp(lastUpdated)
+		version   = csaf.MetadataVersion(csaf.MetadataVersion20)
+		role      = csaf.MetadataRole(csaf.MetadataRoleProvider)
+		no        = false
+		publisher = c.cfg.Mirror.Publisher
+	)
+	ctx.JSON(http.StatusOK, &csaf.ProviderMetadata{
+		CanonicalURL:            &canonical,
+		Distributions:           dists,
+		LastUpdated:             &updated,
+		ListOnCSAFAggregators:   &no,
+		MetadataVersion:         &version,
+		MirrorOnCSAFAggregators: &no,
+		PGPKeys:                 keys,
+		Publisher:               &publisher,
+		Role:                    &role,
+	})
+}
+
+// mirrorKey serves a public OpenPGP key of the mirrored sources.
+func (c *Controller) mirrorKey(ctx *gin.Context, name string) {
+	fingerprint, ok := strings.CutSuffix(name, ".asc")
+	if !ok {
+		models.SendErrorMessage(ctx, http.StatusNotFound, "not found")
+		return
+	}
+	for _, key := range c.sm.PublicKeys(c.cfg.Mirror.Sources) {
+		if !strings.EqualFold(key.GetFingerprint(), fingerprint) {
+			continue
+		}
+		armored, err := key.GetArmoredPublicKey()
+		if err != nil {
+			slog.Error("armoring OpenPGP key failed", "err", err)
+			models.SendError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		ctx.Data(http.StatusOK, "application/pgp-keys", []byte(armored))
+		return
+	}
+	models.SendErrorMessage(ctx, http.StatusNotFound, "not found")
+}
+
+// mirrorIndex serves the index.txt of a TLP folder.
+func (c *Controller) mirrorIndex(ctx *gin.Context, tlp models.TLP) {
+	docs, err := c.mirrorDocuments(ctx.Request.Context(), tlp)
+	if err != nil {
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	var b strings.Builder
+	for _, md := range docs {
+		b.WriteString(md.path())
+		b.WriteByte('\n')
+	}
+	ctx.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(b.String()))
+}
+
+// mirrorChanges serves the changes.csv of a TLP folder.
+func (c *Controller) mirrorChanges(ctx *gin.Context, tlp models.TLP) {
+	docs, err := c.mirrorDocuments(ctx.Request.Context(), tlp)
+	if err != nil {
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	for _, md := range docs {
+		w.Write([]string{md.path(), md.current.Format(time.RFC3339)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	ctx.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// mirrorFeed serves the ROLIE feed of a TLP folder.
+func (c *Controller) mirrorFeed(ctx *gin.Context, tlp models.TLP) {
+	docs, err := c.mirrorDocuments(ctx.Request.Context(), tlp)
+	if err != nil {
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	folder := c.mirrorBaseURL() + "/" + strings.ToLower(string(tlp))
+	feedURL := folder + "/" + mirrorFeedName(tlp)
+	updated := time.Now().UTC()
+	if len(docs) > 0 {
+		updated = docs[0].current
+	}
+	entries := make([]*csaf.Entry, 0, len(docs))
+	for _, md := range docs {
+		docURL := folder + "/" + md.path()
+		links := []csaf.Link{{Rel: "self", HRef: docURL}}
+		if md.signed {
+			links = append(links, csaf.Link{Rel: "signature", HRef: docURL + ".asc"})
+		}
+		links = append(links,
+			csaf.Link{Rel: "hash", HRef: docURL + ".sha256"},
+			csaf.Link{Rel: "hash", HRef: docURL + ".sha512"})
+		entries = append(entries, &csaf.Entry{
+			ID:        md.trackingID,
+			Titel:     md.title,
+			Link:      links,
+			Published: csaf.TimeStamp(md.initial),
+			Updated:   csaf.TimeStamp(md.current),
+			Content:   csaf.Content{Type: "application/json", Src: docURL},
+			Format: csaf.Format{
+				Schema:  "https://docs.oasis-open.org/csaf/csaf/v2.0/csaf_json_schema.json",
+				Version: "2.0",
+			},
+		})
+	}
+	feed := &csaf.ROLIEFeed{Feed: csaf.FeedData{
+		ID:    "csaf-feed-tlp-" + strings.ToLower(string(tlp)),
+		Title: "CSAF feed (TLP:" + string(tlp) + ")",
+		Link:  []csaf.Link{{Rel: "self", HRef: feedURL}},
+		Category: []csaf.ROLIECategory{{
+			Scheme: "urn:ietf:params:rolie:category:information-type",
+			Term:   "csaf",
+		}},
+		Updated: csaf.TimeStamp(updated),
+		Entry:   entries,
+	}}
+	var buf bytes.Buffer
+	if _, err := feed.WriteTo(&buf); err != nil {
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	ctx.Data(http.StatusOK, "application/json", buf.Bytes())
+}
+
+// mirrorFile serves a document, its signature or its hashes.
+func (c *Controller) mirrorFile(ctx *gin.Context, tlp models.TLP, year, name string) {
+	var (
+		filename = name
+		suffix   string
+	)
+	for _, ext := range []string{".asc", ".sha256", ".sha512"} {
+		if strings.HasSuffix(name, ext) {
+			filename, suffix = strings.TrimSuffix(name, ext), ext
+			break
+		}
+	}
+	y, err := strconv.Atoi(year)
+	if err != nil || strconv.Itoa(y) != year {
+		models.SendErrorMessage(ctx, http.StatusNotFound, "not found")
+		return
+	}
+	docs, err := c.queryMirrorDocuments(ctx.Request.Context(), tlp, mirrorFileSQL, filename, y)
+	if err != nil {
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	idx := slices.IndexFunc(docs, func(md *mirrorDocument) bool {
+		return md.year() == year && md.filename() == filename
+	})
+	if idx == -1 {
+		models.SendErrorMessage(ctx, http.StatusNotFound, "not found")
+		return
+	}
+	const sql = `SELECT original, signature FROM documents WHERE id = $1`
+	var original, signature []byte
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			return conn.QueryRow(rctx, sql, docs[idx].id).Scan(&original, &signature)
+		}, 0,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			models.SendErrorMessage(ctx, http.StatusNotFound, "not found")
+		} else {
+			slog.Error("database error", "err", err)
+			models.SendError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	var h hash.Hash
+	switch suffix {
+	case "":
+		ctx.Data(http.StatusOK, "application/json", original)
+		return
+	case ".asc":
+		if signature == nil {
+			models.SendErrorMessage(ctx, http.StatusNotFound, "not found")
+			return
+		}
+		ctx.Data(http.StatusOK, "text/plain; charset=utf-8", signature)
+		return
+	case ".sha256":
+		h = sha256.New()
+	case ".sha512":
+		h = sha512.New()
+	}
+	h.Write(original)
+	ctx.String(http.StatusOK, "%s  %s\n", hex.EncodeToString(h.Sum(nil)), filename)
+}
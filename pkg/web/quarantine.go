@@ -0,0 +1,486 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocsaf/csaf/v3/util"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ISDuBA/ISDuBA/pkg/database/query"
+	"github.com/ISDuBA/ISDuBA/pkg/models"
+	"github.com/ISDuBA/ISDuBA/pkg/sources"
+)
+
+// quarantineEntry is a document rejected by the strict mode of the downloader.
+type quarantineEntry struct {
+	ID         int64     `json:"id"`
+	FeedID     int64     `json:"feed_id"`
+	Feed       string    `json:"feed"`
+	SourceID   int64     `json:"source_id"`
+	Source     string    `json:"source"`
+	URL        string    `json:"url"`
+	Filename   string    `json:"filename"`
+	Time       time.Time `json:"time"`
+	Updated    time.Time `json:"updated"`
+	Publisher  *string   `json:"publisher,omitempty"`
+	TrackingID *string   `json:"tracking_id,omitempty"`
+	Version    *string   `json:"version,omitempty"`
+	TLP        *string   `json:"tlp,omitempty"`
+	Title      *string   `json:"title,omitempty"`
+	Failed     []string  `json:"failed"`
+}
+
+// quarantineDetails is a quarantined document with the results of the checks.
+type quarantineDetails struct {
+	quarantineEntry
+	Signature *string                  `json:"signature,omitempty"`
+	Report    *models.ValidationReport `json:"report,omitempty"`
+}
+
+// quarantineChecks are the columns of the failed checks
+// and their names in the quarantine entries.
+var quarantineChecks = []struct {
+	column string
+	name   string
+}{
+	{"download_failed", "download"},
+	{"filename_failed", "filename"},
+	{"schema_failed", "schema"},
+	{"remote_failed", "remote"},
+	{"checksum_failed", "checksum"},
+	{"signature_failed", "signature"},
+	{"duplicate_failed", "duplicate"},
+}
+
+// quarantineSQL returns the select statement of the quarantine entries.
+func quarantineSQL(extra ...string) string {
+	var b strings.Builder
+	b.WriteString(`SELECT quarantine.id, feeds_id, feeds.label, sources.id, sources.name, ` +
+		`quarantine.url, filename, time, updated, ` +
+		`publisher, tracking_id, version, tlp, title`)
+	for _, check := range quarantineChecks {
+		b.WriteString(", coalesce(")
+		b.WriteString(check.column)
+		b.WriteString(", false)")
+	}
+	for _, e := range extra {
+		b.WriteString(", ")
+		b.WriteString(e)
+	}
+	b.WriteString(` FROM quarantine ` +
+		`JOIN feeds ON quarantine.feeds_id = feeds.id ` +
+		`JOIN sources ON feeds.sources_id = sources.id`)
+	return b.String()
+}
+
+// scan scans a quarantine entry followed by the extra destinations.
+func (qe *quarantineEntry) scan(row pgx.Row, extra ...any) error {
+	failed := make([]bool, len(quarantineChecks))
+	dests := []any{
+		&qe.ID, &qe.FeedID, &qe.Feed, &qe.SourceID, &qe.Source,
+		&qe.URL, &qe.Filename, &qe.Time, &qe.Updated,
+		&qe.Publisher, &qe.TrackingID, &qe.Version, &qe.TLP, &qe.Title,
+	}
+	for i := range failed {
+		dests = append(dests, &failed[i])
+	}
+	if err := row.Scan(append(dests, extra...)...); err != nil {
+		return err
+	}
+	qe.Failed = []string{}
+	for i, check := range quarantineChecks {
+		if failed[i] {
+			qe.Failed = append(qe.Failed, check.name)
+		}
+	}
+	return nil
+}
+
+// quarantineTLPs returns the condition and its arguments restricting
+// the quarantined documents to the publishers and TLPs of the user.
+func (c *Controller) quarantineTLPs(ctx *gin.Context) (string, []any) {
+	var b query.SQLBuilder
+	b.CreateWhere(c.tlps(ctx).AsExprPublisher("quarantine.publisher"))
+	return b.WhereClause, b.Replacements
+}
+
+// sendQuarantineError sends the errors of loading a quarantined document.
+func sendQuarantineError(ctx *gin.Context, err error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		models.SendErrorMessage(ctx, http.StatusNotFound, "quarantined document not found")
+	} else {
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+// viewQuarantine is an endpoint that returns the quarantined documents.
+//
+//	@Summary		Returns the quarantined documents.
+//	@Description	Returns the documents rejected by the strict mode of the downloader, the newest first.
+//	@Description	Only documents of publishers and TLPs the user is allowed to see are returned.
+//	@Param			source	query	int		false	"Source ID"
+//	@Param			feed	query	int		false	"Feed ID"
+//	@Param			limit	query	int		false	"Maximum number of entries"
+//	@Param			offset	query	int		false	"Offset of the entries"
+//	@Param			count	query	bool	false	"Count the entries"
+//	@Produce		json
+//	@Success		200	{object}	web.viewQuarantine.quarantineEntries
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		500	{object}	models.Error
+//	@Router			/sources/quarantine [get]
+func (c *Controller) viewQuarantine(ctx *gin.Context) {
+	type quarantineEntries struct {
+		Entries []quarantineEntry `json:"entries"`
+		Count   *int64            `json:"count,omitempty"`
+	}
+	var (
+		cond, args = c.quarantineTLPs(ctx)
+		conds      = []string{cond}
+		count      bool
+		ok         bool
+	)
+	for _, filter := range []struct {
+		param  string
+		column string
+	}{
+		{"source", "sources.id"},
+		{"feed", "feeds_id"},
+	} {
+		if v := ctx.Query(filter.param); v != "" {
+			id, ok := parse(ctx, toInt64, v)
+			if !ok {
+				return
+			}
+			args = append(args, id)
+			conds = append(conds, fmt.Sprintf("%s = $%d", filter.column, len(args)))
+		}
+	}
+	if cnt := ctx.Query("count"); cnt != "" {
+		if count, ok = parse(ctx, strconv.ParseBool, cnt); !ok {
+			return
+		}
+	}
+	where := ` WHERE ` + strings.Join(conds, ` AND `)
+	listSQL := quarantineSQL() + where + ` ORDER BY time DESC, quarantine.id DESC`
+	for _, page := range []string{"limit", "offset"} {
+		if v := ctx.Query(page); v != "" {
+			n, ok := parse(ctx, toInt64, v)
+			if !ok {
+				return
+			}
+			listSQL += fmt.Sprintf(" %s %d", strings.ToUpper(page), n)
+		}
+	}
+
+	var entries quarantineEntries
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			if count {
+				countSQL := `SELECT count(*) FROM quarantine ` +
+					`JOIN feeds ON quarantine.feeds_id = feeds.id ` +
+					`JOIN sources ON feeds.sources_id = sources.id` + where
+				var n int64
+				if err := conn.QueryRow(rctx, countSQL, args...).Scan(&n); err != nil {
+					return err
+				}
+				entries.Count = &n
+			}
+			rows, _ := conn.Query(rctx, listSQL, args...)
+			var err error
+			entries.Entries, err = pgx.CollectRows(rows,
+				func(row pgx.CollectableRow) (quarantineEntry, error) {
+					var qe quarantineEntry
+					err := qe.scan(row)
+					return qe, err
+				})
+			return err
+		}, 0,
+	); err != nil {
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if entries.Entries == nil {
+		entries.Entries = []quarantineEntry{}
+	}
+	ctx.JSON(http.StatusOK, &entries)
+}
+
+// viewQuarantined is an endpoint that returns a quarantined document.
+//
+//	@Summary		Returns a quarantined document.
+//	@Description	Returns a quarantined document with its signature and the results of the checks.
+//	@Param			id	path	int	true	"Quarantine ID"
+//	@Produce		json
+//	@Success		200	{object}	web.quarantineDetails
+//	@Failure		400	{object}	models.Error	"could not parse id"
+//	@Failure		401
+//	@Failure		404	{object}	models.Error	"quarantined document not found"
+//	@Failure		500	{object}	models.Error
+//	@Router			/sources/quarantine/{id} [get]
+func (c *Controller) viewQuarantined(ctx *gin.Context) {
+	id, ok := parse(ctx, toInt64, ctx.Param("id"))
+	if !ok {
+		return
+	}
+	var (
+		details   quarantineDetails
+		signature []byte
+	)
+	cond, args := c.quarantineTLPs(ctx)
+	args = append(args, id)
+	selectSQL := quarantineSQL("signature", "validation_report") +
+		fmt.Sprintf(` WHERE %s AND quarantine.id = $%d`, cond, len(args))
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			return details.scan(conn.QueryRow(rctx, selectSQL, args...), &signature, &details.Report)
+		}, 0,
+	); err != nil {
+		sendQuarantineError(ctx, err)
+		return
+	}
+	if signature != nil {
+		s := string(signature)
+		details.Signature = &s
+	}
+	ctx.JSON(http.StatusOK, &details)
+}
+
+// viewQuarantinedDocument is an endpoint that exports a quarantined document.
+//
+//	@Summary		Returns the quarantined document.
+//	@Description	Returns the quarantined document in its original format.
+//	@Param			id	path	int	true	"Quarantine ID"
+//	@Produce		json
+//	@Success		200	{object}	any
+//	@Failure		400	{object}	models.Error	"could not parse id"
+//	@Failure		401
+//	@Failure		404	{object}	models.Error	"quarantined document not found"
+//	@Failure		500	{object}	models.Error
+//	@Router			/sources/quarantine/{id}/document [get]
+func (c *Controller) viewQuarantinedDocument(ctx *gin.Context) {
+	id, ok := parse(ctx, toInt64, ctx.Param("id"))
+	if !ok {
+		return
+	}
+	cond, args := c.quarantineTLPs(ctx)
+	args = append(args, id)
+	selectSQL := `SELECT original, filename FROM quarantine ` +
+		fmt.Sprintf(`WHERE %s AND quarantine.id = $%d`, cond, len(args))
+	var (
+		original []byte
+		filename string
+	)
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			return conn.QueryRow(rctx, selectSQL, args...).Scan(&original, &filename)
+		}, 0,
+	); err != nil {
+		sendQuarantineError(ctx, err)
+		return
+	}
+	if filename = util.CleanFileName(filename); filename == "" {
+		filename = "document.json"
+	}
+	extraHeaders := map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=\"%s\"", filename),
+	}
+	ctx.DataFromReader(
+		http.StatusOK, int64(len(original)),
+		"application/json",
+		bytes.NewReader(original),
+		extraHeaders)
+}
+
+// viewQuarantineDiff is an endpoint that returns the diff between
+// a stored and a quarantined document.
+//
+//	@Summary		Returns a diff of a quarantined document.
+//	@Description	Returns the diff from a stored document to the quarantined document.
+//	@Description	Without a document the latest stored version of the same advisory is used.
+//	@Param			id			path	int		true	"Quarantine ID"
+//	@Param			document	query	int		false	"Document ID"
+//	@Param			word-diff	query	bool	false	"Word diff of replaced texts"
+//	@Produce		json
+//	@Success		200	{object}	any
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		404	{object}	models.Error
+//	@Failure		500	{object}	models.Error
+//	@Router			/sources/quarantine/{id}/diff [get]
+func (c *Controller) viewQuarantineDiff(ctx *gin.Context) {
+	id, ok := parse(ctx, toInt64, ctx.Param("id"))
+	if !ok {
+		return
+	}
+	var docID *int64
+	if d := ctx.Query("document"); d != "" {
+		did, ok := parse(ctx, toInt64, d)
+		if !ok {
+			return
+		}
+		docID = &did
+	}
+	tlps := c.tlps(ctx)
+	if len(tlps) == 0 {
+		models.SendErrorMessage(ctx, http.StatusNotFound, "document not found")
+		return
+	}
+
+	var (
+		doc                   [2][]byte
+		publisher, trackingID *string
+	)
+	cond, args := c.quarantineTLPs(ctx)
+	args = append(args, id)
+	quarantinedSQL := `SELECT original, publisher, tracking_id FROM quarantine ` +
+		fmt.Sprintf(`WHERE %s AND quarantine.id = $%d`, cond, len(args))
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			if err := conn.QueryRow(rctx, quarantinedSQL, args...).Scan(
+				&doc[1], &publisher, &trackingID); err != nil {
+				return err
+			}
+			var expr *query.Expr
+			if docID != nil {
+				expr = query.FieldEqInt("documents.id", *docID)
+			} else {
+				if publisher == nil || trackingID == nil {
+					return errNoStoredVersion
+				}
+				expr = query.FieldEqString("publisher", *publisher).
+					And(query.FieldEqString("tracking_id", *trackingID)).
+					And(query.BoolField("latest"))
+			}
+			var b query.SQLBuilder
+			b.CreateWhere(expr.And(tlps.AsExpr()))
+			fetchSQL := `SELECT original ` +
+				`FROM documents JOIN advisories ON documents.advisories_id = advisories.id ` +
+				`WHERE ` + b.WhereClause
+			if err := conn.QueryRow(rctx, fetchSQL, b.Replacements...).Scan(&doc[0]); err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return errNoStoredVersion
+				}
+				return err
+			}
+			return nil
+		}, 0,
+	); err != nil {
+		if errors.Is(err, errNoStoredVersion) {
+			models.SendErrorMessage(ctx, http.StatusNotFound, "document not found")
+		} else {
+			sendQuarantineError(ctx, err)
+		}
+		return
+	}
+	sendDiff(ctx, doc)
+}
+
+// errNoStoredVersion is returned if there is no stored document
+// to compare a quarantined document with.
+var errNoStoredVersion = errors.New("no stored version")
+
+// releaseQuarantined is an endpoint that imports a quarantined document.
+//
+//	@Summary		Releases a quarantined document.
+//	@Description	Imports a quarantined document into the database and removes it from the quarantine.
+//	@Description	A document already in the database is removed from the quarantine, too.
+//	@Param			id	path	int	true	"Quarantine ID"
+//	@Produce		json
+//	@Success		201	{object}	models.ID
+//	@Failure		400	{object}	models.Error	"could not parse id"
+//	@Failure		401
+//	@Failure		403	{object}	models.Error	"False TLP or publisher"
+//	@Failure		404	{object}	models.Error	"quarantined document not found"
+//	@Failure		409	{object}	models.Error	"Already in database"
+//	@Failure		500	{object}	models.Error
+//	@Router			/sources/quarantine/{id}/release [post]
+func (c *Controller) releaseQuarantined(ctx *gin.Context) {
+	id, ok := parse(ctx, toInt64, ctx.Param("id"))
+	if !ok {
+		return
+	}
+	var actor *string
+	if user := c.currentUser(ctx); user.Valid {
+		actor = &user.String
+	}
+	switch docID, err := c.sm.Release(ctx.Request.Context(), id, actor, c.tlps(ctx)); {
+	case err == nil:
+		ctx.JSON(http.StatusCreated, models.ID{ID: docID})
+	case errors.Is(err, sources.NoSuchEntryError("")):
+		models.SendErrorMessage(ctx, http.StatusNotFound, "quarantined document not found")
+	case errors.Is(err, models.ErrAlreadyInDatabase):
+		models.SendErrorMessage(ctx, http.StatusConflict, "already in database")
+	case errors.Is(err, models.ErrNotAllowed):
+		models.SendErrorMessage(ctx, http.StatusForbidden, "wrong publisher/tlp")
+	default:
+		slog.Error("releasing quarantined document failed", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+// discardQuarantined is an endpoint that removes a quarantined document.
+//
+//	@Summary		Discards a quarantined document.
+//	@Description	Removes a document from the quarantine without importing it.
+//	@Description	Only documents of publishers and TLPs the user is allowed to see can be discarded.
+//	@Param			id	path	int	true	"Quarantine ID"
+//	@Produce		json
+//	@Success		200	{object}	models.Success
+//	@Failure		400	{object}	models.Error	"could not parse id"
+//	@Failure		401
+//	@Failure		404	{object}	models.Error	"quarantined document not found"
+//	@Failure		500	{object}	models.Error
+//	@Router			/sources/quarantine/{id} [delete]
+func (c *Controller) discardQuarantined(ctx *gin.Context) {
+	id, ok := parse(ctx, toInt64, ctx.Param("id"))
+	if !ok {
+		return
+	}
+	cond, args := c.quarantineTLPs(ctx)
+	args = append(args, id)
+	deleteSQL := `DELETE FROM quarantine ` +
+		fmt.Sprintf(`WHERE %s AND quarantine.id = $%d`, cond, len(args))
+	var deleted bool
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			tag, err := conn.Exec(rctx, deleteSQL, args...)
+			deleted = tag.RowsAffected() > 0
+			return err
+		}, 0,
+	); err != nil {
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if !deleted {
+		models.SendErrorMessage(ctx, http.StatusNotFound, "quarantined document not found")
+		return
+	}
+	models.SendSuccess(ctx, http.StatusOK, "discarded")
+}
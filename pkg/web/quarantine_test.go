@@ -0,0 +1,56 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package web
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ISDuBA/ISDuBA/pkg/config"
+	"github.com/ISDuBA/ISDuBA/pkg/models"
+)
+
+func TestQuarantineTLPs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		tlps  models.PublishersTLPs
+		parts []string
+		args  int
+	}{
+		{
+			tlps:  models.PublishersTLPs{"Example": {models.TLPWhite, models.TLPGreen}},
+			parts: []string{"(quarantine.publisher)=($1)", "(tlp)=($2)", "(tlp)=($3)"},
+			args:  3,
+		},
+		{
+			tlps:  models.PublishersTLPs{"*": {models.TLPWhite}},
+			parts: []string{"(tlp)=($1)"},
+			args:  1,
+		},
+		{
+			tlps:  models.PublishersTLPs{},
+			parts: []string{"FALSE"},
+		},
+	} {
+		c := &Controller{cfg: &config.Config{PublishersTLPs: tc.tlps}}
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		cond, args := c.quarantineTLPs(ctx)
+		for _, part := range tc.parts {
+			if !strings.Contains(cond, part) {
+				t.Errorf("%v: %q missing in %s", tc.tlps, part, cond)
+			}
+		}
+		if len(args) != tc.args {
+			t.Errorf("%v: expected %d arguments, got %v", tc.tlps, tc.args, args)
+		}
+	}
+}
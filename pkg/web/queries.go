@@ -10,6 +10,7 @@ package web
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -49,11 +50,7 @@ func (c *Controller) createStoredQuery(ctx *gin.Context) {
 		Definer: ctx.GetString("uid"),
 	}
 
-	// We need the name.
-	if sq.Name = ctx.PostForm("name"); sq.Name == "" {
-		models.SendErrorMessage(ctx, http.StatusBadRequest, "missing 'name'")
-		return
-	}
+	sq.Name = ctx.PostForm("name")
 
 	// Advisories flag
 	if kind, ok := ctx.GetPostForm("kind"); ok {
@@ -99,43 +96,36 @@ func (c *Controller) createStoredQuery(ctx *gin.Context) {
 		}
 	}
 
-	parser := query.Parser{Mode: sq.Kind}
-
-	// The query to filter the documents.
-	sq.Query = ctx.DefaultPostForm("query", "true")
-	expr, ok := parse(ctx, parser.Parse, sq.Query)
-	if !ok {
-		return
-	}
-	// In advisory mode we only show the latest.
-	if sq.Kind == query.AdvisoryMode {
-		expr = expr.And(query.BoolField("latest"))
+	// Alert flag
+	if alert, ok := ctx.GetPostForm("alert"); ok {
+		if sq.Alert, ok = parse(ctx, strconv.ParseBool, alert); !ok {
+			return
+		}
 	}
 
-	// columns are not optional.
-	if sq.Columns = strings.Fields(ctx.PostForm("columns")); len(sq.Columns) == 0 {
-		models.SendErrorMessage(ctx, http.StatusBadRequest, "missing 'columns' value")
-		return
+	// Declared parameters
+	if params, ok := ctx.GetPostForm("parameters"); ok {
+		if sq.Parameters, ok = parse(ctx, parseParameters, params); !ok {
+			return
+		}
 	}
 
-	builder := query.SQLBuilder{Mode: sq.Kind}
-	builder.CreateWhere(expr)
-	if err := builder.CheckProjections(sq.Columns); err != nil {
-		models.SendErrorMessage(ctx,
-			http.StatusBadRequest,
-			"bad 'columns' value: "+err.Error())
-		return
+	// Notation of the query
+	if syntax, ok := ctx.GetPostForm("syntax"); ok {
+		if sq.Syntax, ok = parse(ctx, query.ParseSyntax, syntax); !ok {
+			return
+		}
 	}
 
+	// The query to filter the documents.
+	sq.Query = ctx.DefaultPostForm("query", "true")
+
+	// columns are not optional.
+	sq.Columns = strings.Fields(ctx.PostForm("columns"))
+
 	// Check if we have orders given.
 	if orders, ok := ctx.GetPostForm("orders"); ok {
 		os := strings.Fields(orders)
-		if _, err := builder.CreateOrder(os); err != nil {
-			models.SendErrorMessage(ctx,
-				http.StatusBadRequest,
-				"bad 'orders' value"+err.Error())
-			return
-		}
 		sq.Orders = &os
 	}
 
@@ -144,6 +134,11 @@ func (c *Controller) createStoredQuery(ctx *gin.Context) {
 		sq.Description = &description
 	}
 
+	if err := sq.Validate(); err != nil {
+		sendBadRequest(ctx, err)
+		return
+	}
+
 	const insertSQL = `INSERT INTO stored_queries (` +
 		`kind,` +
 		`definer,` +
@@ -155,8 +150,12 @@ func (c *Controller) createStoredQuery(ctx *gin.Context) {
 		`orders,` +
 		`dashboard,` +
 		`role,` +
-		`default_query ` +
-		`) VALUES ($1::stored_queries_kind, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)` +
+		`default_query,` +
+		`parameters,` +
+		`syntax,` +
+		`alert ` +
+		`) VALUES ($1::stored_queries_kind, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, ` +
+		`$13::stored_queries_syntax, $14)` +
 		`RETURNING id, num`
 
 	var queryID, queryNum int64
@@ -176,6 +175,9 @@ func (c *Controller) createStoredQuery(ctx *gin.Context) {
 				sq.Dashboard,
 				sq.Role,
 				sq.DefaultQuery,
+				sq.Parameters,
+				sq.Syntax.String(),
+				sq.Alert,
 			).Scan(&queryID, &queryNum)
 		}, 0,
 	); err != nil {
@@ -195,6 +197,25 @@ func (c *Controller) createStoredQuery(ctx *gin.Context) {
 	})
 }
 
+// parseParameters parses and validates the JSON encoded
+// parameter declarations of a stored query.
+func parseParameters(s string) ([]query.Parameter, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var params []query.Parameter
+	if err := json.Unmarshal([]byte(s), &params); err != nil {
+		return nil, err
+	}
+	if err := query.ValidateParameters(params); err != nil {
+		return nil, err
+	}
+	if len(params) == 0 {
+		return nil, nil
+	}
+	return params, nil
+}
+
 // updateOrder is an endpoint that updates the query order.
 //
 //	@Summary		Updates the query order.
@@ -267,7 +288,11 @@ func (c *Controller) updateOrder(ctx *gin.Context) {
 //	@Failure		500	{object}	models.Error
 //	@Router			/queries [get]
 func (c *Controller) listStoredQueries(ctx *gin.Context) {
-	const selectSQL = `SELECT ` +
+	var (
+		shared         = sharedWith("stored_queries", 1, false)
+		sharedEditable = sharedWith("stored_queries", 1, true)
+	)
+	selectSQL := `SELECT ` +
 		`id,` +
 		`kind::text,` +
 		`definer,` +
@@ -280,22 +305,33 @@ func (c *Controller) listStoredQueries(ctx *gin.Context) {
 		`orders,` +
 		`dashboard,` +
 		`role,` +
-		`default_query ` +
+		`default_query,` +
+		`parameters,` +
+		`syntax::text,` +
+		`alert,` +
+		`NOT global AND definer <> $1,` +
+		sharedEditable + ` ` +
 		`FROM stored_queries WHERE ` +
-		`definer = $1 OR global ` +
+		`definer = $1 OR global OR ` + shared + ` ` +
 		`ORDER BY global desc, definer, num`
 
-	var queries []*models.StoredQuery
+	var (
+		queries []*models.StoredQuery
+		admin   = c.hasAnyRole(ctx, models.Admin)
+	)
 
 	if err := c.db.Run(
 		ctx.Request.Context(),
 		func(rctx context.Context, conn *pgxpool.Conn) error {
-			definer := ctx.GetString("uid")
-			rows, _ := conn.Query(rctx, selectSQL, definer)
+			rows, _ := conn.Query(rctx, selectSQL, c.shareArgs(ctx)...)
 			var err error
 			queries, err = pgx.CollectRows(rows,
 				func(row pgx.CollectableRow) (*models.StoredQuery, error) {
-					var storedQuery models.StoredQuery
+					var (
+						storedQuery    models.StoredQuery
+						isShared       bool
+						editableShared bool
+					)
 					if err := row.Scan(
 						&storedQuery.ID,
 						&storedQuery.Kind,
@@ -310,9 +346,20 @@ func (c *Controller) listStoredQueries(ctx *gin.Context) {
 						&storedQuery.Dashboard,
 						&storedQuery.Role,
 						&storedQuery.DefaultQuery,
+						&storedQuery.Parameters,
+						&storedQuery.Syntax,
+						&storedQuery.Alert,
+						&isShared,
+						&editableShared,
 					); err != nil {
 						return nil, err
 					}
+					// Shared queries are annotated with their owner.
+					if isShared {
+						storedQuery.SharedBy = &storedQuery.Definer
+					}
+					storedQuery.Editable = !isShared && (!storedQuery.Global || admin) ||
+						isShared && editableShared
 					return &storedQuery, nil
 				})
 			return err
@@ -406,7 +453,7 @@ func (c *Controller) fetchStoredQuery(ctx *gin.Context) {
 		return
 	}
 
-	const selectSQL = `SELECT ` +
+	selectSQL := `SELECT ` +
 		`kind::text,` +
 		`definer,` +
 		`global,` +
@@ -418,9 +465,12 @@ func (c *Controller) fetchStoredQuery(ctx *gin.Context) {
 		`orders,` +
 		`dashboard,` +
 		`role,` +
-		`default_query ` +
+		`default_query,` +
+		`parameters,` +
+		`syntax::text,` +
+		`alert ` +
 		`FROM stored_queries WHERE id = $1 AND ` +
-		`(global OR definer = $2)`
+		`(global OR definer = $2 OR ` + sharedWith("stored_queries", 2, false) + `)`
 
 	storedQuery := models.StoredQuery{
 		ID: queryID,
@@ -428,8 +478,8 @@ func (c *Controller) fetchStoredQuery(ctx *gin.Context) {
 	if err := c.db.Run(
 		ctx.Request.Context(),
 		func(rctx context.Context, conn *pgxpool.Conn) error {
-			definer := ctx.GetString("uid")
-			return conn.QueryRow(rctx, selectSQL, queryID, definer).Scan(
+			args := append([]any{queryID}, c.shareArgs(ctx)...)
+			return conn.QueryRow(rctx, selectSQL, args...).Scan(
 				&storedQuery.Kind,
 				&storedQuery.Definer,
 				&storedQuery.Global,
@@ -442,6 +492,9 @@ func (c *Controller) fetchStoredQuery(ctx *gin.Context) {
 				&storedQuery.Dashboard,
 				&storedQuery.Role,
 				&storedQuery.DefaultQuery,
+				&storedQuery.Parameters,
+				&storedQuery.Syntax,
+				&storedQuery.Alert,
 			)
 		}, 0,
 	); err != nil {
@@ -490,12 +543,19 @@ func (c *Controller) updateStoredQuery(ctx *gin.Context) {
 			`dashboard,` +
 			`role,` +
 			`default_query,` +
+			`parameters,` +
+			`syntax::text,` +
+			`alert,` +
 			`definer ` +
 			`FROM stored_queries WHERE id = $1 AND `
+	)
+	var (
+		// Editable shares allow changes, too.
+		sharedEditable   = sharedWith("stored_queries", 2, true)
 		selectNoAdminSQL = selectSQLPrefix +
-			`definer = $2`
+			`(definer = $2 OR ` + sharedEditable + `)`
 		selectAdminSQL = selectSQLPrefix +
-			`(global OR definer = $2)`
+			`(global OR definer = $2 OR ` + sharedEditable + `)`
 	)
 
 	var bad string
@@ -520,8 +580,8 @@ func (c *Controller) updateStoredQuery(ctx *gin.Context) {
 			} else {
 				selectSQL = selectNoAdminSQL
 			}
-			definer := ctx.GetString("uid")
-			if err := tx.QueryRow(rctx, selectSQL, queryID, definer).Scan(
+			args := append([]any{queryID}, c.shareArgs(ctx)...)
+			if err := tx.QueryRow(rctx, selectSQL, args...).Scan(
 				&sq.Kind,
 				&sq.Global,
 				&sq.Name,
@@ -533,6 +593,9 @@ func (c *Controller) updateStoredQuery(ctx *gin.Context) {
 				&sq.Dashboard,
 				&sq.Role,
 				&sq.DefaultQuery,
+				&sq.Parameters,
+				&sq.Syntax,
+				&sq.Alert,
 				&sq.Definer,
 			); err != nil {
 				if errors.Is(err, pgx.ErrNoRows) {
@@ -564,7 +627,33 @@ func (c *Controller) updateStoredQuery(ctx *gin.Context) {
 				sq.Kind = pm
 			}
 
-			parser := query.Parser{Mode: sq.Kind}
+			// Check parameters
+			if prms, ok := ctx.GetPostForm("parameters"); ok {
+				params, err := parseParameters(prms)
+				if err != nil {
+					bad = "bad 'parameters' value: " + err.Error()
+					return nil
+				}
+				add(!slices.EqualFunc(params, sq.Parameters, query.Parameter.Equal), "parameters", params)
+				sq.Parameters = params
+			}
+
+			// Check syntax
+			if syn, ok := ctx.GetPostForm("syntax"); ok {
+				syntax, err := query.ParseSyntax(syn)
+				if err != nil {
+					bad = "bad 'syntax' value: " + err.Error()
+					return nil
+				}
+				add(syntax != sq.Syntax, "syntax", syntax.String())
+				sq.Syntax = syntax
+			}
+
+			parser := query.Parser{
+				Mode:       sq.Kind,
+				Syntax:     sq.Syntax,
+				Parameters: sq.Parameters,
+			}
 
 			// Check query
 			var expr *query.Expr
@@ -606,6 +695,24 @@ func (c *Controller) updateStoredQuery(ctx *gin.Context) {
 				add(dashboard != sq.Dashboard, "dashboard", dashboard)
 			}
 
+			// Check alert
+			if alrt := ctx.PostForm("alert"); alrt != "" {
+				alert, err := strconv.ParseBool(alrt)
+				if err != nil {
+					bad = "bad 'alert' value: " + err.Error()
+					return nil
+				}
+				add(alert != sq.Alert, "alert", alert)
+				sq.Alert = alert
+			}
+			// The kind and the parameters may have changed, too.
+			if sq.Alert {
+				if err := models.CheckAlert(sq.Kind, sq.Parameters); err != nil {
+					bad = err.Error()
+					return nil
+				}
+			}
+
 			// Check default query
 			if dQ := ctx.PostForm("default_query"); dQ != "" {
 				defaultQuery, err := strconv.ParseBool(dQ)
@@ -898,3 +1005,126 @@ func (c *Controller) insertDefaultQueryExclusion(ctx *gin.Context) {
 		ID:   insertedID,
 	})
 }
+
+// requestedStoredQuery loads the stored query referenced by the
+// optional 'stored' parameter of a request if it is visible to the user
+// and of one of the given kinds. A nil query is returned if there
+// is no such parameter.
+func (c *Controller) requestedStoredQuery(
+	ctx *gin.Context,
+	kinds ...query.ParserMode,
+) (*models.StoredQuery, bool) {
+	stored := ctx.Query("stored")
+	if stored == "" {
+		return nil, true
+	}
+	queryID, ok := parse(ctx, toInt64, stored)
+	if !ok {
+		return nil, false
+	}
+
+	selectSQL := `SELECT ` +
+		`kind::text,` +
+		`global,` +
+		`query,` +
+		`columns,` +
+		`orders,` +
+		`role,` +
+		`parameters,` +
+		`syntax::text ` +
+		`FROM stored_queries WHERE id = $1 AND ` +
+		`(global OR definer = $2 OR ` + sharedWith("stored_queries", 2, false) + `)`
+
+	sq := models.StoredQuery{ID: queryID}
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			args := append([]any{queryID}, c.shareArgs(ctx)...)
+			return conn.QueryRow(rctx, selectSQL, args...).Scan(
+				&sq.Kind,
+				&sq.Global,
+				&sq.Query,
+				&sq.Columns,
+				&sq.Orders,
+				&sq.Role,
+				&sq.Parameters,
+				&sq.Syntax,
+			)
+		}, 0,
+	); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			models.SendErrorMessage(ctx, http.StatusNotFound, "stored query not found")
+		default:
+			slog.Error("database error", "err", err)
+			models.SendError(ctx, http.StatusInternalServerError, err)
+		}
+		return nil, false
+	}
+	// Global queries may be restricted to a role.
+	if sq.Global && sq.Role != nil && !c.hasAnyRole(ctx, *sq.Role, models.Admin) {
+		models.SendErrorMessage(ctx, http.StatusNotFound, "stored query not found")
+		return nil, false
+	}
+	if !slices.Contains(kinds, sq.Kind) {
+		models.SendErrorMessage(ctx, http.StatusBadRequest,
+			fmt.Sprintf("stored query is of wrong kind %q", sq.Kind.String()))
+		return nil, false
+	}
+	return &sq, true
+}
+
+// validateQuery is an endpoint that checks a query for editor integration.
+//
+//	@Summary		Validates a query.
+//	@Description	Parses a query and returns its inferred type and the referenced columns or a located error.
+//	@Param			query		query	string	true	"Query"
+//	@Param			syntax		query	string	false	"Query syntax (rpn or infix)"
+//	@Param			kind		query	string	false	"Query kind (documents, advisories or events)"
+//	@Param			parameters	query	string	false	"JSON encoded parameter declarations"
+//	@Produce		json
+//	@Success		200	{object}	web.validateQuery.validation
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Router			/query/validate [get]
+func (c *Controller) validateQuery(ctx *gin.Context) {
+	type validation struct {
+		Valid bool `json:"valid"`
+		*query.Inspection
+		Error *query.ParseError `json:"error,omitempty"`
+	}
+
+	parser := query.Parser{
+		MinSearchLength: MinSearchLength,
+		Me:              ctx.GetString("uid"),
+	}
+
+	var ok bool
+	if kind, found := ctx.GetQuery("kind"); found {
+		if parser.Mode, ok = parse(ctx, parserMode, kind); !ok {
+			return
+		}
+	}
+	if syntax, found := ctx.GetQuery("syntax"); found {
+		if parser.Syntax, ok = parse(ctx, query.ParseSyntax, syntax); !ok {
+			return
+		}
+	}
+	if params, found := ctx.GetQuery("parameters"); found {
+		if parser.Parameters, ok = parse(ctx, parseParameters, params); !ok {
+			return
+		}
+	}
+
+	inspection, err := parser.Inspect(ctx.Query("query"))
+	if err != nil {
+		var pe *query.ParseError
+		if !errors.As(err, &pe) {
+			models.SendError(ctx, http.StatusBadRequest, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, validation{Error: pe})
+		return
+	}
+	ctx.JSON(http.StatusOK, validation{Valid: true, Inspection: inspection})
+}
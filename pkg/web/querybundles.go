@@ -0,0 +1,324 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package web
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ISDuBA/ISDuBA/pkg/models"
+)
+
+// exportStoredQueries is an endpoint that exports stored queries as a bundle.
+//
+//	@Summary		Exports stored queries.
+//	@Description	Exports the selected or all stored queries visible to the user as a versioned bundle.
+//	@Param			ids	query	string	false	"Space separated IDs of the queries"
+//	@Produce		json
+//	@Success		200	{object}	models.QueryBundle
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		500	{object}	models.Error
+//	@Router			/queries/export [get]
+func (c *Controller) exportStoredQueries(ctx *gin.Context) {
+	var ids []int64
+	for _, s := range strings.Fields(ctx.Query("ids")) {
+		id, ok := parse(ctx, toInt64, s)
+		if !ok {
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	selectSQL := `SELECT ` +
+		`kind::text,` +
+		`global,` +
+		`name,` +
+		`description,` +
+		`query,` +
+		`num,` +
+		`columns,` +
+		`orders,` +
+		`dashboard,` +
+		`role,` +
+		`default_query,` +
+		`parameters,` +
+		`syntax::text,` +
+		`alert ` +
+		`FROM stored_queries WHERE ` +
+		`(definer = $1 OR global OR ` + sharedWith("stored_queries", 1, false) + `) ` +
+		`AND ($4::int[] IS NULL OR id = ANY($4)) ` +
+		`ORDER BY global desc, definer, num`
+
+	args := append(c.shareArgs(ctx), ids)
+
+	var queries []*models.StoredQuery
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			rows, _ := conn.Query(rctx, selectSQL, args...)
+			var err error
+			queries, err = pgx.CollectRows(rows,
+				func(row pgx.CollectableRow) (*models.StoredQuery, error) {
+					var sq models.StoredQuery
+					err := row.Scan(
+						&sq.Kind,
+						&sq.Global,
+						&sq.Name,
+						&sq.Description,
+						&sq.Query,
+						&sq.Num,
+						&sq.Columns,
+						&sq.Orders,
+						&sq.Dashboard,
+						&sq.Role,
+						&sq.DefaultQuery,
+						&sq.Parameters,
+						&sq.Syntax,
+						&sq.Alert,
+					)
+					return &sq, err
+				})
+			return err
+		}, 0,
+	); err != nil {
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	bundle := models.QueryBundle{
+		Version:  models.QueryBundleVersion,
+		Exported: time.Now().UTC(),
+		Queries:  []*models.BundledQuery{},
+	}
+	for _, sq := range queries {
+		// Global queries may be restricted to a role.
+		if sq.Global && sq.Role != nil && !c.hasAnyRole(ctx, *sq.Role, models.Admin) {
+			continue
+		}
+		bundle.Queries = append(bundle.Queries, sq.Bundle())
+	}
+	ctx.JSON(http.StatusOK, &bundle)
+}
+
+// importStoredQueries is an endpoint that imports a bundle of stored queries.
+//
+//	@Summary		Imports stored queries.
+//	@Description	Imports the queries of a bundle as queries of the user. Existing queries with the same name are skipped, overwritten or the imported ones are renamed.
+//	@Description	Queries of the bundle with the same name conflict with each other, too. A dry run reports the same as the import.
+//	@Param			bundle		body	models.QueryBundle	true	"Bundle of queries"
+//	@Param			strategy	query	string				false	"Conflict strategy (skip, rename or overwrite)"
+//	@Param			dry			query	bool				false	"Only report what would be done"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{array}		web.importStoredQueries.importResult
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		500	{object}	models.Error
+//	@Router			/queries/import [post]
+func (c *Controller) importStoredQueries(ctx *gin.Context) {
+	type importResult struct {
+		Name     string  `json:"name"`
+		Status   string  `json:"status"`
+		Conflict bool    `json:"conflict"`
+		ID       *int64  `json:"id,omitempty"`
+		Renamed  *string `json:"renamed,omitempty"`
+		Error    string  `json:"error,omitempty"`
+	}
+
+	strategy := ctx.DefaultQuery("strategy", "skip")
+	switch strategy {
+	case "skip", "rename", "overwrite":
+	default:
+		models.SendErrorMessage(ctx, http.StatusBadRequest,
+			fmt.Sprintf("unknown strategy %q", strategy))
+		return
+	}
+	dry, ok := parse(ctx, strconv.ParseBool, ctx.DefaultQuery("dry", "false"))
+	if !ok {
+		return
+	}
+
+	var bundle models.QueryBundle
+	if err := ctx.ShouldBindJSON(&bundle); err != nil {
+		models.SendError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err := bundle.CheckVersion(); err != nil {
+		models.SendError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	// Keep the sort order of the bundle.
+	slices.SortStableFunc(bundle.Queries, func(a, b *models.BundledQuery) int {
+		return cmp.Compare(a.Num, b.Num)
+	})
+
+	const (
+		findSQL   = `SELECT id FROM stored_queries WHERE definer = $1 AND name = $2`
+		insertSQL = `INSERT INTO stored_queries (` +
+			`kind, definer, global, name, description, query, columns, orders, ` +
+			`dashboard, role, default_query, parameters, syntax, alert` +
+			`) VALUES ($1::stored_queries_kind, $2, $3, $4, $5, $6, $7, $8, ` +
+			`$9, $10, $11, $12, $13::stored_queries_syntax, $14) ` +
+			`RETURNING id`
+		overwriteSQL = `UPDATE stored_queries SET (` +
+			`kind, global, description, query, columns, orders, ` +
+			`dashboard, role, default_query, parameters, syntax, alert` +
+			`) = ($2::stored_queries_kind, $3, $4, $5, $6, $7, ` +
+			`$8, $9, $10, $11, $12::stored_queries_syntax, $13) ` +
+			`WHERE id = $1`
+	)
+
+	var (
+		definer = ctx.GetString("uid")
+		admin   = c.hasAnyRole(ctx, models.Admin)
+		results = make([]*importResult, 0, len(bundle.Queries))
+	)
+
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			tx, err := conn.BeginTx(rctx, pgx.TxOptions{})
+			if err != nil {
+				return err
+			}
+			defer tx.Rollback(rctx)
+
+			// The names assigned by this import. A dry run does
+			// not insert so the ids of these are unknown.
+			imported := map[string]*int64{}
+
+			find := func(name string) (*int64, bool, error) {
+				if id, ok := imported[name]; ok {
+					return id, true, nil
+				}
+				var id int64
+				switch err := tx.QueryRow(rctx, findSQL, definer, name).Scan(&id); {
+				case errors.Is(err, pgx.ErrNoRows):
+					return nil, false, nil
+				case err != nil:
+					return nil, false, err
+				}
+				return &id, true, nil
+			}
+			taken := func(name string) (bool, error) {
+				_, found, err := find(name)
+				return found, err
+			}
+
+			for _, bq := range bundle.Queries {
+				result := &importResult{Name: bq.Name}
+				results = append(results, result)
+
+				if err := bq.Validate(); err != nil {
+					result.Status, result.Error = "invalid", err.Error()
+					continue
+				}
+				// Global is only for admins.
+				if bq.Global && !admin {
+					result.Status, result.Error = "invalid", "global flag can only used by admins"
+					continue
+				}
+
+				existing, conflict, err := find(bq.Name)
+				if err != nil {
+					return err
+				}
+				result.Conflict = conflict
+
+				name := bq.Name
+				switch {
+				case conflict && strategy == "skip":
+					result.Status = "skipped"
+					continue
+				case conflict && strategy == "overwrite":
+					result.Status = "overwritten"
+					result.ID = existing
+					imported[name] = existing
+					if dry {
+						continue
+					}
+					if _, err := tx.Exec(rctx, overwriteSQL,
+						*existing,
+						bq.Kind.String(),
+						bq.Global,
+						bq.Description,
+						bq.Query,
+						bq.Columns,
+						bq.Orders,
+						bq.Dashboard,
+						bq.Role,
+						bq.DefaultQuery,
+						bq.Parameters,
+						bq.Syntax.String(),
+						bq.Alert,
+					); err != nil {
+						return err
+					}
+					continue
+				case conflict && strategy == "rename":
+					if name, err = models.RenameFree(bq.Name, taken); err != nil {
+						return err
+					}
+					result.Status, result.Renamed = "renamed", &name
+				default:
+					result.Status = "created"
+				}
+				if dry {
+					imported[name] = nil
+					continue
+				}
+				var id int64
+				if err := tx.QueryRow(rctx, insertSQL,
+					bq.Kind.String(),
+					definer,
+					bq.Global,
+					name,
+					bq.Description,
+					bq.Query,
+					bq.Columns,
+					bq.Orders,
+					bq.Dashboard,
+					bq.Role,
+					bq.DefaultQuery,
+					bq.Parameters,
+					bq.Syntax.String(),
+					bq.Alert,
+				).Scan(&id); err != nil {
+					return err
+				}
+				result.ID = &id
+				imported[name] = &id
+			}
+			if dry {
+				return nil
+			}
+			return tx.Commit(rctx)
+		}, 0,
+	); err != nil {
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, results)
+}
@@ -20,7 +20,6 @@ import (
 	"slices"
 
 	"github.com/ISDuBA/ISDuBA/pkg/database/query"
-	"github.com/ISDuBA/ISDuBA/pkg/models"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/render"
 	"github.com/jackc/pgx/v5"
@@ -65,11 +64,24 @@ func (c *Controller) aggregatedResults(
 	calcCount bool,
 	limit, offset int64,
 	builder *query.AdvancedSQLBuilder,
+	guard *queryGuard,
 ) {
+	// The count is created first as the cursor
+	// adds its values to the replacements.
 	var (
-		ads  aggregatedDocuments
-		sql  = builder.CreateQuery(-1, -1)
-		rctx = ctx.Request.Context()
+		countSQL  string
+		countArgs []any
+	)
+	if calcCount {
+		countSQL = builder.CreateCountSQL()
+		countArgs = slices.Clone(builder.Replacements)
+	}
+	var (
+		ads   aggregatedDocuments
+		count int64
+		next  *query.Cursor
+		sql   = builder.CreateQuery(limit, offset)
+		rctx  = ctx.Request.Context()
 	)
 
 	if slog.Default().Enabled(rctx, slog.LevelDebug) {
@@ -78,23 +90,36 @@ func (c *Controller) aggregatedResults(
 	if err := c.db.Run(
 		rctx,
 		func(rctx context.Context, conn *pgxpool.Conn) error {
-			rows, err := conn.Query(rctx, sql, builder.Replacements...)
-			if err != nil {
-				return fmt.Errorf("cannot fetch results: %w", err)
-			}
-			defer rows.Close()
-			if ads, err = scanAggregatedDocuments(rows, builder.Fields()); err != nil {
-				return fmt.Errorf("loading data failed: %w", err)
+			if calcCount {
+				var err error
+				if count, err = guard.count(rctx, conn, countSQL, countArgs); err != nil {
+					return fmt.Errorf("cannot calculate count %w", err)
+				}
 			}
-			return nil
+			fields := slices.Concat(builder.Fields(), builder.CursorFields())
+			return guard.query(rctx, conn, sql, builder.Replacements, func(rows pgx.Rows) error {
+				var err error
+				if ads, err = scanAggregatedDocuments(rows, fields); err != nil {
+					return fmt.Errorf("loading data failed: %w", err)
+				}
+				return nil
+			})
 		},
 		c.cfg.Database.MaxQueryDuration, // In case the user provided a very expensive query.
 	); err != nil {
-		slog.Error("database error", "err", err)
-		models.SendError(ctx, http.StatusInternalServerError, err)
+		sendQueryError(ctx, err)
 		return
 	}
 
+	// The values of the cursor are the same for all rows of a document.
+	for i := range ads {
+		next = builder.NextCursor(ads[i].rows[0])
+	}
+	// Only full pages have a next one.
+	if limit < 0 || int64(len(ads)) != limit {
+		next = nil
+	}
+
 	// We load texts from the database lazily so we render in another connection.
 	// XXX: Think about moving it to the DB stuff above.
 	if err := c.db.Run(
@@ -127,7 +152,7 @@ func (c *Controller) aggregatedResults(
 				func(w http.ResponseWriter) error {
 					fmt.Fprint(w, "{")
 					if calcCount {
-						fmt.Fprintf(w, `"count":%d`, len(ads))
+						fmt.Fprintf(w, `"count":%d`, count)
 					}
 					// Only produce documents when we have them.
 					if len(ads) == 0 {
@@ -137,12 +162,14 @@ func (c *Controller) aggregatedResults(
 					if calcCount {
 						fmt.Fprint(w, ",")
 					}
+					if next != nil {
+						fmt.Fprintf(w, `"next_cursor":%q,`, next.String())
+					}
 					fmt.Fprint(w, `"documents":[`)
 					firstDocument := true
 					enc := json.NewEncoder(w)
 
-					if err := ads.window(
-						limit, offset,
+					if err := ads.each(
 						func(ad *aggregatedDocument) error {
 							if firstDocument {
 								firstDocument = false
@@ -168,7 +195,7 @@ func (c *Controller) aggregatedResults(
 									}
 									fmt.Fprintf(w, "%s:", key)
 									// If we have an alias we need to load the text from the database.
-									if builder.HasAlias(k) {
+									if builder.ReferencesText(k) {
 										id, ok := asInt64(v)
 										if !ok {
 											return fmt.Errorf("alias %q has not an int value", k)
@@ -314,22 +341,10 @@ func asInt64(x any) (int64, bool) {
 	return 0, false
 }
 
-func (ads aggregatedDocuments) window(
-	limit, offset int64,
+func (ads aggregatedDocuments) each(
 	write func(*aggregatedDocument) error,
 ) error {
-	var start, end int
-	if offset < 0 {
-		start = 0
-	} else {
-		start = min(int(offset), len(ads))
-	}
-	if limit < 0 {
-		end = len(ads)
-	} else {
-		end = min(int(offset+limit), len(ads))
-	}
-	for i := start; i < end; i++ {
+	for i := range ads {
 		if err := write(&ads[i]); err != nil {
 			return err
 		}
@@ -0,0 +1,247 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ISDuBA/ISDuBA/pkg/ginkeycloak"
+	"github.com/ISDuBA/ISDuBA/pkg/models"
+)
+
+// sharedWith returns an SQL condition checking if the stored query of
+// the given table is shared with the user. The user, the groups and
+// the roles of the user are expected as replacements starting at first.
+// If editable is set only shares allowing changes are considered.
+func sharedWith(table string, first int, editable bool) string {
+	var onlyEditable string
+	if editable {
+		onlyEditable = ` AND sqs.editable`
+	}
+	return fmt.Sprintf(`EXISTS (SELECT 1 FROM stored_queries_shares sqs `+
+		`WHERE sqs.stored_queries_id = %[1]s.id%[2]s AND (`+
+		`(sqs.kind = 'user' AND sqs.name = $%[3]d) OR `+
+		`(sqs.kind = 'group' AND sqs.name = ANY($%[4]d)) OR `+
+		`(sqs.kind = 'role' AND sqs.name = ANY($%[5]d))))`,
+		table, onlyEditable, first, first+1, first+2)
+}
+
+// shareArgs returns the user, the groups and the roles of the user
+// as replacements for [sharedWith].
+func (c *Controller) shareArgs(ctx *gin.Context) []any {
+	groups, roles := []string{}, []string{}
+	if token, ok := ctx.Get("token"); ok {
+		if kct, ok := token.(*ginkeycloak.KeycloakToken); ok && kct != nil {
+			groups = append(groups, kct.Groups...)
+			roles = append(roles, kct.RealmAccess.Roles...)
+		}
+	}
+	return []any{ctx.GetString("uid"), groups, roles}
+}
+
+// ownsStoredQuery checks if the user is allowed to manage the shares
+// of the stored query. These are the definer and for global queries
+// the admins. Unknown queries are not owned by anyone.
+func (c *Controller) ownsStoredQuery(
+	rctx context.Context,
+	ctx *gin.Context,
+	conn *pgxpool.Conn,
+	queryID int64,
+) (bool, error) {
+	const selectSQL = `SELECT definer, global FROM stored_queries WHERE id = $1`
+	var (
+		definer string
+		global  bool
+	)
+	switch err := conn.QueryRow(rctx, selectSQL, queryID).Scan(&definer, &global); {
+	case errors.Is(err, pgx.ErrNoRows):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return definer == ctx.GetString("uid") ||
+		(global && c.hasAnyRole(ctx, models.Admin)), nil
+}
+
+// viewStoredQueryShares is an endpoint that returns the shares of a stored query.
+//
+//	@Summary		Returns the shares of a stored query.
+//	@Description	Returns the users, groups and roles the stored query is shared with.
+//	@Param			query	path	int	true	"Query ID"
+//	@Produce		json
+//	@Success		200	{array}		models.QueryShare
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		404	{object}	models.Error
+//	@Failure		500	{object}	models.Error
+//	@Router			/queries/{query}/shares [get]
+func (c *Controller) viewStoredQueryShares(ctx *gin.Context) {
+	queryID, ok := parse(ctx, toInt64, ctx.Param("query"))
+	if !ok {
+		return
+	}
+	const selectSQL = `SELECT kind::text, name, editable ` +
+		`FROM stored_queries_shares WHERE stored_queries_id = $1 ` +
+		`ORDER BY kind, name`
+
+	var (
+		owner  bool
+		shares []models.QueryShare
+	)
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			var err error
+			if owner, err = c.ownsStoredQuery(rctx, ctx, conn, queryID); err != nil || !owner {
+				return err
+			}
+			rows, _ := conn.Query(rctx, selectSQL, queryID)
+			shares, err = pgx.CollectRows(rows,
+				func(row pgx.CollectableRow) (models.QueryShare, error) {
+					var qs models.QueryShare
+					err := row.Scan(&qs.Kind, &qs.Name, &qs.Editable)
+					return qs, err
+				})
+			return err
+		}, 0,
+	); err != nil {
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if !owner {
+		models.SendErrorMessage(ctx, http.StatusNotFound, "query not found")
+		return
+	}
+	ctx.JSON(http.StatusOK, shares)
+}
+
+// shareStoredQuery is an endpoint that shares a stored query.
+//
+//	@Summary		Shares a stored query.
+//	@Description	Shares the stored query with a user, a group or a role, read-only or editable.
+//	@Param			query	path	int					true	"Query ID"
+//	@Param			share	body	models.QueryShare	true	"Share"
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	models.Success
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		404	{object}	models.Error
+//	@Failure		500	{object}	models.Error
+//	@Router			/queries/{query}/shares [put]
+func (c *Controller) shareStoredQuery(ctx *gin.Context) {
+	queryID, ok := parse(ctx, toInt64, ctx.Param("query"))
+	if !ok {
+		return
+	}
+	var share models.QueryShare
+	if err := ctx.ShouldBindJSON(&share); err != nil {
+		models.SendError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	if err := share.Validate(); err != nil {
+		models.SendError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	const upsertSQL = `INSERT INTO stored_queries_shares ` +
+		`(stored_queries_id, kind, name, editable) ` +
+		`VALUES ($1, $2::stored_queries_shares_kind, $3, $4) ` +
+		`ON CONFLICT (stored_queries_id, kind, name) ` +
+		`DO UPDATE SET editable = excluded.editable`
+
+	var owner bool
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			var err error
+			if owner, err = c.ownsStoredQuery(rctx, ctx, conn, queryID); err != nil || !owner {
+				return err
+			}
+			_, err = conn.Exec(rctx, upsertSQL,
+				queryID, string(share.Kind), share.Name, share.Editable)
+			return err
+		}, 0,
+	); err != nil {
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if !owner {
+		models.SendErrorMessage(ctx, http.StatusNotFound, "query not found")
+		return
+	}
+	models.SendSuccess(ctx, http.StatusOK, "shared")
+}
+
+// unshareStoredQuery is an endpoint that removes a share of a stored query.
+//
+//	@Summary		Removes a share of a stored query.
+//	@Description	Stops sharing the stored query with the user, group or role.
+//	@Param			query	path	int		true	"Query ID"
+//	@Param			kind	path	string	true	"Kind of share (user, group or role)"
+//	@Param			name	path	string	true	"Name of user, group or role"
+//	@Produce		json
+//	@Success		200	{object}	models.Success
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		404	{object}	models.Error
+//	@Failure		500	{object}	models.Error
+//	@Router			/queries/{query}/shares/{kind}/{name} [delete]
+func (c *Controller) unshareStoredQuery(ctx *gin.Context) {
+	queryID, ok := parse(ctx, toInt64, ctx.Param("query"))
+	if !ok {
+		return
+	}
+	kind, ok := parse(ctx, models.ParseShareKind, ctx.Param("kind"))
+	if !ok {
+		return
+	}
+
+	const deleteSQL = `DELETE FROM stored_queries_shares ` +
+		`WHERE stored_queries_id = $1 AND kind = $2::stored_queries_shares_kind AND name = $3`
+
+	var owner, deleted bool
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			var err error
+			if owner, err = c.ownsStoredQuery(rctx, ctx, conn, queryID); err != nil || !owner {
+				return err
+			}
+			tag, err := conn.Exec(rctx, deleteSQL, queryID, string(kind), ctx.Param("name"))
+			if err != nil {
+				return err
+			}
+			deleted = tag.RowsAffected() > 0
+			return nil
+		}, 0,
+	); err != nil {
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	switch {
+	case !owner:
+		models.SendErrorMessage(ctx, http.StatusNotFound, "query not found")
+	case !deleted:
+		models.SendErrorMessage(ctx, http.StatusNotFound, "share not found")
+	default:
+		models.SendSuccess(ctx, http.StatusOK, "deleted")
+	}
+}
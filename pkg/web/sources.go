@@ -18,6 +18,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -51,25 +52,27 @@ func (sa sourceAge) MarshalText() ([]byte, error) {
 }
 
 type source struct {
-	ID                   int64          `json:"id" form:"id"`
-	Name                 string         `json:"name" form:"name" binding:"required,min=1"`
-	URL                  string         `json:"url" form:"url" binding:"required,min=1"`
-	Active               bool           `json:"active" form:"active"`
-	Attention            bool           `json:"attention" form:"attention"`
-	Status               []string       `json:"status,omitempty"`
-	Rate                 *float64       `json:"rate,omitempty" form:"rate" binding:"omitnil,gte=0"`
-	Slots                *int           `json:"slots,omitempty" form:"slots" binding:"omitnil,gte=0"`
-	Headers              []string       `json:"headers,omitempty" form:"headers"`
-	StrictMode           *bool          `json:"strict_mode,omitempty" form:"strict_mode"`
-	Secure               *bool          `json:"secure,omitempty" form:"secure"`
-	SignatureCheck       *bool          `json:"signature_check,omitempty" form:"signature_check"`
-	Age                  *sourceAge     `json:"age,omitempty" form:"age" swaggertype:"primitive,integer"`
-	IgnorePatterns       []string       `json:"ignore_patterns,omitempty" form:"ignore_patterns"`
-	ClientCertPublic     *string        `json:"client_cert_public,omitempty" form:"client_cert_public"`
-	ClientCertPrivate    *string        `json:"client_cert_private,omitempty" form:"client_cert_private"`
-	ClientCertPassphrase *string        `json:"client_cert_passphrase,omitempty" form:"client_cert_passphrase"`
-	Stats                *sources.Stats `json:"stats,omitempty"`
-	Healthy              *bool          `json:"healthy,omitempty"`
+	ID                   int64             `json:"id" form:"id"`
+	Name                 string            `json:"name" form:"name" binding:"required,min=1"`
+	URL                  string            `json:"url" form:"url" binding:"required,min=1"`
+	Mirrors              []string          `json:"mirrors,omitempty" form:"mirrors"`
+	Active               bool              `json:"active" form:"active"`
+	Attention            bool              `json:"attention" form:"attention"`
+	Status               []string          `json:"status,omitempty"`
+	Rate                 *float64          `json:"rate,omitempty" form:"rate" binding:"omitnil,gte=0"`
+	Slots                *int              `json:"slots,omitempty" form:"slots" binding:"omitnil,gte=0"`
+	Headers              []string          `json:"headers,omitempty" form:"headers"`
+	StrictMode           *bool             `json:"strict_mode,omitempty" form:"strict_mode"`
+	Secure               *bool             `json:"secure,omitempty" form:"secure"`
+	SignatureCheck       *bool             `json:"signature_check,omitempty" form:"signature_check"`
+	Age                  *sourceAge        `json:"age,omitempty" form:"age" swaggertype:"primitive,integer"`
+	IgnorePatterns       []string          `json:"ignore_patterns,omitempty" form:"ignore_patterns"`
+	ClientCertPublic     *string           `json:"client_cert_public,omitempty" form:"client_cert_public"`
+	ClientCertPrivate    *string           `json:"client_cert_private,omitempty" form:"client_cert_private"`
+	ClientCertPassphrase *string           `json:"client_cert_passphrase,omitempty" form:"client_cert_passphrase"`
+	Keys                 []sources.KeyInfo `json:"keys,omitempty" form:"-"`
+	Stats                *sources.Stats    `json:"stats,omitempty"`
+	Healthy              *bool             `json:"healthy,omitempty"`
 }
 
 type feed struct {
@@ -100,6 +103,7 @@ func newSource(si *sources.SourceInfo, healthy *bool) *source {
 		ID:                   si.ID,
 		Name:                 si.Name,
 		URL:                  si.URL,
+		Mirrors:              si.Mirrors,
 		Active:               si.Active,
 		Attention:            si.Attention,
 		Status:               si.Status,
@@ -114,6 +118,7 @@ func newSource(si *sources.SourceInfo, healthy *bool) *source {
 		ClientCertPublic:     threeStars(si.HasClientCertPublic),
 		ClientCertPrivate:    threeStars(si.HasClientCertPrivate),
 		ClientCertPassphrase: threeStars(si.HasClientCertPassphrase),
+		Keys:                 si.Keys,
 		Stats:                si.Stats,
 		Healthy:              healthy,
 	}
@@ -230,6 +235,7 @@ func hasBlock(data []byte) bool {
 //
 //	@Summary		Creates a source.
 //	@Description	Creates a source with the specified configuration.
+//	@Description	If the URL is a bare domain the PMD is discovered.
 //	@Param			source	formData	source	true	"Source configuration"
 //	@Accept			multipart/form-data
 //	@Produce		json
@@ -260,10 +266,35 @@ func (c *Controller) createSource(ctx *gin.Context) {
 	if src.Slots != nil && *src.Slots == 0 {
 		src.Slots = nil
 	}
+	if sources.IsDomain(src.URL) {
+		pd, err := c.sm.DiscoverPMD(src.URL)
+		if err != nil {
+			models.SendError(ctx, http.StatusBadRequest, err)
+			return
+		}
+		if pd.Selected == "" {
+			models.SendErrorMessage(ctx, http.StatusBadRequest,
+				fmt.Sprintf("no valid PMD found for domain %q", pd.Domain))
+			return
+		}
+		src.URL = pd.Selected
+	} else if !strings.Contains(src.URL, "://") {
+		// Otherwise loading the PMD would look up the name on its own.
+		models.SendErrorMessage(ctx, http.StatusBadRequest,
+			fmt.Sprintf("%q is neither a URL nor a domain", src.URL))
+		return
+	}
 	if err := validateHeaders(src.Headers); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	src.Mirrors = slices.DeleteFunc(src.Mirrors, func(m string) bool { return m == "" })
+	for _, mirror := range src.Mirrors {
+		if err := sources.ValidateMirror(mirror); err != nil {
+			models.SendError(ctx, http.StatusBadRequest, err)
+			return
+		}
+	}
 	ignorePatterns, err := sources.AsRegexps(src.IgnorePatterns)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -299,6 +330,7 @@ func (c *Controller) createSource(ctx *gin.Context) {
 	switch id, err := c.sm.AddSource(
 		src.Name,
 		src.URL,
+		src.Mirrors,
 		src.Rate,
 		src.Slots,
 		src.Headers,
@@ -428,6 +460,13 @@ func (c *Controller) updateSource(ctx *gin.Context) {
 				return err
 			}
 		}
+		// mirrors
+		if mirrors, ok := ctx.GetPostFormArray("mirrors"); ok {
+			mirrors = slices.DeleteFunc(mirrors, func(m string) bool { return m == "" })
+			if err := su.UpdateMirrors(mirrors); err != nil {
+				return err
+			}
+		}
 		// rate
 		if rate, ok := ctx.GetPostForm("rate"); ok {
 			var r *float64
@@ -611,6 +650,137 @@ func validateHeaders(headers []string) error {
 	return nil
 }
 
+// viewSourceKeys is an endpoint that returns the pinned OpenPGP keys of a source.
+//
+//	@Summary		Returns the OpenPGP keys of a source.
+//	@Description	Returns the OpenPGP keys seen in the PMD of the source and the manually added ones.
+//	@Description	Keys which are not approved are not used to verify signatures.
+//	@Param			id	path	int	true	"Source ID"
+//	@Produce		json
+//	@Success		200	{array}		sources.KeyInfo
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		404	{object}	models.Error
+//	@Failure		500	{object}	models.Error
+//	@Router			/sources/{id}/keys [get]
+func (c *Controller) viewSourceKeys(ctx *gin.Context) {
+	id, ok := parse(ctx, toInt64, ctx.Param("id"))
+	if !ok {
+		return
+	}
+	switch keys, err := c.sm.Keys(id); {
+	case err == nil:
+		ctx.JSON(http.StatusOK, keys)
+	case errors.Is(err, sources.NoSuchEntryError("")):
+		models.SendError(ctx, http.StatusNotFound, err)
+	default:
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+// approveSourceKey is an endpoint that approves a pinned OpenPGP key of a source.
+//
+//	@Summary		Approves a pinned OpenPGP key.
+//	@Description	Approves or revokes the approval of a pinned OpenPGP key of a source.
+//	@Param			id			path		int		true	"Source ID"
+//	@Param			fingerprint	path		string	true	"Key fingerprint"
+//	@Param			approved	formData	bool	true	"Approval"
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Success		200	{object}	models.Success	"key approval changed"
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		404	{object}	models.Error
+//	@Failure		500	{object}	models.Error
+//	@Router			/sources/{id}/keys/{fingerprint} [put]
+func (c *Controller) approveSourceKey(ctx *gin.Context) {
+	id, ok := parse(ctx, toInt64, ctx.Param("id"))
+	if !ok {
+		return
+	}
+	approved, ok := parse(ctx, strconv.ParseBool, ctx.PostForm("approved"))
+	if !ok {
+		return
+	}
+	switch err := c.sm.ApproveKey(id, ctx.Param("fingerprint"), approved); {
+	case err == nil:
+		models.SendSuccess(ctx, http.StatusOK, "key approval changed")
+	case errors.Is(err, sources.NoSuchEntryError("")):
+		models.SendError(ctx, http.StatusNotFound, err)
+	default:
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+// uploadSourceKey is an endpoint that adds an OpenPGP key to a source.
+//
+//	@Summary		Adds an OpenPGP key to a source.
+//	@Description	Adds an armored public OpenPGP key to the key ring of a source.
+//	@Param			id	path		int		true	"Source ID"
+//	@Param			key	formData	string	true	"Armored public key"
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Success		201	{object}	sources.KeyInfo
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		404	{object}	models.Error
+//	@Failure		500	{object}	models.Error
+//	@Router			/sources/{id}/keys [post]
+func (c *Controller) uploadSourceKey(ctx *gin.Context) {
+	id, ok := parse(ctx, toInt64, ctx.Param("id"))
+	if !ok {
+		return
+	}
+	armored, ok := parse(ctx, notEmpty, ctx.PostForm("key"))
+	if !ok {
+		return
+	}
+	switch ki, err := c.sm.UploadKey(id, armored); {
+	case err == nil:
+		ctx.JSON(http.StatusCreated, ki)
+	case errors.Is(err, sources.NoSuchEntryError("")):
+		models.SendError(ctx, http.StatusNotFound, err)
+	case errors.Is(err, sources.InvalidArgumentError("")):
+		models.SendError(ctx, http.StatusBadRequest, err)
+	default:
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+// deleteSourceKey is an endpoint that removes a manually added OpenPGP key from a source.
+//
+//	@Summary		Removes an OpenPGP key from a source.
+//	@Description	Removes a manually added OpenPGP key from the key ring of a source.
+//	@Param			id			path	int		true	"Source ID"
+//	@Param			fingerprint	path	string	true	"Key fingerprint"
+//	@Produce		json
+//	@Success		200	{object}	models.Success	"key deleted"
+//	@Failure		400	{object}	models.Error
+//	@Failure		401
+//	@Failure		404	{object}	models.Error
+//	@Failure		500	{object}	models.Error
+//	@Router			/sources/{id}/keys/{fingerprint} [delete]
+func (c *Controller) deleteSourceKey(ctx *gin.Context) {
+	id, ok := parse(ctx, toInt64, ctx.Param("id"))
+	if !ok {
+		return
+	}
+	switch err := c.sm.RemoveKey(id, ctx.Param("fingerprint")); {
+	case err == nil:
+		models.SendSuccess(ctx, http.StatusOK, "key deleted")
+	case errors.Is(err, sources.NoSuchEntryError("")):
+		models.SendError(ctx, http.StatusNotFound, err)
+	case errors.Is(err, sources.InvalidArgumentError("")):
+		models.SendError(ctx, http.StatusBadRequest, err)
+	default:
+		slog.Error("database error", "err", err)
+		models.SendError(ctx, http.StatusInternalServerError, err)
+	}
+}
+
 type feedResult struct {
 	Feeds []*feed `json:"feeds"`
 }
@@ -1110,6 +1280,7 @@ func (c *Controller) defaultSourceConfig(ctx *gin.Context) {
 //
 //	@Summary		Returns the pmd.
 //	@Description	Fetches and returns the provider metadata for the specified URL.
+//	@Description	Feeds listed in ROLIE service documents are added to the ROLIE distribution.
 //	@Param			url	query	string	true	"PMD URL"
 //	@Produce		json
 //	@Success		200	{object}	any
@@ -1143,5 +1314,38 @@ func (c *Controller) pmd(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadGateway, h)
 		return
 	}
-	ctx.JSON(http.StatusOK, cpmd.Loaded.Document)
+	doc, err := cpmd.Expanded(c.cfg)
+	if err != nil {
+		models.SendError(ctx, http.StatusBadGateway, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, doc)
+}
+
+// discoverPMD is an endpoint that looks up the PMDs of a domain.
+//
+//	@Summary		Discovers the PMDs of a domain.
+//	@Description	Looks up the PMDs in the well-known path, the security.txt and the DNS path.
+//	@Description	Every candidate is reported with its validity. The first valid one is selected.
+//	@Param			domain	query	string	true	"Domain"
+//	@Produce		json
+//	@Success		200	{object}	sources.PMDDiscovery
+//	@Failure		400	{object}	models.Error	"could not parse domain"
+//	@Failure		401
+//	@Router			/pmd/discover [get]
+func (c *Controller) discoverPMD(ctx *gin.Context) {
+	type inputForm struct {
+		Domain string `form:"domain" binding:"required,min=1"`
+	}
+	input := inputForm{}
+	if err := ctx.ShouldBindQuery(&input); err != nil {
+		models.SendError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	pd, err := c.sm.DiscoverPMD(input.Domain)
+	if err != nil {
+		models.SendError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, pd)
 }
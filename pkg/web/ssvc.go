@@ -75,7 +75,7 @@ func (c *Controller) changeSSVC(ctx *gin.Context) {
 			`($1::varchar, $2::integer, $3)`
 	)
 
-	var forbidden, unchanged, bad bool
+	var forbidden, unchanged, bad, stateChanged bool
 
 	if err := c.db.Run(
 		ctx.Request.Context(),
@@ -143,6 +143,7 @@ func (c *Controller) changeSSVC(ctx *gin.Context) {
 				if err := logEvent(models.StateChangeEvent, models.AssessingWorkflow); err != nil {
 					return err
 				}
+				stateChanged = true
 			}
 
 			// Now do the actual SSVC update.
@@ -169,6 +170,9 @@ func (c *Controller) changeSSVC(ctx *gin.Context) {
 	case bad:
 		models.SendErrorMessage(ctx, http.StatusBadRequest, "unsuited state")
 	default:
+		if stateChanged {
+			c.evaluateAlerts(ctx, models.StateChangeEvent, documentID)
+		}
 		models.SendSuccess(ctx, http.StatusOK, "changed")
 	}
 }
@@ -65,12 +65,33 @@ func notEmpty(s string) (string, error) {
 // toInt64 parses a given string to a 64bit integer.
 func toInt64(s string) (int64, error) { return strconv.ParseInt(s, 10, 64) }
 
+// parseError is the error sent if a query cannot be parsed.
+// It extends the plain error with the location of the problem.
+type parseError struct {
+	models.Error
+	Details *query.ParseError `json:"details"`
+}
+
+// sendBadRequest sends an error with a bad request status code.
+// Query parse errors are sent with their details.
+func sendBadRequest(ctx *gin.Context, err error) {
+	var pe *query.ParseError
+	if !errors.As(err, &pe) {
+		models.SendError(ctx, http.StatusBadRequest, err)
+		return
+	}
+	ctx.JSON(http.StatusBadRequest, parseError{
+		Error:   models.Error{Error: err.Error(), Code: http.StatusBadRequest},
+		Details: pe,
+	})
+}
+
 // parse parses a string with a given function to a value.
 // If that fails a bad request status code is set in the gin context.
 func parse[T any](ctx *gin.Context, conv func(string) (T, error), s string) (T, bool) {
 	v, err := conv(s)
 	if err != nil {
-		models.SendError(ctx, http.StatusBadRequest, err)
+		sendBadRequest(ctx, err)
 		return v, false
 	}
 	return v, true
@@ -0,0 +1,165 @@
+// This file is Free Software under the Apache-2.0 License
+// without warranty, see README.md and LICENSES/Apache-2.0.txt for details.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// SPDX-FileCopyrightText: 2026 German Federal Office for Information Security (BSI) <https://www.bsi.bund.de>
+// Software-Engineering: 2026 Intevation GmbH <https://intevation.de>
+
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ISDuBA/ISDuBA/pkg/database/query"
+	"github.com/ISDuBA/ISDuBA/pkg/models"
+)
+
+// documentValidation is the validation report of a download or an import
+// of a document.
+type documentValidation struct {
+	Time       *time.Time               `json:"time,omitempty"`
+	Feed       *string                  `json:"feed,omitempty"`
+	Source     *string                  `json:"source,omitempty"`
+	Recomputed bool                     `json:"recomputed,omitempty"`
+	Report     *models.ValidationReport `json:"report"`
+}
+
+// sendValidationError sends the error and the report of a failed validation.
+func sendValidationError(ctx *gin.Context, err error, report *models.ValidationReport) {
+	ctx.JSON(http.StatusBadRequest, models.ValidationError{
+		Error:  err.Error(),
+		Code:   http.StatusBadRequest,
+		Report: report,
+	})
+}
+
+// storeRejectedImport stores the validation report of an uploaded
+// document which is rejected before it is imported. Like the reports
+// of imported documents it is recorded for the 'single' feed.
+func (c *Controller) storeRejectedImport(ctx context.Context, report *models.ValidationReport) {
+	const insertSQL = `INSERT INTO downloads ` +
+		`(feeds_id, schema_failed, remote_failed, validation_report) VALUES (` +
+		`(SELECT id FROM feeds WHERE sources_id = 0 AND label = 'single'), $1, $2, $3)`
+	if err := c.db.Run(
+		ctx,
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			_, err := conn.Exec(rctx, insertSQL,
+				report.SchemaFailed(), report.RemoteFailed(), report)
+			return err
+		}, 0,
+	); err != nil {
+		slog.Error("storing validation report failed", "err", err)
+	}
+}
+
+// viewValidation is an endpoint that returns the validation reports of a document.
+//
+//	@Summary		Returns the validation reports of a document.
+//	@Description	Returns the validation reports stored with the downloads and imports of the document, the newest first.
+//	@Description	If none is stored the report is recomputed from the stored document.
+//	@Param			id	path	int	true	"Document ID"
+//	@Produce		json
+//	@Success		200	{array}	web.documentValidation
+//	@Failure		400	{object}	models.Error	"could not parse id"
+//	@Failure		401
+//	@Failure		404	{object}	models.Error	"document not found"
+//	@Failure		500	{object}	models.Error
+//	@Router			/documents/{id}/validation [get]
+func (c *Controller) viewValidation(ctx *gin.Context) {
+	id, ok := parse(ctx, toInt64, ctx.Param("id"))
+	if !ok {
+		return
+	}
+
+	expr := c.andTLPExpr(ctx, query.FieldEqInt("id", id))
+
+	fields := []string{"original", "filename"}
+	builder := query.SQLBuilder{}
+	builder.CreateWhere(expr)
+	sql := builder.CreateQuery(fields, "", -1, -1)
+
+	const reportsSQL = `SELECT downloads.time, feeds.label, sources.name, validation_report ` +
+		`FROM downloads ` +
+		`LEFT JOIN feeds ON downloads.feeds_id = feeds.id ` +
+		`LEFT JOIN sources ON feeds.sources_id = sources.id ` +
+		`WHERE documents_id = $1 AND validation_report IS NOT NULL ` +
+		`ORDER BY downloads.time DESC`
+
+	var (
+		original    []byte
+		filename    *string
+		validations []documentValidation
+	)
+	if err := c.db.Run(
+		ctx.Request.Context(),
+		func(rctx context.Context, conn *pgxpool.Conn) error {
+			if err := conn.QueryRow(rctx, sql, builder.Replacements...).
+				Scan(&original, &filename); err != nil {
+				return err
+			}
+			rows, _ := conn.Query(rctx, reportsSQL, id)
+			var err error
+			validations, err = pgx.CollectRows(rows,
+				func(row pgx.CollectableRow) (documentValidation, error) {
+					var dv documentValidation
+					err := row.Scan(&dv.Time, &dv.Feed, &dv.Source, &dv.Report)
+					return dv, err
+				})
+			return err
+		}, 0,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			models.SendErrorMessage(ctx, http.StatusNotFound, "document not found")
+		} else {
+			slog.Error("database error", "err", err)
+			models.SendError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	// Documents stored before the reports were introduced.
+	if len(validations) == 0 {
+		report, err := c.recomputeValidation(original, filename)
+		if err != nil {
+			slog.Error("recomputing validation report failed", "err", err)
+			models.SendError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		validations = append(validations, documentValidation{
+			Recomputed: true,
+			Report:     report,
+		})
+	}
+	ctx.JSON(http.StatusOK, validations)
+}
+
+// recomputeValidation validates a stored document again.
+func (c *Controller) recomputeValidation(
+	original []byte,
+	filename *string,
+) (*models.ValidationReport, error) {
+	var document any
+	if err := json.Unmarshal(original, &document); err != nil {
+		return nil, err
+	}
+	report := models.NewValidationReport()
+	// Errors running the schema validation are part of the report.
+	_ = report.CheckSchema(document)
+	if c.val != nil {
+		report.AddRemote(c.val.Validate(document))
+	}
+	if filename != nil && *filename != "" {
+		report.CheckFilename(document, *filename)
+	}
+	return report, nil
+}